@@ -72,6 +72,13 @@ func PanicApiError(state *ApiState, cause error, message string, args ...any) {
 // 可以为 BadRequestError 指定一个描述信息，此信息可能作为 WebAPI 的返回值，被请求者看到。
 type BadRequestError struct {
 	withinStateError
+
+	// Code 是随错误返回给请求者的错误码，为 0 时使用 [ErrorCodeBadRequest] 。
+	Code int
+
+	// Data 是随错误返回给请求者的附加数据，如 [ValidationErrors] 。可为 nil 。
+	// 使用 [NewBasicApiResponseBuilder] 时，若不为 nil ，其被放在 [ApiResponse.Data] 上。
+	Data any
 }
 
 // CreateBadRequestError 创建一个 BadRequestError 。
@@ -80,7 +87,7 @@ type BadRequestError struct {
 func CreateBadRequestError(state *ApiState, cause error, message string, args ...any) BadRequestError {
 	message = fmt.Sprintf(message, args...)
 	e := BadRequestError{
-		withinStateError{
+		withinStateError: withinStateError{
 			ErrorCause: errx.ErrorCause{Err: cause},
 			State:      state,
 			Message:    message,
@@ -0,0 +1,98 @@
+package webapi
+
+import "sort"
+
+// ApiMetaParamNames 是记录方法参数名称的元数据的 key ，值为 []string ，可通过 [ApiSetup.SetParamNames] 设置。
+// 元素与方法参数表中的参数按位置对应，空字符串表示参数没有名称；元素个数可少于参数个数，多出的参数没有名称。
+//
+// Go 无法通过反射获取参数名称，需在注册时给出。参数名称如何使用由 [ApiDecoder] 决定，
+// 例如 SlimAPI 按名称从请求中读取基础类型的参数。 [ArgumentDecoderPipeline] 允许有名称的参数的类型重复。
+const ApiMetaParamNames = "ParamNames"
+
+// ApiMetaMaxBodySize 是限制请求 body 大小的元数据的 key ，值为 int64 ，单位是字节，可通过 [ApiSetup.SetMaxBodySize] 设置。
+// 此值优先于 [ApiHandlerWrapper.MaxBodySize] ，小于等于 0 表示不限制。见 [LimitRequestBody] 。
+const ApiMetaMaxBodySize = "MaxBodySize"
+
+// ApiMetaStreamHeartbeat 是流式输出的心跳间隔的元数据的 key ，值为 [time.Duration] ，可通过 [ApiSetup.SetStreamHeartbeat] 设置。
+// 此值优先于 [ApiHandlerWrapper.StreamHeartbeat] ，小于等于 0 表示不发送心跳。见 [StreamHeartbeat] 。
+const ApiMetaStreamHeartbeat = "StreamHeartbeat"
+
+// ApiMetaLongPollMaxWait 是长轮询单次请求最长等待时间的元数据的 key ，值为 [time.Duration] ，可通过 [ApiSetup.SetLongPollMaxWait] 设置。
+// 此值优先于 [ApiHandlerWrapper.LongPollMaxWait] 。见 [LongPollMaxWait] 。
+const ApiMetaLongPollMaxWait = "LongPollMaxWait"
+
+// ApiMeta 记录注册 API 方法时附带的元数据，为一组 key-value 对。
+// 元数据可用于描述方法（如生成文档），也可作为各管道环节的配置，例如限制请求 body 的大小。
+//
+// 此类型是不可变的，修改操作会返回新的实例，以便在多个方法间共享同一实例。
+// nil 是合法的值，表示没有元数据。
+type ApiMeta struct {
+	m map[string]any
+}
+
+// NewApiMeta 使用给定的 key-value 创建 [ApiMeta] ，给定的 map 会被复制。
+func NewApiMeta(m map[string]any) *ApiMeta {
+	res := &ApiMeta{m: make(map[string]any, len(m))}
+	for k, v := range m {
+		res.m[k] = v
+	}
+	return res
+}
+
+// Get 获取给定 key 的值。若 key 不存在，返回 nil 和 false 。
+func (x *ApiMeta) Get(key string) (any, bool) {
+	if x == nil {
+		return nil, false
+	}
+
+	v, ok := x.m[key]
+	return v, ok
+}
+
+// With 返回一个新的 [ApiMeta] ，其在当前实例的基础上设置了给定的 key-value ，当前实例不会被修改。
+// 可在 nil 上调用。
+func (x *ApiMeta) With(key string, value any) *ApiMeta {
+	res := NewApiMeta(x.Map())
+	res.m[key] = value
+	return res
+}
+
+// Keys 返回全部的 key ，按升序排列。
+func (x *ApiMeta) Keys() []string {
+	if x == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(x.m))
+	for k := range x.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Map 返回全部 key-value 的副本。在 nil 上调用时返回 nil 。
+func (x *ApiMeta) Map() map[string]any {
+	if x == nil {
+		return nil
+	}
+
+	res := make(map[string]any, len(x.m))
+	for k, v := range x.m {
+		res[k] = v
+	}
+	return res
+}
+
+// GetApiMetaValue 从 meta 中获取给定 key 的值，并断言为类型 T 。
+// 若 key 不存在或值的类型不是 T ，返回 T 的零值和 false 。
+func GetApiMetaValue[T any](meta *ApiMeta, key string) (T, bool) {
+	v, ok := meta.Get(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+
+	t, ok := v.(T)
+	return t, ok
+}
@@ -0,0 +1,116 @@
+package webapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiMeta(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		var m *ApiMeta
+		v, ok := m.Get("a")
+		require.False(t, ok)
+		require.Nil(t, v)
+		require.Nil(t, m.Keys())
+		require.Nil(t, m.Map())
+
+		m2 := m.With("a", 1)
+		v, ok = m2.Get("a")
+		require.True(t, ok)
+		require.Equal(t, 1, v)
+	})
+
+	t.Run("immutable", func(t *testing.T) {
+		src := map[string]any{"b": 2, "a": 1}
+		m := NewApiMeta(src)
+		src["c"] = 3
+		require.Equal(t, []string{"a", "b"}, m.Keys())
+
+		m2 := m.With("c", 3).With("a", 10)
+		require.Equal(t, map[string]any{"a": 1, "b": 2}, m.Map())
+		require.Equal(t, map[string]any{"a": 10, "b": 2, "c": 3}, m2.Map())
+
+		mm := m.Map()
+		mm["x"] = 0
+		require.Equal(t, []string{"a", "b"}, m.Keys())
+	})
+}
+
+func TestGetApiMetaValue(t *testing.T) {
+	m := NewApiMeta(map[string]any{"i": 1, "s": "v"})
+
+	i, ok := GetApiMetaValue[int](m, "i")
+	require.True(t, ok)
+	require.Equal(t, 1, i)
+
+	_, ok = GetApiMetaValue[int](m, "s")
+	require.False(t, ok)
+
+	_, ok = GetApiMetaValue[int](m, "none")
+	require.False(t, ok)
+
+	_, ok = GetApiMetaValue[int](nil, "i")
+	require.False(t, ok)
+}
+
+func TestApiSetup_SetMethodMeta(t *testing.T) {
+	h := setupApiHandlerWrapper(&ApiHandlerWrapper{
+		ApiMethodRegister: NewBasicApiMethodRegister(BasicApiMethodRegisterOp{}),
+	})
+	h.RegisterMethod(ApiMethod{Name: "Abc", Value: reflect.ValueOf(func() {})})
+
+	setup := NewEngine().Handle("/", h, nil)
+	setup.SetMethodMeta("abc", "k1", 1).SetMethodMeta("ABC", "k2", "v")
+
+	m, _ := h.GetMethod("abc")
+	require.Equal(t, "Abc", m.Name)
+	require.Equal(t, map[string]any{"k1": 1, "k2": "v"}, m.Meta.Map())
+
+	require.PanicsWithValue(t, "method 'none' not found", func() {
+		setup.SetMethodMeta("none", "k", 1)
+	})
+}
+
+func TestApiSetup_SetParamNames(t *testing.T) {
+	h := setupApiHandlerWrapper(&ApiHandlerWrapper{
+		ApiMethodRegister: NewBasicApiMethodRegister(BasicApiMethodRegisterOp{}),
+	})
+	h.RegisterMethod(ApiMethod{Name: "Abc", Value: reflect.ValueOf(func(*ApiState, int, string) {})})
+
+	setup := NewEngine().Handle("/", h, nil)
+	setup.SetParamNames("abc", "", "id")
+
+	m, _ := h.GetMethod("abc")
+	require.Equal(t, []string{"", "id"}, m.Meta.Map()[ApiMetaParamNames])
+	require.Equal(t, "", m.ParamName(0))
+	require.Equal(t, "id", m.ParamName(1))
+	require.Equal(t, "", m.ParamName(2))
+	require.Equal(t, "", m.ParamName(-1))
+	require.Equal(t, "", ApiMethod{}.ParamName(0))
+
+	require.PanicsWithValue(t, "method 'abc' has 3 parameters, got 4 names", func() {
+		setup.SetParamNames("abc", "a", "b", "c", "d")
+	})
+
+	require.PanicsWithValue(t, "method 'none' not found", func() {
+		setup.SetParamNames("none")
+	})
+}
+
+func TestApiSetup_SetMetaForAllMethods(t *testing.T) {
+	h := setupApiHandlerWrapper(&ApiHandlerWrapper{
+		ApiMethodRegister: NewBasicApiMethodRegister(BasicApiMethodRegisterOp{}),
+	})
+	h.RegisterMethod(ApiMethod{Name: "A", Value: reflect.ValueOf(func() {})})
+	h.RegisterMethod(ApiMethod{Name: "B", Value: reflect.ValueOf(func() {})})
+
+	NewEngine().Handle("/", h, nil).SetMetaForAllMethods("k", 1).SetMethodMeta("b", "k", 2)
+
+	a, _ := h.GetMethod("a")
+	require.Equal(t, map[string]any{"k": 1}, a.Meta.Map())
+
+	b, _ := h.GetMethod("b")
+	require.Equal(t, map[string]any{"k": 2}, b.Meta.Map())
+}
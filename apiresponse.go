@@ -27,6 +27,10 @@ const (
 
 	// ContentTypeNdJson 对应 Content-Type: application/x-ndjson 的值。
 	ContentTypeNdJson = "application/x-ndjson"
+
+	// ContentTypeJsonArrayStream 是 [JsonArray] 的 Content-Type ，在 application/json 上附加 stream=array 参数，
+	// 使客户端可以将其与一般的 JSON 回执区分开，以逐项读取。
+	ContentTypeJsonArrayStream = "application/json; stream=array"
 )
 
 const (
@@ -35,6 +39,9 @@ const (
 
 	// HttpHeaderContentDisposition 对应 HTTP 头中的 Content-Disposition 字段。
 	HttpHeaderContentDisposition = "Content-Disposition"
+
+	// HttpHeaderLastEventId 对应 HTTP 头中的 Last-Event-ID 字段，见 [LastEventId] 。
+	HttpHeaderLastEventId = "Last-Event-ID"
 )
 
 // 用于 WebAPI 预定义的状态码。1000以下基本抄 HTTP 状态码。
@@ -42,6 +49,9 @@ const (
 	// 错误码。表示不合规的请求数据。
 	ErrorCodeBadRequest = 400
 
+	// 错误码。表示请求的 body 超过了允许的大小，见 [LimitRequestBody] 。
+	ErrorCodeRequestEntityTooLarge = 413
+
 	// 错误码。表示发生内部错误。
 	ErrorCodeInternalError = 500
 )
@@ -1,5 +1,10 @@
 package webapi
 
+import (
+	"fmt"
+	"time"
+)
+
 // ApiSetup 用于向 ApiHandler 注册 API 方法。
 type ApiSetup struct {
 	engine  *ApiEngine
@@ -24,3 +29,66 @@ func (setup ApiSetup) RegisterMethods(providerStruct any) ApiSetup {
 	setup.handler.RegisterMethods(providerStruct)
 	return setup
 }
+
+// SetMethodMeta 为已注册的方法设置一项元数据（ [ApiMethod.Meta] ）。若方法不存在，则 panic 。
+// 返回 ApiSetup 实例自身，以便编码形成流式调用。
+func (setup ApiSetup) SetMethodMeta(name, key string, value any) ApiSetup {
+	m, ok := setup.handler.GetMethod(name)
+	if !ok {
+		panic(fmt.Sprintf("method '%v' not found", name))
+	}
+
+	m.Meta = m.Meta.With(key, value)
+	setup.handler.RegisterMethod(m)
+	return setup
+}
+
+// SetMetaForAllMethods 为已注册的全部方法设置一项元数据（ [ApiMethod.Meta] ），之后注册的方法不受影响。
+// 方法通过 [ListMethods] 枚举，若 ApiHandler 不支持枚举方法，则 panic 。
+// 可先以此设置默认值，再通过 [ApiSetup.SetMethodMeta] 为个别方法单独设置。
+// 返回 ApiSetup 实例自身，以便编码形成流式调用。
+func (setup ApiSetup) SetMetaForAllMethods(key string, value any) ApiSetup {
+	for _, m := range ListMethods(setup.handler) {
+		m.Meta = m.Meta.With(key, value)
+		setup.handler.RegisterMethod(m)
+	}
+	return setup
+}
+
+// SetParamNames 为已注册的方法设置参数的名称（元数据 [ApiMetaParamNames] ），按位置与方法参数表中的参数对应，
+// 空字符串表示参数没有名称，例如方法 func(state *ApiState, id int, name string) 可使用 "", "id", "name" 。
+// 若方法不存在，或名称的个数多于参数的个数，则 panic 。
+// 返回 ApiSetup 实例自身，以便编码形成流式调用。
+func (setup ApiSetup) SetParamNames(name string, paramNames ...string) ApiSetup {
+	m, ok := setup.handler.GetMethod(name)
+	if !ok {
+		panic(fmt.Sprintf("method '%v' not found", name))
+	}
+
+	if numIn := m.Value.Type().NumIn(); len(paramNames) > numIn {
+		panic(fmt.Sprintf("method '%v' has %d parameters, got %d names", name, numIn, len(paramNames)))
+	}
+
+	return setup.SetMethodMeta(name, ApiMetaParamNames, paramNames)
+}
+
+// SetMaxBodySize 为已注册的方法设置请求 body 的最大字节数（元数据 [ApiMetaMaxBodySize] ），小于等于 0 表示不限制。
+// 若方法不存在，则 panic 。
+// 返回 ApiSetup 实例自身，以便编码形成流式调用。
+func (setup ApiSetup) SetMaxBodySize(name string, size int64) ApiSetup {
+	return setup.SetMethodMeta(name, ApiMetaMaxBodySize, size)
+}
+
+// SetStreamHeartbeat 为已注册的方法设置流式输出的心跳间隔（元数据 [ApiMetaStreamHeartbeat] ），小于等于 0 表示不发送心跳。
+// 若方法不存在，则 panic 。
+// 返回 ApiSetup 实例自身，以便编码形成流式调用。
+func (setup ApiSetup) SetStreamHeartbeat(name string, interval time.Duration) ApiSetup {
+	return setup.SetMethodMeta(name, ApiMetaStreamHeartbeat, interval)
+}
+
+// SetLongPollMaxWait 为已注册的方法设置长轮询单次请求的最长等待时间（元数据 [ApiMetaLongPollMaxWait] ），
+// 小于等于 0 表示使用默认值。若方法不存在，则 panic 。
+// 返回 ApiSetup 实例自身，以便编码形成流式调用。
+func (setup ApiSetup) SetLongPollMaxWait(name string, maxWait time.Duration) ApiSetup {
+	return setup.SetMethodMeta(name, ApiMetaLongPollMaxWait, maxWait)
+}
@@ -1,6 +1,7 @@
 package webapi
 
 import (
+	"context"
 	"iter"
 	"net/http"
 	"reflect"
@@ -76,19 +77,37 @@ type ApiState struct {
 
 	// customData 用于记录没有预定义的数据，即不在其他字段中体现的数据，由各处理过程自行决定。
 	customData []struct{ k, v any }
+
+	// cleanups 记录 AddCleanup 添加的函数。
+	cleanups []func()
+
+	// cancelRequest 取消 RawRequest 的 context ，见 CancelRequest 。
+	cancelRequest context.CancelFunc
 }
 
 // NewState 创建一个新的 ApiState ，每个请求应使用一个新的 ApiState 。
+//
+// RawRequest 的 context 基于 w 的 context ，并可通过 [ApiState.CancelRequest] 取消。
 func NewState(r http.ResponseWriter, w *http.Request, handler ApiHandler) *ApiState {
+	ctx, cancel := context.WithCancel(w.Context())
 	s := &ApiState{
-		Handler:     handler,
-		RawRequest:  w,
-		RawResponse: r,
+		Handler:       handler,
+		RawRequest:    w.WithContext(ctx),
+		RawResponse:   r,
+		cancelRequest: cancel,
 	}
 	s.Query = ParseQueryString(w.URL.RawQuery)
 	return s
 }
 
+// CancelRequest 取消 RawRequest 的 context （见 [http.Request.Context] ），使仍在执行的过程（如流式输出的迭代器）能够感知并停止。
+// 回执无法输出（通常是连接已断开）时，由 [CreateHandlerFunc] 等调用。 ApiState 不是由 [NewState] 创建时，不执行任何操作。
+func (s *ApiState) CancelRequest() {
+	if s.cancelRequest != nil {
+		s.cancelRequest()
+	}
+}
+
 // MustHaveName checks the Name field, panics if the field is not initialized.
 func (s *ApiState) MustHaveName() {
 	if s.Name == "" {
@@ -123,3 +142,27 @@ func (s *ApiState) GetCustomData(key any) (any, bool) {
 	}
 	return nil, false
 }
+
+// AddCleanup 添加一个在请求处理结束时（回执已输出、日志已记录）执行的函数，用于释放请求过程中占用的资源，如删除临时文件。
+// 多个函数按添加的相反顺序执行；其中的 panic 会被忽略，不影响其他函数的执行。
+// 由 [CreateHandlerFunc] 保证执行，自行驱动处理流程时，需调用 [ApiState.Cleanup] 。
+func (s *ApiState) AddCleanup(f func()) {
+	s.cleanups = append(s.cleanups, f)
+}
+
+// Cleanup 按添加的相反顺序执行 [ApiState.AddCleanup] 添加的函数，每个函数只执行一次。
+// 之后，取消 RawRequest 的 context （见 [ApiState.CancelRequest] ）。
+func (s *ApiState) Cleanup() {
+	defer s.CancelRequest()
+
+	for len(s.cleanups) > 0 {
+		last := len(s.cleanups) - 1
+		f := s.cleanups[last]
+		s.cleanups = s.cleanups[:last]
+
+		func() {
+			defer func() { recover() }()
+			f()
+		}()
+	}
+}
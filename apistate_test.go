@@ -1,6 +1,7 @@
 package webapi
 
 import (
+	"net/http/httptest"
 	"reflect"
 	"testing"
 
@@ -29,7 +30,7 @@ func TestApiState_MustHaveMethod(t *testing.T) {
 			}
 		}()
 
-		(&ApiState{Method: ApiMethod{"", reflect.ValueOf(1), ""}}).MustHaveMethod()
+		(&ApiState{Method: ApiMethod{Value: reflect.ValueOf(1)}}).MustHaveMethod()
 	})
 
 	t.Run("OK", func(t *testing.T) {
@@ -42,7 +43,7 @@ func TestApiState_MustHaveMethod(t *testing.T) {
 		}()
 
 		f := reflect.ValueOf(func() {})
-		(&ApiState{Method: ApiMethod{"", f, ""}}).MustHaveMethod()
+		(&ApiState{Method: ApiMethod{Value: f}}).MustHaveMethod()
 	})
 }
 
@@ -65,3 +66,38 @@ func TestApiState_SetCustomData(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, 2, v)
 }
+
+func TestApiState_Cleanup(t *testing.T) {
+	s := &ApiState{}
+
+	var calls []int
+	s.AddCleanup(func() { calls = append(calls, 1) })
+	s.AddCleanup(func() { panic("ignored") })
+	s.AddCleanup(func() { calls = append(calls, 3) })
+
+	s.Cleanup()
+	assert.Equal(t, []int{3, 1}, calls)
+
+	// 每个函数只执行一次。
+	s.Cleanup()
+	assert.Equal(t, []int{3, 1}, calls)
+}
+
+func TestApiState_CancelRequest(t *testing.T) {
+	// 不是由 NewState 创建的，不执行任何操作。
+	(&ApiState{}).CancelRequest()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	s := NewState(httptest.NewRecorder(), r, nil)
+	ctx := s.RawRequest.Context()
+	assert.NoError(t, ctx.Err())
+
+	s.CancelRequest()
+	assert.Error(t, ctx.Err())
+	assert.NoError(t, r.Context().Err())
+
+	// Cleanup 之后， context 也被取消。
+	s = NewState(httptest.NewRecorder(), r, nil)
+	s.Cleanup()
+	assert.Error(t, s.RawRequest.Context().Err())
+}
@@ -120,7 +120,7 @@ func Test_basicApiMethodCaller_Call(t *testing.T) {
 
 			argLen := len(tt.args)
 			state := &ApiState{
-				Method: ApiMethod{"", reflect.ValueOf(tt.method), ""},
+				Method: ApiMethod{Value: reflect.ValueOf(tt.method)},
 				Args:   make([]reflect.Value, argLen),
 			}
 
@@ -3,6 +3,7 @@ package webapi
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 
@@ -15,9 +16,14 @@ type basicApiMethodRegister struct {
 	op      BasicApiMethodRegisterOp
 }
 
+var _ ApiMethodLister = (*basicApiMethodRegister)(nil)
+
 // BasicApiMethodRegisterOp 用于 [NewBasicApiMethodRegister] ，提供选项配置。
 type BasicApiMethodRegisterOp struct {
 	SupportStreamingResponse bool // 是否允许方法返回 [StreamingResponse] 。
+
+	// CheckMethod 若不为 nil ，则在注册每个方法时调用，用于校验协议特有的约定（如参数上的 struct tag ），不合规时应 panic 。
+	CheckMethod func(m ApiMethod)
 }
 
 // NewBasicApiMethodRegister 返回一个预定义的 ApiMethodRegister 的标准实现。
@@ -33,6 +39,16 @@ func NewBasicApiMethodRegister(op BasicApiMethodRegisterOp) ApiMethodRegister {
 func (r *basicApiMethodRegister) RegisterMethod(m ApiMethod) {
 	r.checkMethodOut(m.Value, m.Name)
 
+	// 在注册时即解析参数上的 validate tag ，使错误的 tag 在启动时暴露。
+	methodType := m.Value.Type()
+	for i := 0; i < methodType.NumIn(); i++ {
+		PrepareValidateRules(methodType.In(i))
+	}
+
+	if r.op.CheckMethod != nil {
+		r.op.CheckMethod(m)
+	}
+
 	// 用于检索的名称忽略大小写。
 	name := strings.ToLower(m.Name)
 	r.methods.Store(name, m)
@@ -60,7 +76,7 @@ func (r *basicApiMethodRegister) RegisterMethods(providerStruct any) {
 		}
 
 		valMethod := v.Method(i)
-		r.RegisterMethod(ApiMethod{name, valMethod, t.Name()})
+		r.RegisterMethod(ApiMethod{Name: name, Value: valMethod, Provider: t.Name()})
 	}
 }
 
@@ -80,6 +96,23 @@ func (r *basicApiMethodRegister) GetMethod(name string) (method ApiMethod, ok bo
 	return
 }
 
+// Methods implements ApiMethodLister.Methods
+func (r *basicApiMethodRegister) Methods() []ApiMethod {
+	var keys []string
+	r.methods.Range(func(k, _ any) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	sort.Strings(keys)
+
+	res := make([]ApiMethod, 0, len(keys))
+	for _, k := range keys {
+		m, _ := r.methods.Load(k)
+		res = append(res, m.(ApiMethod))
+	}
+	return res
+}
+
 // checkMethodOut 校验方法的输出参数。在参数不合规时 panic 。
 //
 // 允许方法允许有0-2个输出参数。
@@ -40,7 +40,7 @@ func Test_basicApiMethodRegister_RegisterMethod(t *testing.T) {
 			}()
 
 			methodValue := reflect.ValueOf(f)
-			reg.RegisterMethod(ApiMethod{name, methodValue, ""})
+			reg.RegisterMethod(ApiMethod{Name: name, Value: methodValue})
 
 			found := false
 			lowerName := strings.ToLower(name)
@@ -77,6 +77,11 @@ func Test_basicApiMethodRegister_RegisterMethod(t *testing.T) {
 	testOne("NotError", "'NotError' must be an error", func() (int, string) { panic("never run") })
 	testOne("TooManyParam", "'TooManyParam' has more than 2 output parameters", func() (int, string, int) { panic("never run") })
 	testOne("NotSupportedType", "'chan int' of method 'NotSupportedType' is not supported", func() chan int { panic("never run") })
+	// 参数上的 validate tag 在注册时解析。
+	type BadTag struct {
+		A int `validate:"what"`
+	}
+	testOne("BadValidateTag", `validate tag of webapi.BadTag.A: unknown rule "what"`, func(p struct{ Items []*BadTag }) { panic("never run") })
 	// 未开启流式支持时报错。
 	testOne("StreamingResponse", "'StreamingResponse' is not supported", func() StreamingResponse { panic("never run") })
 	testOne("EventStream", "'EventStream' is not supported", func() EventStream[int] { panic("never run") })
@@ -150,21 +155,44 @@ func Test_basicApiMethodRegister_GetMethod(t *testing.T) {
 
 	// 测试用例和 RegisterMethod 方法相互验证。
 	m := reflect.ValueOf(func() {})
-	reg.RegisterMethod(ApiMethod{"", m, ""})
+	reg.RegisterMethod(ApiMethod{Name: "", Value: m})
 	testOne("", true, m)
 
-	reg.RegisterMethod(ApiMethod{"AbCd", m, ""})
+	reg.RegisterMethod(ApiMethod{Name: "AbCd", Value: m})
 	testOne("AbCd", true, m)
 	testOne("abcd", true, m)
 	testOne("aBCd", true, m)
 
 	// 重复注册，覆盖原有。
 	m2 := reflect.ValueOf(func() {})
-	reg.RegisterMethod(ApiMethod{"abcd", m2, ""})
+	reg.RegisterMethod(ApiMethod{Name: "abcd", Value: m2})
 	testOne("ABCD", true, m2)
 	testOne("AbcD", true, m2)
 }
 
+func Test_basicApiMethodRegister_Methods(t *testing.T) {
+	reg := NewBasicApiMethodRegister(BasicApiMethodRegisterOp{})
+	if len(ListMethods(reg)) != 0 {
+		t.Error("expect empty")
+		return
+	}
+
+	m := reflect.ValueOf(func() {})
+	reg.RegisterMethod(ApiMethod{Name: "c", Value: m})
+	reg.RegisterMethod(ApiMethod{Name: "A", Value: m})
+	reg.RegisterMethod(ApiMethod{Name: "b", Value: m})
+	reg.RegisterMethod(ApiMethod{Name: "B", Value: m}) // 覆盖 b 。
+
+	var names []string
+	for _, v := range ListMethods(reg) {
+		names = append(names, v.Name)
+	}
+
+	if strings.Join(names, ",") != "A,B,c" {
+		t.Errorf("unexpected order: %v", names)
+	}
+}
+
 func Test_basicApiMethodRegister_RegisterMethods(t *testing.T) {
 	reg := NewBasicApiMethodRegister(BasicApiMethodRegisterOp{}).(*basicApiMethodRegister)
 	provider := basicApiMethodRegisterTestProvider{}
@@ -38,7 +38,21 @@ func (r *basicApiResponseBuilder) BuildResponse(state *ApiState, callResult any,
 	var badRequestErr BadRequestError
 	if errors.As(callError, &badRequestErr) {
 		resp.Code = ErrorCodeBadRequest
+		if badRequestErr.Code != 0 {
+			resp.Code = badRequestErr.Code
+		}
+
 		resp.Message = badRequestErr.Message
+		if badRequestErr.Data != nil {
+			resp.Data = badRequestErr.Data
+		}
+		return resp
+	}
+
+	// 方法自行读取 body （如以流的方式）时，超过 LimitRequestBody 限制的大小所产生的错误。
+	if e, ok := AsBodyTooLargeError(state, callError); ok {
+		resp.Code = e.Code
+		resp.Message = e.Message
 		return resp
 	}
 
@@ -3,6 +3,7 @@ package webapi
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 
 	"github.com/cmstar/go-errx"
@@ -68,6 +69,52 @@ func Test_basicApiResponseBuilder_BuildResponse(t *testing.T) {
 		assert.Equal(t, expect, resp)
 	})
 
+	t.Run("bad-request-data", func(t *testing.T) {
+		e := CreateBadRequestError(nil, nil, "x")
+		e.Data = ValidationErrors{{Field: "F"}}
+		state := &ApiState{
+			Data:  "d",
+			Error: e,
+		}
+		resp := b.BuildResponse(state, state.Data, state.Error)
+		expect := ApiResponse[any]{
+			Code:    ErrorCodeBadRequest,
+			Message: "x",
+			Data:    ValidationErrors{{Field: "F"}},
+		}
+		assert.Equal(t, expect, resp)
+	})
+
+	t.Run("bad-request-code", func(t *testing.T) {
+		e := CreateBadRequestError(nil, nil, "x")
+		e.Code = ErrorCodeRequestEntityTooLarge
+		state := &ApiState{
+			Data:  "d",
+			Error: e,
+		}
+		resp := b.BuildResponse(state, state.Data, state.Error)
+		expect := ApiResponse[any]{
+			Code:    ErrorCodeRequestEntityTooLarge,
+			Message: "x",
+			Data:    "d",
+		}
+		assert.Equal(t, expect, resp)
+	})
+
+	t.Run("body-too-large", func(t *testing.T) {
+		state := &ApiState{
+			Data:  nil,
+			Error: fmt.Errorf("read: %w", &http.MaxBytesError{Limit: 1}),
+		}
+		resp := b.BuildResponse(state, state.Data, state.Error)
+		expect := ApiResponse[any]{
+			Code:    ErrorCodeRequestEntityTooLarge,
+			Message: "request body too large",
+			Data:    nil,
+		}
+		assert.Equal(t, expect, resp)
+	})
+
 	t.Run("bad-request-wrap-from-panic", func(t *testing.T) {
 		var err error
 		func() {
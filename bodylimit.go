@@ -0,0 +1,62 @@
+package webapi
+
+import (
+	"errors"
+	"net/http"
+)
+
+// 用作在 ApiState 上存储自定义数据的 key 。
+type customDataKey int
+
+const (
+	// 自定义字段。记录 LimitRequestBody 已被调用过。
+	customData_BodyLimited customDataKey = iota
+)
+
+// MaxBodySize 返回当前请求的 body 允许的最大字节数，小于等于 0 表示不限制。
+// 优先使用 [ApiState.Method] 上的元数据 [ApiMetaMaxBodySize] ；
+// 其次，若 [ApiState.Handler] 是 [*ApiHandlerWrapper] ，使用 [ApiHandlerWrapper.MaxBodySize] 。
+func MaxBodySize(state *ApiState) int64 {
+	if size, ok := GetApiMetaValue[int64](state.Method.Meta, ApiMetaMaxBodySize); ok {
+		return size
+	}
+
+	if w, ok := state.Handler.(*ApiHandlerWrapper); ok {
+		return w.MaxBodySize
+	}
+	return 0
+}
+
+// LimitRequestBody 使用 [http.MaxBytesReader] 将 [ApiState.RawRequest] 的 body 可读取的字节数限制为 [MaxBodySize] 。
+// 读取超过此大小时得到 [*http.MaxBytesError] ，可通过 [AsBodyTooLargeError] 转换为返回给请求者的错误。
+// 对同一个请求，仅第一次调用有效。
+//
+// [CreateHandlerFunc] 在获取到 [ApiState.Method] 之后、调用 [ApiDecoder.Decode] 之前调用此方法。
+// 若在此之前就需要读取 body （例如校验签名），需先行调用。
+func LimitRequestBody(state *ApiState) {
+	if _, ok := state.GetCustomData(customData_BodyLimited); ok {
+		return
+	}
+	state.SetCustomData(customData_BodyLimited, true)
+
+	size := MaxBodySize(state)
+	req := state.RawRequest
+	if size <= 0 || req.Body == nil {
+		return
+	}
+
+	req.Body = http.MaxBytesReader(state.RawResponse, req.Body, size)
+}
+
+// AsBodyTooLargeError 判断 err 是否为读取 body 超过 [LimitRequestBody] 限制的大小而产生的错误（ [*http.MaxBytesError] ），
+// 若是，返回一个 Code 为 [ErrorCodeRequestEntityTooLarge] 的 [BadRequestError] 及 true ；否则返回零值及 false 。
+func AsBodyTooLargeError(state *ApiState, err error) (BadRequestError, bool) {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return BadRequestError{}, false
+	}
+
+	e := CreateBadRequestError(state, err, "request body too large")
+	e.Code = ErrorCodeRequestEntityTooLarge
+	return e, true
+}
@@ -0,0 +1,84 @@
+package webapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cmstar/go-errx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	h := &ApiHandlerWrapper{MaxBodySize: 10}
+	require.Equal(t, int64(0), MaxBodySize(&ApiState{}))
+	require.Equal(t, int64(10), MaxBodySize(&ApiState{Handler: h}))
+	require.Equal(t, int64(10), MaxBodySize(&ApiState{Handler: Wrap(h)}))
+
+	m := ApiMethod{Meta: NewApiMeta(map[string]any{ApiMetaMaxBodySize: int64(20)})}
+	require.Equal(t, int64(20), MaxBodySize(&ApiState{Handler: h, Method: m}))
+
+	m = ApiMethod{Meta: NewApiMeta(map[string]any{ApiMetaMaxBodySize: int64(-1)})}
+	require.Equal(t, int64(-1), MaxBodySize(&ApiState{Handler: h, Method: m}))
+}
+
+func TestLimitRequestBody(t *testing.T) {
+	newState := func(size int64, body string) *ApiState {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		return NewState(httptest.NewRecorder(), r, &ApiHandlerWrapper{MaxBodySize: size})
+	}
+
+	t.Run("within", func(t *testing.T) {
+		state := newState(3, "abc")
+		LimitRequestBody(state)
+		b, err := io.ReadAll(state.RawRequest.Body)
+		require.NoError(t, err)
+		require.Equal(t, "abc", string(b))
+	})
+
+	t.Run("exceed", func(t *testing.T) {
+		state := newState(3, "abcd")
+		LimitRequestBody(state)
+		_, err := io.ReadAll(state.RawRequest.Body)
+
+		e, ok := AsBodyTooLargeError(state, errx.Wrap("read", err))
+		require.True(t, ok)
+		require.Equal(t, ErrorCodeRequestEntityTooLarge, e.Code)
+		require.Equal(t, "request body too large", e.Message)
+	})
+
+	t.Run("no-limit", func(t *testing.T) {
+		state := newState(0, "abcd")
+		body := state.RawRequest.Body
+		LimitRequestBody(state)
+		require.Equal(t, body, state.RawRequest.Body)
+	})
+
+	t.Run("once", func(t *testing.T) {
+		state := newState(3, "abcd")
+		LimitRequestBody(state)
+		body := state.RawRequest.Body
+		LimitRequestBody(state)
+		require.Equal(t, body, state.RawRequest.Body)
+	})
+}
+
+func TestAsBodyTooLargeError(t *testing.T) {
+	_, ok := AsBodyTooLargeError(nil, io.EOF)
+	require.False(t, ok)
+}
+
+func TestApiSetup_SetMaxBodySize(t *testing.T) {
+	h := setupApiHandlerWrapper(&ApiHandlerWrapper{
+		ApiMethodRegister: NewBasicApiMethodRegister(BasicApiMethodRegisterOp{}),
+	})
+	h.RegisterMethod(ApiMethod{Name: "Abc", Value: reflect.ValueOf(func() {})})
+
+	NewEngine().Handle("/", h, nil).SetMaxBodySize("abc", 100)
+
+	m, _ := h.GetMethod("abc")
+	require.Equal(t, int64(100), MaxBodySize(&ApiState{Method: m}))
+}
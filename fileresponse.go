@@ -0,0 +1,141 @@
+package webapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+// FileResponse 表示以文件的形式输出 HTTP body ，用于文件下载。
+//
+// API 方法可将 *FileResponse 作为返回值，如 func() (*webapi.FileResponse, error) 。
+// 输出时，文件内容即 HTTP body ，不使用 [ApiResponse] 信封；支持 Range 、 If-Range 、 If-Modified-Since 等请求头，
+// 以实现断点续传，见 [http.ServeContent] 。
+// 方法返回 error 或 nil 时，仍以信封的形式输出。
+//
+// 目前 slimapi 的 ApiResponseWriter 支持此类型。
+type FileResponse struct {
+	// Name 是文件名，不为空时输出 Content-Disposition 头，并用于推断 Content-Type 。
+	Name string
+
+	// ContentType 指定 Content-Type 头。为空时按 Name 的扩展名推断，仍不能确定时，根据文件开头的数据判断。
+	ContentType string
+
+	// ModTime 是文件的修改时间。不为零值时输出 Last-Modified 头，并用于处理 If-Modified-Since 、 If-Range 等条件请求。
+	ModTime time.Time
+
+	// Inline 为 true 时， Content-Disposition 为 inline ，浏览器通常直接显示文件；否则为 attachment ，浏览器通常下载文件。
+	Inline bool
+
+	content io.ReadSeeker
+}
+
+// NewFileResponse 创建一个输出 content 的全部内容的 FileResponse 。若 content 实现了 [io.Closer] ，
+// 其在输出完成后被关闭（见 [FileResponse.Close] ）。
+func NewFileResponse(name string, content io.ReadSeeker) *FileResponse {
+	return &FileResponse{
+		Name:    name,
+		content: content,
+	}
+}
+
+// NewBytesFileResponse 创建一个输出 data 的 FileResponse 。
+func NewBytesFileResponse(name string, data []byte) *FileResponse {
+	return NewFileResponse(name, bytes.NewReader(data))
+}
+
+// NewFsFileResponse 创建一个输出 f 的 FileResponse ， Name 和 ModTime 取自 f.Stat() 。
+// f 需实现 [io.Seeker] ，如 [*os.File] 或 [embed.FS] 打开的文件；不能是目录。
+// 返回错误时， f 不会被关闭。
+func NewFsFileResponse(f fs.File) (*FileResponse, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, fmt.Errorf("'%s' is a directory", info.Name())
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		return nil, fmt.Errorf("the file '%s' does not implement io.Seeker", info.Name())
+	}
+
+	res := NewFileResponse(info.Name(), content)
+	res.ModTime = info.ModTime()
+	return res, nil
+}
+
+// Content 返回文件的内容。
+func (x *FileResponse) Content() io.ReadSeeker {
+	return x.content
+}
+
+// Close 在文件的内容实现了 [io.Closer] 时，将其关闭；否则不做任何事。
+func (x *FileResponse) Close() error {
+	if c, ok := x.content.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ContentDisposition 返回 Content-Disposition 头的值。 Name 为空时返回空字符串。
+// 文件名包含非 ASCII 字符时，按 RFC 2231 编码。
+func (x *FileResponse) ContentDisposition() string {
+	if x.Name == "" {
+		return ""
+	}
+
+	disposition := "attachment"
+	if x.Inline {
+		disposition = "inline"
+	}
+	return mime.FormatMediaType(disposition, map[string]string{"filename": x.Name})
+}
+
+// DetectContentType 返回 Content-Type 头的值：依次使用 ContentType 、 Name 的扩展名，
+// 最后读取文件开头的数据，使用 [http.DetectContentType] 判断。
+// 读取后，文件被重新定位到开头。
+func (x *FileResponse) DetectContentType() (string, error) {
+	if x.content == nil {
+		return "", errors.New("the FileResponse has no content")
+	}
+
+	if x.ContentType != "" {
+		return x.ContentType, nil
+	}
+
+	if typ := mime.TypeByExtension(path.Ext(x.Name)); typ != "" {
+		return typ, nil
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(x.content, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	if _, err := x.content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// ServeContent 将文件输出到 w 。 w 上的 Content-Type 头需预先设置（见 [FileResponse.DetectContentType] ），
+// 此方法设置 Content-Disposition 头，并使用 [http.ServeContent] 输出 Content-Length 、 Last-Modified 等头和文件内容，
+// 处理 Range 、 If-Range 等请求头。
+func (x *FileResponse) ServeContent(w http.ResponseWriter, r *http.Request) {
+	if disposition := x.ContentDisposition(); disposition != "" {
+		w.Header().Set(HttpHeaderContentDisposition, disposition)
+	}
+
+	// 传入空的文件名，以免 ServeContent 再推断 Content-Type 。
+	http.ServeContent(w, r, "", x.ModTime, x.content)
+}
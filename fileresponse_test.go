@@ -0,0 +1,88 @@
+package webapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileResponse_ContentDisposition(t *testing.T) {
+	f := NewBytesFileResponse("", nil)
+	assert.Equal(t, "", f.ContentDisposition())
+
+	f.Name = "a b.txt"
+	assert.Equal(t, `attachment; filename="a b.txt"`, f.ContentDisposition())
+
+	f.Inline = true
+	assert.Equal(t, `inline; filename="a b.txt"`, f.ContentDisposition())
+
+	f.Name = "中.txt"
+	assert.Equal(t, `inline; filename*=utf-8''%E4%B8%AD.txt`, f.ContentDisposition())
+}
+
+func TestFileResponse_DetectContentType(t *testing.T) {
+	f := NewBytesFileResponse("a", []byte("%PDF-1.4"))
+	f.ContentType = "x/y"
+	typ, err := f.DetectContentType()
+	require.NoError(t, err)
+	assert.Equal(t, "x/y", typ)
+
+	f.ContentType = ""
+	f.Name = "a.png"
+	typ, _ = f.DetectContentType()
+	assert.Equal(t, "image/png", typ)
+
+	// 根据内容判断，之后仍从头读取。
+	f.Name = "a"
+	typ, _ = f.DetectContentType()
+	assert.Equal(t, "application/pdf", typ)
+
+	data, _ := io.ReadAll(f.Content())
+	assert.Equal(t, "%PDF-1.4", string(data))
+
+	_, err = (&FileResponse{}).DetectContentType()
+	assert.Error(t, err)
+}
+
+func TestNewFsFileResponse(t *testing.T) {
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"dir/a.txt": {Data: []byte("abc"), ModTime: modTime},
+	}
+
+	file, err := fsys.Open("dir/a.txt")
+	require.NoError(t, err)
+
+	f, err := NewFsFileResponse(file)
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", f.Name)
+	assert.Equal(t, modTime, f.ModTime)
+	assert.NoError(t, f.Close())
+
+	dir, err := fsys.Open("dir")
+	require.NoError(t, err)
+	_, err = NewFsFileResponse(dir)
+	assert.Error(t, err)
+}
+
+func TestFileResponse_ServeContent(t *testing.T) {
+	f := NewFileResponse("a.txt", strings.NewReader("0123456789"))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Range", "bytes=-3")
+	w := httptest.NewRecorder()
+	w.Header().Set(HttpHeaderContentType, "text/plain")
+	f.ServeContent(w, r)
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "789", w.Body.String())
+	assert.Equal(t, "text/plain", w.Header().Get(HttpHeaderContentType))
+	assert.Equal(t, "attachment; filename=a.txt", w.Header().Get(HttpHeaderContentDisposition))
+}
@@ -0,0 +1,188 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// DefaultHubBufferSize 是 [Hub.BufferSize] 未指定时，每个订阅者的缓冲区能容纳的消息数。
+const DefaultHubBufferSize = 64
+
+// ErrSlowConsumer 在 [SlowConsumerDisconnect] 策略下，订阅者的缓冲区已满时，作为订阅的流的最后一段数据的 error 。
+var ErrSlowConsumer = errors.New("slow consumer")
+
+// SlowConsumerPolicy 指定 [Hub] 的订阅者的缓冲区已满（通常是客户端接收得太慢）时的处理方式。
+type SlowConsumerPolicy int
+
+const (
+	// SlowConsumerDrop 丢弃发给该订阅者的新消息，订阅继续。
+	SlowConsumerDrop SlowConsumerPolicy = iota
+
+	// SlowConsumerDisconnect 结束该订阅：缓冲区中尚未输出的消息被丢弃，流的最后一段数据的 error 为 [ErrSlowConsumer] 。
+	SlowConsumerDisconnect
+)
+
+// Hub 是进程内的发布/订阅中心，用于将同一主题（ topic ）的消息广播给多个订阅者，如多个客户端同时观看的实时看板。
+// 零值可以直接使用；并发安全。
+//
+// API 方法通过 [Hub.Subscribe] 获得订阅的流，将其作为返回值，每条消息即输出为一段数据：
+//
+//	var hub webapi.Hub[Quote]
+//
+//	func (Methods) Watch(ctx context.Context, req struct{ Symbol string }) webapi.EventStream[Quote] {
+//		return hub.Subscribe(ctx, req.Symbol)
+//	}
+//
+//	// 其他地方。
+//	hub.Publish("AAPL", quote)
+//
+// 每个订阅者有独立的缓冲区， [Hub.Publish] 不会因为某个订阅者接收得慢而阻塞，缓冲区满时按 SlowConsumer 处理。
+type Hub[T any] struct {
+	// BufferSize 是每个订阅者的缓冲区能容纳的消息数，小于等于 0 时使用 [DefaultHubBufferSize] 。
+	// 修改只影响此后的订阅。
+	BufferSize int
+
+	// SlowConsumer 指定订阅者的缓冲区已满时的处理方式，默认为 [SlowConsumerDrop] 。
+	SlowConsumer SlowConsumerPolicy
+
+	mu     sync.Mutex
+	topics map[string]map[*hubSubscription[T]]struct{}
+}
+
+// 一个订阅者。
+type hubSubscription[T any] struct {
+	ch     chan T
+	kicked chan struct{} // 在 SlowConsumerDisconnect 策略下被移除时关闭。
+}
+
+// Subscribe 订阅 topic 上的消息，返回的流依次给出此后 [Hub.Publish] 发布的消息。订阅在调用时即生效，而不是在开始迭代时。
+//
+// 以下情况下，流结束，订阅被取消：
+//   - ctx 被取消。通常传入请求的 context （见 [ContextArgumentDecoder] ）；
+//     客户端断开连接，或回执输出失败（见 [ApiState.CancelRequest] ）时，该 context 被取消，订阅随之取消。
+//   - 迭代被中止，即 yield 返回 false 。
+//   - 在 [SlowConsumerDisconnect] 策略下，缓冲区已满，此时最后一段数据的 error 为 [ErrSlowConsumer] 。
+//
+// 流只能被迭代一次。
+func (h *Hub[T]) Subscribe(ctx context.Context, topic string) EventStream[T] {
+	size := h.BufferSize
+	if size <= 0 {
+		size = DefaultHubBufferSize
+	}
+
+	sub := &hubSubscription[T]{
+		ch:     make(chan T, size),
+		kicked: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	if h.topics == nil {
+		h.topics = make(map[string]map[*hubSubscription[T]]struct{})
+	}
+	subs := h.topics[topic]
+	if subs == nil {
+		subs = make(map[*hubSubscription[T]]struct{})
+		h.topics[topic] = subs
+	}
+	subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	// 流可能不会被迭代（如方法随后返回了错误），此时依靠 ctx 取消订阅。
+	stopAfter := context.AfterFunc(ctx, func() { h.unsubscribe(topic, sub) })
+
+	return func(yield func(data T, err error) bool) {
+		defer func() {
+			stopAfter()
+			h.unsubscribe(topic, sub)
+		}()
+
+		var zero T
+		for {
+			// 优先检查是否已被移除，此时缓冲区中通常还有消息。
+			select {
+			case <-sub.kicked:
+				yield(zero, ErrSlowConsumer)
+				return
+			default:
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-sub.kicked:
+				yield(zero, ErrSlowConsumer)
+				return
+
+			case v := <-sub.ch:
+				if !yield(v, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Publish 将 data 发布给 topic 的全部订阅者，返回成功放入缓冲区的订阅者的数量。
+// 不会阻塞：订阅者的缓冲区已满时，按 [Hub.SlowConsumer] 处理。
+func (h *Hub[T]) Publish(topic string, data T) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for sub := range h.topics[topic] {
+		select {
+		case sub.ch <- data:
+			n++
+			continue
+		default:
+		}
+
+		if h.SlowConsumer == SlowConsumerDisconnect {
+			h.removeLocked(topic, sub)
+			close(sub.kicked)
+		}
+	}
+	return n
+}
+
+// Subscribers 返回 topic 当前的订阅者数量。
+func (h *Hub[T]) Subscribers(topic string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.topics[topic])
+}
+
+// Topics 返回当前有订阅者的主题，按字典序排列。
+func (h *Hub[T]) Topics() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	res := make([]string, 0, len(h.topics))
+	for topic := range h.topics {
+		res = append(res, topic)
+	}
+	slices.Sort(res)
+	return res
+}
+
+func (h *Hub[T]) unsubscribe(topic string, sub *hubSubscription[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(topic, sub)
+}
+
+// 从 topic 中移除 sub ，没有订阅者的 topic 也被移除。调用方需持有锁。
+func (h *Hub[T]) removeLocked(topic string, sub *hubSubscription[T]) {
+	subs := h.topics[topic]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.topics, topic)
+	}
+}
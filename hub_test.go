@@ -0,0 +1,126 @@
+package webapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// 在另一个 goroutine 中迭代 stream ，将数据发送到返回的 channel ，迭代结束时关闭 channel 。
+func hubTestConsume[T any](stream EventStream[T]) <-chan any {
+	out := make(chan any, 100)
+	go func() {
+		defer close(out)
+		for v, err := range stream {
+			if err != nil {
+				out <- err
+				continue
+			}
+			out <- v
+		}
+	}()
+	return out
+}
+
+func hubTestReceive(t *testing.T, ch <-chan any) any {
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+		return nil
+	}
+}
+
+func TestHub(t *testing.T) {
+	t.Run("broadcast", func(t *testing.T) {
+		var hub Hub[int]
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		a := hub.Subscribe(ctx, "t")
+		b := hub.Subscribe(ctx, "t")
+		other := hub.Subscribe(ctx, "other")
+		require.Equal(t, 2, hub.Subscribers("t"))
+		require.Equal(t, []string{"other", "t"}, hub.Topics())
+
+		// 订阅在调用 Subscribe 时即生效。
+		require.Equal(t, 2, hub.Publish("t", 1))
+		require.Equal(t, 0, hub.Publish("none", 1))
+
+		chA, chB := hubTestConsume(a), hubTestConsume(b)
+		require.Equal(t, 1, hubTestReceive(t, chA))
+		require.Equal(t, 1, hubTestReceive(t, chB))
+
+		hub.Publish("t", 2)
+		require.Equal(t, 2, hubTestReceive(t, chA))
+		require.Equal(t, 2, hubTestReceive(t, chB))
+
+		// ctx 被取消后，流结束，订阅被取消。
+		cancel()
+		_, ok := <-chA
+		require.False(t, ok)
+		_, ok = <-chB
+		require.False(t, ok)
+
+		// 没有被迭代的订阅，依靠 ctx 取消。
+		require.Eventually(t, func() bool { return len(hub.Topics()) == 0 }, 5*time.Second, time.Millisecond)
+		for range other {
+			require.Fail(t, "should not yield")
+		}
+	})
+
+	t.Run("break", func(t *testing.T) {
+		var hub Hub[int]
+		stream := hub.Subscribe(context.Background(), "t")
+		hub.Publish("t", 1)
+
+		for range stream {
+			break
+		}
+		require.Equal(t, 0, hub.Subscribers("t"))
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		hub := Hub[int]{BufferSize: 2}
+		stream := hub.Subscribe(context.Background(), "t")
+
+		require.Equal(t, 1, hub.Publish("t", 1))
+		require.Equal(t, 1, hub.Publish("t", 2))
+		require.Equal(t, 0, hub.Publish("t", 3))
+		require.Equal(t, 1, hub.Subscribers("t"))
+
+		var got []int
+		for v, err := range stream {
+			require.NoError(t, err)
+			got = append(got, v)
+			if len(got) == 2 {
+				break
+			}
+		}
+		require.Equal(t, []int{1, 2}, got)
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		hub := Hub[int]{BufferSize: 1, SlowConsumer: SlowConsumerDisconnect}
+		slow := hub.Subscribe(context.Background(), "t")
+		fast := hubTestConsume(hub.Subscribe(context.Background(), "t"))
+
+		hub.Publish("t", 1)
+		require.Equal(t, 1, hubTestReceive(t, fast))
+
+		// slow 没有在迭代，缓冲区已满，被移除。
+		require.Equal(t, 1, hub.Publish("t", 2))
+		require.Equal(t, 1, hub.Subscribers("t"))
+		require.Equal(t, 2, hubTestReceive(t, fast))
+
+		var errs []error
+		for v, err := range slow {
+			require.Equal(t, 0, v)
+			errs = append(errs, err)
+		}
+		require.Equal(t, []error{ErrSlowConsumer}, errs)
+	})
+}
@@ -124,6 +124,30 @@ func TestFiles(t *testing.T) {
 		}
 		assert.Equal(t, want, state.LogMessage)
 	})
+
+	t.Run("same-name", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		w := multipart.NewWriter(buf)
+
+		file0, _ := w.CreateFormFile("f", "f0")
+		file0.Write(make([]byte, 1))
+		file1, _ := w.CreateFormFile("f", "f1")
+		file1.Write(make([]byte, 2))
+
+		w.Close()
+		state := buildState(buf, w)
+
+		// 同名的文件按上传的顺序输出。
+		want := []any{
+			"File0", "f0",
+			"Length0", int64(1),
+			"ContentType0", "application/octet-stream",
+			"File1", "f1",
+			"Length1", int64(2),
+			"ContentType1", "application/octet-stream",
+		}
+		assert.Equal(t, want, state.LogMessage)
+	})
 }
 
 func TestContentType(t *testing.T) {
@@ -73,7 +73,7 @@ func (err) Setup(state *webapi.ApiState) {
 //   - LengthX 文件长度。
 //   - ContentTypeX 文件的 Content-Type 。
 //
-// 不会输出非文件的部分。
+// 文件按 name 排序，同名的多个文件按上传的顺序输出。不会输出非文件的部分。
 //
 // 这是一个单例。
 var Files = files{}
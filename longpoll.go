@@ -0,0 +1,84 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultLongPollMaxWait 是没有通过 [ApiMetaLongPollMaxWait] 或 [ApiHandlerWrapper.LongPollMaxWait] 指定时，长轮询单次请求的最长等待时间。
+const DefaultLongPollMaxWait = 30 * time.Second
+
+// LongPollMaxWait 返回当前请求的长轮询单次最长等待时间。
+// 优先使用 [ApiState.Method] 上的元数据 [ApiMetaLongPollMaxWait] ；
+// 其次，若 [ApiState.Handler] 是 [*ApiHandlerWrapper] ，使用 [ApiHandlerWrapper.LongPollMaxWait] 。
+// 均未指定或小于等于 0 时，为 [DefaultLongPollMaxWait] 。
+func LongPollMaxWait(state *ApiState) time.Duration {
+	maxWait, ok := GetApiMetaValue[time.Duration](state.Method.Meta, ApiMetaLongPollMaxWait)
+	if !ok {
+		if w, ok := state.Handler.(*ApiHandlerWrapper); ok {
+			maxWait = w.LongPollMaxWait
+		}
+	}
+
+	if maxWait <= 0 {
+		return DefaultLongPollMaxWait
+	}
+	return maxWait
+}
+
+// LongPoll 描述一次长轮询请求。用于客户端所在的网络环境（如某些代理服务器）不能正常使用 SSE 等流式输出的场景：
+// 方法阻塞等待新数据，在有数据或等待超时后返回，客户端收到回执后立即再次请求。
+//
+// 方法的参数表中可以有此类型的参数，由 [ApiDecoder] 赋值，例如 SlimAPI 从元参数 ~cursor 和 ~wait 读取。
+// 通常与 [WaitLongPoll] 一起使用：
+//
+//	func (Methods) Poll(ctx context.Context, lp webapi.LongPoll, req struct{ Topic string }) (webapi.LongPollResult[[]Item], error) {
+//		return webapi.WaitLongPoll(ctx, lp, func(ctx context.Context) ([]Item, string, error) {
+//			return store.WaitAfter(ctx, req.Topic, lp.Cursor) // 阻塞直到有 lp.Cursor 之后的数据，或 ctx 结束。
+//		})
+//	}
+type LongPoll struct {
+	// Cursor 是客户端已收到的数据的游标（如版本号），由上一次请求的 [LongPollResult.Cursor] 给出，首次请求时为空字符串。
+	Cursor string
+
+	// Wait 是本次请求最多等待的时长，不超过 [LongPollMaxWait] 。为 0 时不等待，仅检查是否已有新数据。
+	Wait time.Duration
+}
+
+// LongPollResult 是长轮询方法的返回值，见 [WaitLongPoll] 。
+type LongPollResult[T any] struct {
+	// Changed 表示是否有新数据。为 false 时表示等待超时仍没有新数据， Data 为零值，客户端使用原游标再次请求即可。
+	Changed bool
+
+	// Cursor 是 Data 对应的游标，客户端下次请求时给出。没有新数据时，同请求中的游标。
+	Cursor string
+
+	// Data 是新数据。
+	Data T
+}
+
+// WaitLongPoll 在 lp.Wait 内等待 lp.Cursor 之后的新数据。
+//
+// fn 接收一个从 ctx 派生、在 lp.Wait 后超时的 context ，它应阻塞直到有新数据，返回数据及其游标；
+// 或在该 context 结束时返回其 Err() 。 lp.Wait 为 0 时，该 context 已经超时， fn 应先检查是否已有新数据。
+//
+// 返回：
+//   - fn 给出数据时， Changed 为 true 的结果；
+//   - 等待超时（ fn 返回 [context.DeadlineExceeded] ）时， Changed 为 false 、 Cursor 为 lp.Cursor 的结果；
+//   - ctx 被取消（如客户端断开连接）或 fn 返回其他错误时，该错误。
+func WaitLongPoll[T any](ctx context.Context, lp LongPoll, fn func(ctx context.Context) (data T, cursor string, err error)) (LongPollResult[T], error) {
+	waitCtx, cancel := context.WithTimeout(ctx, max(lp.Wait, 0))
+	defer cancel()
+
+	data, cursor, err := fn(waitCtx)
+	if err == nil {
+		return LongPollResult[T]{Changed: true, Cursor: cursor, Data: data}, nil
+	}
+
+	// 仅等待超时视为没有新数据； ctx 自身结束时，原样返回错误。
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return LongPollResult[T]{Cursor: lp.Cursor}, nil
+	}
+	return LongPollResult[T]{}, err
+}
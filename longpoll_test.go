@@ -0,0 +1,80 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLongPollMaxWait(t *testing.T) {
+	h := &ApiHandlerWrapper{LongPollMaxWait: time.Second}
+	require.Equal(t, DefaultLongPollMaxWait, LongPollMaxWait(&ApiState{}))
+	require.Equal(t, time.Second, LongPollMaxWait(&ApiState{Handler: h}))
+	require.Equal(t, time.Second, LongPollMaxWait(&ApiState{Handler: Wrap(h)}))
+
+	m := ApiMethod{Meta: NewApiMeta(map[string]any{ApiMetaLongPollMaxWait: 2 * time.Second})}
+	require.Equal(t, 2*time.Second, LongPollMaxWait(&ApiState{Handler: h, Method: m}))
+
+	m = ApiMethod{Meta: NewApiMeta(map[string]any{ApiMetaLongPollMaxWait: time.Duration(-1)})}
+	require.Equal(t, DefaultLongPollMaxWait, LongPollMaxWait(&ApiState{Handler: h, Method: m}))
+}
+
+func TestWaitLongPoll(t *testing.T) {
+	// 阻塞直到 ch 给出数据或 ctx 结束。
+	waitOn := func(ch <-chan int) func(ctx context.Context) (int, string, error) {
+		return func(ctx context.Context) (int, string, error) {
+			select {
+			case <-ctx.Done():
+				return 0, "", ctx.Err()
+			case v := <-ch:
+				return v, "c2", nil
+			}
+		}
+	}
+
+	t.Run("changed", func(t *testing.T) {
+		ch := make(chan int, 1)
+		ch <- 1
+		res, err := WaitLongPoll(context.Background(), LongPoll{Cursor: "c1", Wait: time.Second}, waitOn(ch))
+		require.NoError(t, err)
+		require.Equal(t, LongPollResult[int]{Changed: true, Cursor: "c2", Data: 1}, res)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		start := time.Now()
+		res, err := WaitLongPoll(context.Background(), LongPoll{Cursor: "c1", Wait: 20 * time.Millisecond}, waitOn(nil))
+		require.NoError(t, err)
+		require.Equal(t, LongPollResult[int]{Cursor: "c1"}, res)
+		require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("no-wait", func(t *testing.T) {
+		res, err := WaitLongPoll(context.Background(), LongPoll{Cursor: "c1"}, waitOn(nil))
+		require.NoError(t, err)
+		require.Equal(t, LongPollResult[int]{Cursor: "c1"}, res)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := WaitLongPoll(ctx, LongPoll{Wait: time.Second}, waitOn(nil))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("parent-deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := WaitLongPoll(ctx, LongPoll{Wait: time.Second}, waitOn(nil))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := WaitLongPoll(context.Background(), LongPoll{Wait: time.Second}, func(ctx context.Context) (int, string, error) {
+			return 0, "", errors.New("fail")
+		})
+		require.EqualError(t, err, "fail")
+	})
+}
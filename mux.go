@@ -28,7 +28,7 @@ func AllRouteParams(r *http.Request) []RouteParam {
 	}
 
 	ln := len(c.URLParams.Keys)
-	res := make([]RouteParam, ln)
+	res := make([]RouteParam, 0, ln)
 	for i := 0; i < ln; i++ {
 		res = append(res, RouteParam{
 			Key:   c.URLParams.Keys[i],
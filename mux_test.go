@@ -0,0 +1,18 @@
+package webapi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllRouteParams(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	require.Nil(t, AllRouteParams(r))
+
+	r = SetRouteParams(r, map[string]string{"a": "1"})
+	r = SetRouteParams(r, map[string]string{"b": "2"})
+	require.Equal(t, []RouteParam{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}, AllRouteParams(r))
+	require.Equal(t, "2", GetRouteParam(r, "b"))
+}
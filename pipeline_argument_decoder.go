@@ -1,6 +1,7 @@
 package webapi
 
 import (
+	"context"
 	"reflect"
 )
 
@@ -43,8 +44,45 @@ func (apiStateArgumentDecoder) DecodeArg(state *ApiState, index int, argType ref
 	return true, state, nil
 }
 
+// LastEventIdArgumentDecoder 是一个 [ArgumentDecoder] ，它用于解析并赋值 [LastEventId] ，其值来自 HTTP 头 Last-Event-ID 。
+//
+// 这是一个单例。
+var LastEventIdArgumentDecoder = lastEventIdArgumentDecoder{}
+
+type lastEventIdArgumentDecoder struct{}
+
+var _ ArgumentDecoder = (*lastEventIdArgumentDecoder)(nil)
+
+var typeLastEventId = reflect.TypeOf(LastEventId(""))
+
+func (lastEventIdArgumentDecoder) DecodeArg(state *ApiState, index int, argType reflect.Type) (ok bool, v any, err error) {
+	if argType != typeLastEventId {
+		return false, nil, nil
+	}
+	return true, LastEventId(state.RawRequest.Header.Get(HttpHeaderLastEventId)), nil
+}
+
+// ContextArgumentDecoder 是一个 [ArgumentDecoder] ，它用于解析并赋值 [context.Context] ，其值为 [ApiState.RawRequest] 的 Context 。
+// 客户端断开连接时，该 context 被取消，方法（特别是返回 [EventStream] 等流式输出的方法）可据此及时结束。
+//
+// 这是一个单例。
+var ContextArgumentDecoder = contextArgumentDecoder{}
+
+type contextArgumentDecoder struct{}
+
+var _ ArgumentDecoder = (*contextArgumentDecoder)(nil)
+
+var typeContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+func (contextArgumentDecoder) DecodeArg(state *ApiState, index int, argType reflect.Type) (ok bool, v any, err error) {
+	if argType != typeContext {
+		return false, nil, nil
+	}
+	return true, state.RawRequest.Context(), nil
+}
+
 // ArgumentDecoderPipeline 是 [ArgumentDecoder] 组成的管道。
-// 实现 [ApiDecoder] ，此实现要求被调用的每个方法，其参数表中的参数类型是不重复的。
+// 实现 [ApiDecoder] ，此实现要求被调用的每个方法，其参数表中没有名称（见 [ApiMetaParamNames] ）的参数的类型是不重复的。
 //
 // 在 [ApiDecoder.Decode] 时，将依次执行管道内的每个 [ArgumentDecoder.DecodeArg] 。
 // 可以通过增减和调整元素的顺序定制执行的过程。
@@ -53,10 +91,11 @@ type ArgumentDecoderPipeline []ArgumentDecoder
 var _ ApiDecoder = (*ArgumentDecoderPipeline)(nil)
 
 // NewArgumentDecoderPipeline 返回一个 [ArgumentDecoderPipeline] 。
-// 其第一个元素是预定义的 [ApiStateDecodeFunc] ，用于赋值 [*ApiState] ； decodeFuncs 会追加在后面。
+// 其前几个元素是预定义的 [ApiStateArgumentDecoder] 、 [ContextArgumentDecoder] 和 [LastEventIdArgumentDecoder] ，
+// 分别用于赋值 [*ApiState] 、 [context.Context] 和 [LastEventId] ； decodeFuncs 会追加在后面。
 func NewArgumentDecoderPipeline(d ...ArgumentDecoder) ArgumentDecoderPipeline {
-	p := make([]ArgumentDecoder, 0, len(d)+1)
-	p = append(p, ApiStateArgumentDecoder)
+	p := make([]ArgumentDecoder, 0, len(d)+3)
+	p = append(p, ApiStateArgumentDecoder, ContextArgumentDecoder, LastEventIdArgumentDecoder)
 	p = append(p, d...)
 	return p
 }
@@ -70,9 +109,9 @@ func (d ArgumentDecoderPipeline) Decode(state *ApiState) {
 	for i := 0; i < numIn; i++ {
 		argType := methodType.In(i)
 
-		// 参数表里一种类型只能出现一次。
-		for j := 0; j < len(args); j++ {
-			if args[j].Type() == argType {
+		// 参数表里没有名称的参数，一种类型只能出现一次。
+		for j := 0; j < len(args) && state.Method.ParamName(i) == ""; j++ {
+			if state.Method.ParamName(j) == "" && methodType.In(j) == argType {
 				PanicApiError(state, nil, "method '%s' arg%d %v: argument type cannot be duplicated", state.Name, i, argType)
 			}
 		}
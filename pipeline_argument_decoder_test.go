@@ -1,7 +1,9 @@
 package webapi
 
 import (
+	"context"
 	"errors"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 
@@ -70,6 +72,35 @@ func TestDecodeFuncPipeline(t *testing.T) {
 		run(func(ApiState) {})
 	})
 
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		s := &ApiState{
+			RawRequest: httptest.NewRequest("GET", "/", nil).WithContext(ctx),
+			Method: ApiMethod{
+				Value: reflect.ValueOf(func(context.Context) {}),
+			},
+		}
+		decoder.Decode(s)
+		assert.Equal(t, ctx, s.Args[0].Interface())
+	})
+
+	t.Run("last-event-id", func(t *testing.T) {
+		s := &ApiState{
+			RawRequest: httptest.NewRequest("GET", "/", nil),
+			Method: ApiMethod{
+				Value: reflect.ValueOf(func(LastEventId) {}),
+			},
+		}
+		decoder.Decode(s)
+		assert.Equal(t, LastEventId(""), s.Args[0].Interface())
+
+		s.RawRequest.Header.Set("Last-Event-ID", "12")
+		decoder.Decode(s)
+		assert.Equal(t, LastEventId("12"), s.Args[0].Interface())
+	})
+
 	t.Run("string-int", func(t *testing.T) {
 		s := run(func(string, int) {})
 		assert.Equal(t, 2, len(s.Args))
@@ -93,6 +124,21 @@ func TestDecodeFuncPipeline(t *testing.T) {
 		run(func(string, string) {})
 	})
 
+	t.Run("named-duplicate-type", func(t *testing.T) {
+		s := &ApiState{
+			Method: ApiMethod{
+				Value: reflect.ValueOf(func(string, string, int, int) {}),
+				Meta:  NewApiMeta(map[string]any{ApiMetaParamNames: []string{"", "b", "c"}}),
+			},
+		}
+		decoder.Decode(s)
+		assert.Equal(t, 4, len(s.Args))
+
+		// 没有名称的参数之间，类型依然不能重复。
+		s.Method.Value = reflect.ValueOf(func(string, string, int, string) {})
+		assert.PanicsWithError(t, "method '' arg3 string: argument type cannot be duplicated", func() { decoder.Decode(s) })
+	})
+
 	t.Run("panic-unsupported-type", func(t *testing.T) {
 		defer func() {
 			r := recover()
@@ -0,0 +1,174 @@
+package slimapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/cmstar/go-webapi"
+)
+
+// optionalState 记录 [Optional] 的值的来源。
+type optionalState uint8
+
+const (
+	optionalAbsent optionalState = iota // 没有给出值。
+	optionalNull                        // 给出了 null 。
+	optionalSet                         // 给出了非 null 的值。
+)
+
+// Optional 用于 struct 参数的字段，记录客户端是否给出了此字段，以区分“未给出”、“给出了 null ”和“给出了值”三种情况。
+// 常用于 PATCH 风格的更新方法，仅更新客户端给出的字段：
+//
+//	type UpdateUserRequest struct {
+//	    Id   int
+//	    Name slimapi.Optional[string]
+//	    Age  slimapi.Optional[int]
+//	}
+//
+//	if req.Name.IsPresent() { ... }
+//
+// 零值表示未给出。 [Conv] 转换时，参数中没有对应的 key 时保持零值；值为 nil （ JSON 中的 null ）时为 null ；
+// 其余情况下将值转换为 T 。对于 GET 和表单格式，无法给出 null ，出现参数即视为给出了值（可以是空字符串）。
+//
+// 字段上的 validate tag （见 [webapi.ValidateStruct] ）作用于给出的值： required 要求给出了非 null 的值（可以是零值），
+// 未给出或为 null 时，跳过其余规则；给出了值时，即使是零值也会执行其余规则。
+//
+// 用于回执时，未给出和 null 均序列化为 JSON null 。
+// 由于实现了 IsZero() ，字段可使用 json:",omitzero" ，使未给出的字段不出现在 JSON 中。
+type Optional[T any] struct {
+	value T
+	state optionalState
+}
+
+var _ json.Marshaler = Optional[int]{}
+var _ json.Unmarshaler = (*Optional[int])(nil)
+var _ webapi.OptionalValue = Optional[int]{}
+
+// OptionalOf 返回一个给出了值 v 的 [Optional] 。
+func OptionalOf[T any](v T) Optional[T] {
+	return Optional[T]{value: v, state: optionalSet}
+}
+
+// OptionalNull 返回一个给出了 null 的 [Optional] 。
+func OptionalNull[T any]() Optional[T] {
+	return Optional[T]{state: optionalNull}
+}
+
+// IsPresent 判断是否给出了此字段，值可以是 null 。
+func (x Optional[T]) IsPresent() bool {
+	return x.state != optionalAbsent
+}
+
+// IsNull 判断是否给出了 null 。
+func (x Optional[T]) IsNull() bool {
+	return x.state == optionalNull
+}
+
+// HasValue 判断是否给出了非 null 的值。
+func (x Optional[T]) HasValue() bool {
+	return x.state == optionalSet
+}
+
+// Value 返回给出的值。没有给出值（未给出或为 null ）时，返回 T 的零值。
+func (x Optional[T]) Value() T {
+	return x.value
+}
+
+// Get 返回给出的值，及是否给出了非 null 的值。
+func (x Optional[T]) Get() (T, bool) {
+	return x.value, x.state == optionalSet
+}
+
+// ValueOr 返回给出的值；没有给出值（未给出或为 null ）时，返回 def 。
+func (x Optional[T]) ValueOr(def T) T {
+	if x.state == optionalSet {
+		return x.value
+	}
+	return def
+}
+
+// ElemType 返回 T 的类型。实现 [webapi.OptionalValue] 。
+func (x Optional[T]) ElemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// GetAny 同 [Optional.Get] ，返回 any 类型的值。实现 [webapi.OptionalValue] 。
+func (x Optional[T]) GetAny() (any, bool) {
+	return x.value, x.state == optionalSet
+}
+
+// IsZero 判断是否未给出此字段。用于支持 json:",omitzero" 。
+func (x Optional[T]) IsZero() bool {
+	return x.state == optionalAbsent
+}
+
+// String 实现 fmt.Stringer 。未给出时返回 <absent> ，为 null 时返回 <null> 。
+func (x Optional[T]) String() string {
+	switch x.state {
+	case optionalAbsent:
+		return "<absent>"
+	case optionalNull:
+		return "<null>"
+	}
+	return fmt.Sprint(x.value)
+}
+
+// MarshalJSON 实现 json.Marshaler 。给出了值时，序列化其值；否则为 null 。
+func (x Optional[T]) MarshalJSON() ([]byte, error) {
+	if x.state != optionalSet {
+		return []byte("null"), nil
+	}
+	return json.Marshal(x.value)
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 。 JSON 中没有出现的字段不会调用此方法，保持未给出的状态。
+func (x *Optional[T]) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		var zero T
+		x.value, x.state = zero, optionalNull
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	x.value, x.state = v, optionalSet
+	return nil
+}
+
+// optionalField 由 *Optional[T] 实现，用于在不知道 T 的情况下操作 [Optional] 。
+type optionalField interface {
+	ElemType() reflect.Type
+	setConverted(value any, convert func(value any, typ reflect.Type) (any, error)) error
+}
+
+// setConverted 设置 Optional 的值，使用 convert 将 value 转换为 T 。
+func (x *Optional[T]) setConverted(value any, convert func(value any, typ reflect.Type) (any, error)) error {
+	if value == nil {
+		x.state = optionalNull
+		return nil
+	}
+
+	v, err := convert(value, x.ElemType())
+	if err != nil {
+		return err
+	}
+
+	if v != nil {
+		x.value = v.(T)
+	}
+	x.state = optionalSet
+	return nil
+}
+
+var typeOptionalField = reflect.TypeOf((*optionalField)(nil)).Elem()
+
+// optionalElemType 若 typ 是 [Optional] ，返回其 T 。
+func optionalElemType(typ reflect.Type) (reflect.Type, bool) {
+	if typ.Kind() != reflect.Struct || !reflect.PointerTo(typ).Implements(typeOptionalField) {
+		return nil, false
+	}
+	return reflect.New(typ).Interface().(optionalField).ElemType(), true
+}
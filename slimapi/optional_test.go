@@ -0,0 +1,139 @@
+package slimapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptional(t *testing.T) {
+	t.Run("absent", func(t *testing.T) {
+		var v Optional[int]
+		assert.False(t, v.IsPresent())
+		assert.False(t, v.IsNull())
+		assert.False(t, v.HasValue())
+		assert.True(t, v.IsZero())
+		assert.Equal(t, 0, v.Value())
+		assert.Equal(t, 9, v.ValueOr(9))
+		assert.Equal(t, "<absent>", v.String())
+
+		_, ok := v.Get()
+		assert.False(t, ok)
+	})
+
+	t.Run("null", func(t *testing.T) {
+		v := OptionalNull[int]()
+		assert.True(t, v.IsPresent())
+		assert.True(t, v.IsNull())
+		assert.False(t, v.HasValue())
+		assert.False(t, v.IsZero())
+		assert.Equal(t, 9, v.ValueOr(9))
+		assert.Equal(t, "<null>", v.String())
+	})
+
+	t.Run("set", func(t *testing.T) {
+		v := OptionalOf(0)
+		assert.True(t, v.IsPresent())
+		assert.False(t, v.IsNull())
+		assert.True(t, v.HasValue())
+		assert.False(t, v.IsZero())
+		assert.Equal(t, 0, v.ValueOr(9))
+		assert.Equal(t, "0", v.String())
+
+		got, ok := v.Get()
+		assert.True(t, ok)
+		assert.Equal(t, 0, got)
+	})
+}
+
+func TestOptional_json(t *testing.T) {
+	type S struct {
+		A Optional[int]
+		B Optional[string]
+		C Optional[[]int] `json:",omitzero"`
+		D Optional[int]   `json:",omitzero"`
+		E Optional[Time]  `json:",omitzero"`
+	}
+
+	t.Run("marshal", func(t *testing.T) {
+		tm := Time(time.Date(2022, 11, 03, 7, 30, 6, 0, time.UTC))
+		s := S{
+			B: OptionalNull[string](),
+			C: OptionalOf([]int{1, 2}),
+			E: OptionalOf(tm),
+		}
+		j, err := json.Marshal(s)
+		require.NoError(t, err)
+		assert.Equal(t, `{"A":null,"B":null,"C":[1,2],"E":"2022-11-03 07:30:06"}`, string(j))
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		var s S
+		require.NoError(t, json.Unmarshal([]byte(`{"A":null,"B":"b","C":[1]}`), &s))
+		assert.Equal(t, OptionalNull[int](), s.A)
+		assert.Equal(t, OptionalOf("b"), s.B)
+		assert.Equal(t, OptionalOf([]int{1}), s.C)
+		assert.False(t, s.D.IsPresent())
+	})
+
+	t.Run("unmarshal-error", func(t *testing.T) {
+		var s S
+		require.Error(t, json.Unmarshal([]byte(`{"A":"x"}`), &s))
+	})
+}
+
+func TestOptional_conv(t *testing.T) {
+	type Inner struct {
+		N Optional[int]
+	}
+
+	type S struct {
+		I  Optional[int]
+		S  Optional[string]
+		T  Optional[Time]
+		Sl Optional[[]int]
+		In Optional[Inner]
+		P  *Optional[int]
+	}
+
+	t.Run("states", func(t *testing.T) {
+		res, err := Conv.ConvertType(map[string]any{
+			"i":  nil,
+			"s":  "",
+			"t":  "2022-11-03 07:30:06",
+			"sl": "1~2",
+			"in": map[string]any{"n": "3"},
+		}, reflect.TypeOf(S{}))
+		require.NoError(t, err)
+
+		assert.Equal(t, S{
+			I:  OptionalNull[int](),
+			S:  OptionalOf(""),
+			T:  OptionalOf(Time(time.Date(2022, 11, 03, 7, 30, 6, 0, time.UTC))),
+			Sl: OptionalOf([]int{1, 2}),
+			In: OptionalOf(Inner{N: OptionalOf(3)}),
+		}, res)
+	})
+
+	t.Run("ptr", func(t *testing.T) {
+		res, err := Conv.ConvertType(map[string]any{"p": 1}, reflect.TypeOf(S{}))
+		require.NoError(t, err)
+		v := OptionalOf(1)
+		assert.Equal(t, &v, res.(S).P)
+	})
+
+	t.Run("same-type", func(t *testing.T) {
+		res, err := Conv.ConvertType(OptionalOf(1), reflect.TypeOf(Optional[int]{}))
+		require.NoError(t, err)
+		assert.Equal(t, OptionalOf(1), res)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := Conv.ConvertType(map[string]any{"i": "x"}, reflect.TypeOf(S{}))
+		require.Error(t, err)
+	})
+}
@@ -13,6 +13,8 @@ const (
 	meta_Param_Method   = "~method"
 	meta_Param_Format   = "~format"
 	meta_Param_Callback = "~callback"
+	meta_Param_Cursor   = "~cursor" // 长轮询的游标，见 LongPollArgumentDecoder 。
+	meta_Param_Wait     = "~wait"   // 长轮询的等待秒数，见 LongPollArgumentDecoder 。
 
 	// URL 上表示请求格式的串。用于兼容不方便指定 Content-Type 的情况。
 	meta_RequestFormat_Json   = "json"
@@ -21,7 +23,7 @@ const (
 	meta_ResponseFormat_Plain = "plain"
 
 	// 解析请求的 body 部分时最大可用的内存，读取 multipart-form-data 型数据时，超过此字节数将使用临时文件存储。
-	// 另外，也是单独使用代码读取 body 时，允许的最大的字节数。
+	// 另外，也是没有设置 webapi.MaxBodySize 时，单独使用代码读取 body 允许的最大的字节数。
 	maxMemorySizeParseRequestBody = 10 * 1024 * 1024
 )
 
@@ -37,6 +39,12 @@ const (
 
 	// 自定义字段。记录当前请求 body 部分， ApiDecoder.Decode() 在执行后，将读取到的 body 存储在此字段上。
 	customData_BufferedBody
+
+	// 自定义字段。记录解析得到的参数表，方法有多个参数时，各参数共用，避免重复读取 body 。
+	customData_ParamMap
+
+	// 自定义字段。记录 body 已作为流交给方法的参数，见 StreamArgumentDecoder 。
+	customData_BodyStream
 )
 
 // NewSlimApiHandler 创建一个实现 SlimAPI 协议的 webapi.ApiHandlerWrapper 。
@@ -51,6 +59,7 @@ func NewSlimApiHandler(name string) *webapi.ApiHandlerWrapper {
 		ApiResponseBuilder: webapi.NewBasicApiResponseBuilder(),
 		ApiMethodRegister: webapi.NewBasicApiMethodRegister(webapi.BasicApiMethodRegisterOp{
 			SupportStreamingResponse: true,
+			CheckMethod:              checkFromTags,
 		}),
 		ApiUserHostResolver: webapi.NewBasicApiUserHostResolver(),
 		ApiResponseWriter:   NewSlimApiResponseWriter(),
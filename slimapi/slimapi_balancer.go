@@ -0,0 +1,407 @@
+package slimapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BalanceStrategy 指定 [LoadBalancer] 选择节点的策略。
+type BalanceStrategy int
+
+const (
+	// BalanceRoundRobin 依次轮流选择每个节点。这是默认策略。
+	BalanceRoundRobin BalanceStrategy = iota
+
+	// BalanceLeastPending 选择当前未完成的请求数最少的节点。
+	// 对于流式响应，请求在其 body 被关闭时才算完成。
+	BalanceLeastPending
+
+	// BalanceConsistentHash 基于一致性哈希选择节点，相同的散列键总是落在同一个节点上（除非节点不可用）。
+	// 散列键由 [LoadBalancerOp.HashKey] 给出，使用此策略时必须提供。
+	BalanceConsistentHash
+)
+
+const (
+	// 节点发生传输错误后，默认被暂时剔除的时长。
+	defaultEjectDuration = 30 * time.Second
+
+	// 一致性哈希中，每个节点的虚拟节点数量。
+	consistentHashReplicas = 100
+)
+
+// LoadBalancerOp 用于初始化 [LoadBalancer] 。
+type LoadBalancerOp struct {
+	// Uris 是一组静态的基础 URL ，如 http://10.0.0.1:8080/api 。与 Resolver 至少需提供一个，都提供时 Resolver 优先。
+	Uris []string
+
+	// Resolver 用于动态获取基础 URL 的列表。每次调用均会执行一次，应实现为快速返回（如读取缓存）。
+	Resolver func() ([]string, error)
+
+	// Strategy 是选择节点的策略。
+	Strategy BalanceStrategy
+
+	// HashKey 用于 [BalanceConsistentHash] ，返回请求的散列键，如用户 ID 。使用该策略时必须提供。
+	// 请求的 URL 路径对应于被调用的方法，不适合作为散列键，否则同一方法的请求总是落在同一个节点上。
+	HashKey func(r *http.Request) string
+
+	// EjectDuration 是节点发生传输错误后被暂时剔除的时长。为 0 时使用默认值 30 秒。
+	// 请求的 context 被取消或超时、超过 [http.Client.Timeout] 导致的错误不会使节点被剔除。
+	// 若全部节点均被剔除，则忽略剔除状态，仍从全部节点中选择。
+	// 节点不再由 Resolver 给出后，其剔除状态随之丢弃。
+	EjectDuration time.Duration
+
+	// MaxAttempts 是单次调用最多尝试的节点数量（含第一次）。为 0 时，最多尝试全部节点各一次。
+	MaxAttempts int
+}
+
+// LoadBalancer 用于 [SlimApiInvoker] 在多个服务节点间做客户端负载均衡。
+//
+// 节点发生传输错误（如连接被拒绝）时，会被暂时剔除（被动健康检查）；
+// 传输错误及 HTTP 502/503/504 被视为可重试的失败，调用将自动转移到其他节点。
+//
+// 此类型是线程安全的，可被多个 [SlimApiInvoker] 共享。
+type LoadBalancer struct {
+	op LoadBalancerOp
+
+	mu        sync.Mutex
+	counter   uint64
+	endpoints map[string]*endpointState
+	ringKey   string
+	ring      []ringNode
+}
+
+type endpointState struct {
+	pending      int
+	ejectedUntil time.Time
+}
+
+type ringNode struct {
+	hash     uint32
+	endpoint string
+}
+
+// NewLoadBalancer 创建一个 [LoadBalancer] 。若 Uris 和 Resolver 都未提供，或使用 [BalanceConsistentHash] 但未提供 HashKey ，则 panic 。
+func NewLoadBalancer(op LoadBalancerOp) *LoadBalancer {
+	if len(op.Uris) == 0 && op.Resolver == nil {
+		panic("either Uris or Resolver must be provided")
+	}
+
+	if op.Strategy == BalanceConsistentHash && op.HashKey == nil {
+		panic("HashKey must be provided for BalanceConsistentHash")
+	}
+
+	if op.EjectDuration <= 0 {
+		op.EjectDuration = defaultEjectDuration
+	}
+
+	return &LoadBalancer{
+		op:        op,
+		endpoints: make(map[string]*endpointState),
+	}
+}
+
+// Endpoints 返回当前的全部节点，含被剔除的节点。
+func (b *LoadBalancer) Endpoints() ([]string, error) {
+	if b.op.Resolver == nil {
+		return b.op.Uris, nil
+	}
+
+	uris, err := b.op.Resolver()
+	if err != nil {
+		return nil, fmt.Errorf("resolve endpoints: %w", err)
+	}
+	return uris, nil
+}
+
+// IsEjected 判断给定的节点当前是否处于被剔除的状态。
+func (b *LoadBalancer) IsEjected(endpoint string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.endpoints[endpoint]
+	return ok && time.Now().Before(s.ejectedUntil)
+}
+
+// Eject 将给定的节点暂时剔除，时长由 [LoadBalancerOp.EjectDuration] 指定。
+// 发生传输错误时，节点会被自动剔除，通常不需要手动调用此方法。
+func (b *LoadBalancer) Eject(endpoint string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state(endpoint).ejectedUntil = time.Now().Add(b.op.EjectDuration)
+}
+
+// pick 从 endpoints 中排除 tried 后，按策略选择一个节点。
+func (b *LoadBalancer) pick(r *http.Request, endpoints []string, tried map[string]bool) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	candidates := make([]string, 0, len(endpoints))
+	var ejected []string
+	for _, v := range endpoints {
+		if tried[v] {
+			continue
+		}
+
+		if s, ok := b.endpoints[v]; ok && now.Before(s.ejectedUntil) {
+			ejected = append(ejected, v)
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+
+	// 没有健康的节点了，只能在被剔除的节点里面碰碰运气。
+	if len(candidates) == 0 {
+		candidates = ejected
+	}
+
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	switch b.op.Strategy {
+	case BalanceLeastPending:
+		// 从轮转的起点开始找，使 pending 相同的节点也能被均匀选中。
+		start := int(b.counter % uint64(len(candidates)))
+		b.counter++
+
+		res := candidates[start]
+		min := b.state(res).pending
+		for i := 1; i < len(candidates); i++ {
+			v := candidates[(start+i)%len(candidates)]
+			if p := b.state(v).pending; p < min {
+				res, min = v, p
+			}
+		}
+		return res, true
+
+	case BalanceConsistentHash:
+		return b.pickFromRing(r, endpoints, candidates), true
+
+	default:
+		res := candidates[b.counter%uint64(len(candidates))]
+		b.counter++
+		return res, true
+	}
+}
+
+func (b *LoadBalancer) pickFromRing(r *http.Request, endpoints, candidates []string) string {
+	// 环基于全部节点构建，这样节点被剔除时，只有落在该节点上的键会迁移。
+	key := strings.Join(endpoints, "\n")
+	if key != b.ringKey {
+		ring := make([]ringNode, 0, len(endpoints)*consistentHashReplicas)
+		for _, v := range endpoints {
+			for i := 0; i < consistentHashReplicas; i++ {
+				h := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + "#" + v))
+				ring = append(ring, ringNode{h, v})
+			}
+		}
+		sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+		b.ring = ring
+		b.ringKey = key
+	}
+
+	hashKey := b.op.HashKey(r)
+	available := make(map[string]bool, len(candidates))
+	for _, v := range candidates {
+		available[v] = true
+	}
+
+	h := crc32.ChecksumIEEE([]byte(hashKey))
+	start := sort.Search(len(b.ring), func(i int) bool { return b.ring[i].hash >= h })
+	for i := 0; i < len(b.ring); i++ {
+		node := b.ring[(start+i)%len(b.ring)]
+		if available[node.endpoint] {
+			return node.endpoint
+		}
+	}
+
+	return candidates[0] // never run
+}
+
+// acquire 增加节点的 pending 计数，返回用于减少计数的函数，该函数可被重复调用。
+func (b *LoadBalancer) acquire(endpoint string) (release func()) {
+	b.mu.Lock()
+	b.state(endpoint).pending++
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			b.state(endpoint).pending--
+			b.mu.Unlock()
+		})
+	}
+}
+
+// 需在锁内调用。
+func (b *LoadBalancer) state(endpoint string) *endpointState {
+	s, ok := b.endpoints[endpoint]
+	if !ok {
+		s = &endpointState{}
+		b.endpoints[endpoint] = s
+	}
+	return s
+}
+
+// prune 丢弃不在 endpoints 中且没有未完成的请求的节点的状态，
+// 以免 Resolver 给出的节点不断变化时，状态无限增长。
+func (b *LoadBalancer) prune(endpoints []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.endpoints) == 0 {
+		return
+	}
+
+	current := make(map[string]bool, len(endpoints))
+	for _, v := range endpoints {
+		current[v] = true
+	}
+
+	for k, s := range b.endpoints {
+		if !current[k] && s.pending == 0 {
+			delete(b.endpoints, k)
+		}
+	}
+}
+
+// roundTrip 选择节点并发送请求，对可重试的失败自动转移到其他节点。
+// path 会被追加到节点的基础 URL 之后； setup 在每次发送前对该次请求调用。
+// 成功时，返回的 *http.Request 是最终发送的请求。
+func (b *LoadBalancer) roundTrip(
+	client *http.Client, r *http.Request, path string, setup func(r *http.Request) error,
+) (*http.Response, *http.Request, string, error) {
+	endpoints, err := b.Endpoints()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	b.prune(endpoints)
+
+	if len(endpoints) == 0 {
+		return nil, nil, "", errors.New("no endpoint available")
+	}
+
+	maxAttempts := b.op.MaxAttempts
+	if maxAttempts <= 0 || maxAttempts > len(endpoints) {
+		maxAttempts = len(endpoints)
+	}
+
+	// body 不能重读的话，就没法转移到其他节点了。
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		maxAttempts = 1
+	}
+
+	tried := make(map[string]bool)
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		endpoint, ok := b.pick(r, endpoints, tried)
+		if !ok {
+			break
+		}
+		tried[endpoint] = true
+
+		req, err := b.buildRequest(r, endpoint, path)
+		if err != nil {
+			return nil, nil, endpoint, err
+		}
+
+		if setup != nil {
+			err = setup(req)
+			if err != nil {
+				return nil, nil, endpoint, err
+			}
+		}
+
+		release := b.acquire(endpoint)
+		response, err := client.Do(req)
+		if err != nil {
+			release()
+			err = fmt.Errorf("endpoint %s: %w", endpoint, err)
+
+			// 调用方取消了请求或请求超时，不是节点的问题，不剔除节点，也无需再尝试其他节点。
+			if isCallerError(client, r, err) {
+				return nil, nil, endpoint, err
+			}
+
+			b.Eject(endpoint)
+			lastErr = err
+			continue
+		}
+
+		isLast := attempt == maxAttempts-1 || len(tried) == len(endpoints)
+		if !isLast && isRetryableStatus(response.StatusCode) {
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+			release()
+			lastErr = fmt.Errorf("endpoint %s: unexpected HTTP status %d", endpoint, response.StatusCode)
+			continue
+		}
+
+		response.Body = &releaseOnClose{response.Body, release}
+		return response, req, endpoint, nil
+	}
+
+	return nil, nil, "", lastErr
+}
+
+func (b *LoadBalancer) buildRequest(r *http.Request, endpoint, path string) (*http.Request, error) {
+	target := strings.TrimRight(endpoint, "/")
+	if path != "" {
+		target += "/" + strings.TrimLeft(path, "/")
+	}
+
+	u, err := r.URL.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+
+	req := r.Clone(r.Context())
+	req.URL = u
+	req.Host = u.Host
+
+	if r.GetBody != nil {
+		req.Body, err = r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
+// isCallerError 判断请求的错误是否由调用方引起：请求的 context 被取消或超时，或超过了 client.Timeout 。
+func isCallerError(client *http.Client, r *http.Request, err error) bool {
+	if r.Context().Err() != nil {
+		return true
+	}
+
+	// http.Client 在超过 Timeout 时给出的错误满足 errors.Is(err, context.DeadlineExceeded) 。
+	return client.Timeout > 0 && errors.Is(err, context.DeadlineExceeded)
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// releaseOnClose 在 body 被关闭时，减少节点的 pending 计数。
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (x *releaseOnClose) Close() error {
+	x.release()
+	return x.ReadCloser.Close()
+}
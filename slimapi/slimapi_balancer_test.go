@@ -0,0 +1,355 @@
+package slimapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/stretchr/testify/require"
+)
+
+// 创建一个只有 Who 方法的服务，该方法返回给定的 name 。
+func newWhoServer(name string) *httptest.Server {
+	h := NewSlimApiHandler("")
+	h.RegisterMethod(webapi.ApiMethod{
+		Name:  "Who",
+		Value: reflect.ValueOf(func() string { return name }),
+	})
+
+	e := webapi.NewEngine()
+	e.Handle("/api/{~method}", h, nil)
+	return httptest.NewServer(e)
+}
+
+func TestNewLoadBalancer(t *testing.T) {
+	require.Panics(t, func() { NewLoadBalancer(LoadBalancerOp{}) })
+	require.PanicsWithValue(t, "HashKey must be provided for BalanceConsistentHash", func() {
+		NewLoadBalancer(LoadBalancerOp{Uris: []string{"a"}, Strategy: BalanceConsistentHash})
+	})
+
+	b := NewLoadBalancer(LoadBalancerOp{Uris: []string{"a"}})
+	require.Equal(t, defaultEjectDuration, b.op.EjectDuration)
+}
+
+func TestLoadBalancer_pick(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodPost, "/path", nil)
+	endpoints := []string{"a", "b", "c"}
+
+	t.Run("round-robin", func(t *testing.T) {
+		b := NewLoadBalancer(LoadBalancerOp{Uris: endpoints})
+		var got []string
+		for i := 0; i < 6; i++ {
+			v, ok := b.pick(r, endpoints, nil)
+			require.True(t, ok)
+			got = append(got, v)
+		}
+		require.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, got)
+	})
+
+	t.Run("tried", func(t *testing.T) {
+		b := NewLoadBalancer(LoadBalancerOp{Uris: endpoints})
+		v, ok := b.pick(r, endpoints, map[string]bool{"a": true, "b": true})
+		require.True(t, ok)
+		require.Equal(t, "c", v)
+
+		_, ok = b.pick(r, endpoints, map[string]bool{"a": true, "b": true, "c": true})
+		require.False(t, ok)
+	})
+
+	t.Run("ejected", func(t *testing.T) {
+		b := NewLoadBalancer(LoadBalancerOp{Uris: endpoints})
+		b.Eject("a")
+		b.Eject("b")
+		require.True(t, b.IsEjected("a"))
+		require.False(t, b.IsEjected("c"))
+
+		for i := 0; i < 3; i++ {
+			v, _ := b.pick(r, endpoints, nil)
+			require.Equal(t, "c", v)
+		}
+
+		// 全部被剔除时，仍然可以选出节点。
+		b.Eject("c")
+		_, ok := b.pick(r, endpoints, nil)
+		require.True(t, ok)
+	})
+
+	t.Run("least-pending", func(t *testing.T) {
+		b := NewLoadBalancer(LoadBalancerOp{Uris: endpoints, Strategy: BalanceLeastPending})
+		releaseA := b.acquire("a")
+		b.acquire("c")
+
+		for i := 0; i < 3; i++ {
+			v, _ := b.pick(r, endpoints, nil)
+			require.Equal(t, "b", v)
+		}
+
+		releaseA()
+		releaseA() // 可重复调用。
+		b.acquire("b")
+		v, _ := b.pick(r, endpoints, nil)
+		require.Equal(t, "a", v)
+	})
+
+	t.Run("consistent-hash", func(t *testing.T) {
+		b := NewLoadBalancer(LoadBalancerOp{
+			Uris:     endpoints,
+			Strategy: BalanceConsistentHash,
+			HashKey:  func(r *http.Request) string { return r.Header.Get("X-Key") },
+		})
+
+		hits := make(map[string]string)
+		for _, key := range []string{"k1", "k2", "k3", "k4", "k5", "k6", "k7", "k8"} {
+			req, _ := http.NewRequest(http.MethodPost, "/path", nil)
+			req.Header.Set("X-Key", key)
+
+			first, _ := b.pick(req, endpoints, nil)
+			for i := 0; i < 3; i++ {
+				v, _ := b.pick(req, endpoints, nil)
+				require.Equal(t, first, v)
+			}
+			hits[key] = first
+		}
+
+		// 剔除一个节点，只有落在该节点上的键会迁移。
+		b.Eject("a")
+		for key, old := range hits {
+			req, _ := http.NewRequest(http.MethodPost, "/path", nil)
+			req.Header.Set("X-Key", key)
+			v, _ := b.pick(req, endpoints, nil)
+			if old == "a" {
+				require.NotEqual(t, "a", v)
+			} else {
+				require.Equal(t, old, v)
+			}
+		}
+	})
+}
+
+func TestSlimApiInvoker_Balancer(t *testing.T) {
+	s1 := newWhoServer("s1")
+	defer s1.Close()
+	s2 := newWhoServer("s2")
+	defer s2.Close()
+
+	t.Run("round-robin", func(t *testing.T) {
+		b := NewLoadBalancer(LoadBalancerOp{Uris: []string{s1.URL + "/api", s2.URL + "/api/"}})
+		invoker := NewBalancedSlimApiInvoker[struct{}, string](b, "/Who")
+
+		var got []string
+		for i := 0; i < 4; i++ {
+			got = append(got, invoker.MustDo(struct{}{}))
+		}
+		require.Equal(t, []string{"s1", "s2", "s1", "s2"}, got)
+	})
+
+	t.Run("resolver", func(t *testing.T) {
+		b := NewLoadBalancer(LoadBalancerOp{
+			Resolver: func() ([]string, error) { return []string{s2.URL + "/api"}, nil },
+		})
+		invoker := NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+		require.Equal(t, "s2", invoker.MustDo(struct{}{}))
+
+		b = NewLoadBalancer(LoadBalancerOp{
+			Resolver: func() ([]string, error) { return nil, errors.New("gg") },
+		})
+		invoker = NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+		_, err := invoker.Do(struct{}{})
+		require.ErrorContains(t, err, "resolve endpoints: gg")
+	})
+
+	t.Run("failover-transport", func(t *testing.T) {
+		dead := httptest.NewServer(http.NotFoundHandler())
+		dead.Close()
+
+		b := NewLoadBalancer(LoadBalancerOp{Uris: []string{dead.URL + "/api", s1.URL + "/api"}})
+		invoker := NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+
+		var endpoint string
+		invoker.Middlewares = InvokeChain{
+			func(next InvokeHandler) InvokeHandler {
+				return func(ctx *InvokeContext) error {
+					err := next(ctx)
+					endpoint = ctx.Endpoint
+					return err
+				}
+			},
+		}
+
+		require.Equal(t, "s1", invoker.MustDo(struct{}{}))
+		require.Equal(t, s1.URL+"/api", endpoint)
+		require.True(t, b.IsEjected(dead.URL+"/api"))
+
+		// 被剔除后，不再尝试该节点。
+		require.Equal(t, "s1", invoker.MustDo(struct{}{}))
+	})
+
+	t.Run("failover-status", func(t *testing.T) {
+		unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer unavailable.Close()
+
+		b := NewLoadBalancer(LoadBalancerOp{Uris: []string{unavailable.URL, s2.URL + "/api"}})
+		invoker := NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+		require.Equal(t, "s2", invoker.MustDo(struct{}{}))
+		require.False(t, b.IsEjected(unavailable.URL))
+
+		// 只剩一个节点可选时，不可重试的状态码直接作为错误返回。
+		b = NewLoadBalancer(LoadBalancerOp{Uris: []string{unavailable.URL}})
+		invoker = NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+		_, err := invoker.Do(struct{}{})
+		require.ErrorContains(t, err, "unexpected HTTP status 503")
+	})
+
+	t.Run("caller-canceled", func(t *testing.T) {
+		b := NewLoadBalancer(LoadBalancerOp{Uris: []string{s1.URL + "/api", s2.URL + "/api"}})
+		invoker := NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		attempts := 0
+		invoker.Middlewares = InvokeChain{
+			func(next InvokeHandler) InvokeHandler {
+				return func(c *InvokeContext) error {
+					c.Request = c.Request.WithContext(ctx)
+					return next(c)
+				}
+			},
+		}
+		invoker.RequestSetup = func(r *http.Request) error {
+			attempts++
+			return nil
+		}
+
+		_, err := invoker.Do(struct{}{})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 1, attempts)
+		require.False(t, b.IsEjected(s1.URL+"/api"))
+		require.False(t, b.IsEjected(s2.URL+"/api"))
+	})
+
+	t.Run("caller-timeout", func(t *testing.T) {
+		// 节点很慢，但没有问题。
+		done := make(chan struct{})
+		slow := func() *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-done
+			}))
+		}
+		slow1, slow2 := slow(), slow()
+		defer slow1.Close()
+		defer slow2.Close()
+		defer close(done)
+
+		newInvoker := func() (*LoadBalancer, *SlimApiInvoker[struct{}, string], *int) {
+			b := NewLoadBalancer(LoadBalancerOp{Uris: []string{slow1.URL, slow2.URL}})
+			invoker := NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+			attempts := new(int)
+			invoker.RequestSetup = func(r *http.Request) error {
+				*attempts++
+				return nil
+			}
+			return b, invoker, attempts
+		}
+
+		requireNotEjected := func(b *LoadBalancer, attempts int) {
+			require.Equal(t, 1, attempts)
+			require.False(t, b.IsEjected(slow1.URL))
+			require.False(t, b.IsEjected(slow2.URL))
+		}
+
+		t.Run("context-timeout", func(t *testing.T) {
+			b, invoker, attempts := newInvoker()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			_, err := invoker.WithContext(ctx).Do(struct{}{})
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			requireNotEjected(b, *attempts)
+		})
+
+		t.Run("context-cancel", func(t *testing.T) {
+			b, invoker, attempts := newInvoker()
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			_, err := invoker.WithContext(ctx).Do(struct{}{})
+			require.ErrorIs(t, err, context.Canceled)
+			requireNotEjected(b, *attempts)
+		})
+
+		t.Run("client-timeout", func(t *testing.T) {
+			b, invoker, attempts := newInvoker()
+			invoker.Client = &http.Client{Timeout: 50 * time.Millisecond}
+
+			_, err := invoker.Do(struct{}{})
+			require.ErrorContains(t, err, "Client.Timeout exceeded")
+			requireNotEjected(b, *attempts)
+		})
+	})
+
+	t.Run("all-dead", func(t *testing.T) {
+		dead := httptest.NewServer(http.NotFoundHandler())
+		dead.Close()
+
+		b := NewLoadBalancer(LoadBalancerOp{Uris: []string{dead.URL}})
+		invoker := NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+		_, err := invoker.Do(struct{}{})
+		require.ErrorContains(t, err, "endpoint "+dead.URL)
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		dead := httptest.NewServer(http.NotFoundHandler())
+		dead.Close()
+
+		var uris []string
+		b := NewLoadBalancer(LoadBalancerOp{
+			Resolver: func() ([]string, error) { return uris, nil },
+		})
+		invoker := NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+
+		// 节点各不相同，状态不应累积。
+		for i := 0; i < 5; i++ {
+			uris = []string{fmt.Sprintf("%s/api%d", dead.URL, i), s1.URL + "/api"}
+			require.Equal(t, "s1", invoker.MustDo(struct{}{}))
+			require.True(t, b.IsEjected(uris[0]))
+		}
+
+		b.mu.Lock()
+		require.LessOrEqual(t, len(b.endpoints), 2)
+		b.mu.Unlock()
+
+		// 有未完成的请求的节点不会被丢弃。
+		release := b.acquire("busy")
+		uris = []string{s1.URL + "/api"}
+		invoker.MustDo(struct{}{})
+
+		b.mu.Lock()
+		require.Contains(t, b.endpoints, "busy")
+		require.NotContains(t, b.endpoints, dead.URL+"/api4")
+		b.mu.Unlock()
+
+		release()
+		invoker.MustDo(struct{}{})
+		b.mu.Lock()
+		require.Equal(t, []string{s1.URL + "/api"}, slices.Collect(maps.Keys(b.endpoints)))
+		b.mu.Unlock()
+	})
+
+	t.Run("pending-released", func(t *testing.T) {
+		b := NewLoadBalancer(LoadBalancerOp{Uris: []string{s1.URL + "/api"}, Strategy: BalanceLeastPending})
+		invoker := NewBalancedSlimApiInvoker[struct{}, string](b, "Who")
+		invoker.MustDo(struct{}{})
+		require.Equal(t, 0, b.state(s1.URL+"/api").pending)
+	})
+}
@@ -17,8 +17,9 @@ import (
 // FilePart 用于封装一个 *multipart.FileHeader ，用于 [Conv] 对象进行类型转换，
 // 以支持 multipart/form-data 方式的参数及文件上传。
 type FilePart struct {
-	*multipart.FileHeader     // 原始的 FileHeader 。
-	jsonValue             any // 对于 application/json 类型的数据， 读取并 json.Unmarshal 然后存储在这里。
+	*multipart.FileHeader              // 原始的 FileHeader 。
+	jsonValue             any          // 对于 application/json 类型的数据， 读取并 json.Unmarshal 然后存储在这里。
+	file                  UploadedFile // 由 UploadStorage 存储时，文件的数据在这里，不能通过 FileHeader 读取。
 }
 
 var _ json.Marshaler = (*FilePart)(nil)
@@ -28,8 +29,14 @@ var _ json.Marshaler = (*FilePart)(nil)
 // 如果 part 带有 HTTP 头 Content-Type:application/json ，则 JSON 内容会被读取并校验其格式。
 // 若读取或校验失败，返回对应的错误。
 func NewFilePart(fh *multipart.FileHeader) (*FilePart, error) {
+	return newStoredFilePart(fh, nil)
+}
+
+// 创建一个 FilePart ，其数据由 UploadStorage 存储在 file 中。 file 为 nil 时，通过 FileHeader 读取数据。
+func newStoredFilePart(fh *multipart.FileHeader, file UploadedFile) (*FilePart, error) {
 	f := &FilePart{
 		FileHeader: fh,
+		file:       file,
 	}
 
 	if f.IsJson() {
@@ -63,6 +70,15 @@ func (x *FilePart) IsJson() bool {
 	return x.ContentType() == webapi.ContentTypeJson
 }
 
+// Open 打开当前 part 的数据用于读取，可以被多次调用。
+// 文件由 [UploadStorage] 存储时，从存储中读取，否则同 [multipart.FileHeader.Open] 。
+func (x *FilePart) Open() (multipart.File, error) {
+	if x.file != nil {
+		return x.file.Open()
+	}
+	return x.FileHeader.Open()
+}
+
 // ReadAll 读取当前 part 的全部数据。
 // 此方法可被重复调用。
 func (x *FilePart) ReadAll() (res []byte, err error) {
@@ -151,7 +167,15 @@ var _internalConv = conv.Conv{Conf: _convConf}
 //   - 目标值类型是 [*multipart.FileHeader] 时，原样返回输入值，不做转换。
 //   - 目标值时 []byte 时，将其数据读取出来。
 //   - 目标值时其他类型时，若此分部的 Content-Type 为 application/json ，则将其内容作为 JSON 读取，并将此 JSON 反序列化到目标值。
+//
+// 同名的多个文件以 []*FilePart 给出，目标值类型是 []*FilePart 、 []*multipart.FileHeader 、 [][]byte 等 slice 时，按顺序逐个转换；
+// 目标值不是 slice 时，仅转换最后一个。反之，单个 [*FilePart] 也可转换为上述 slice ，得到只有一个元素的 slice 。
+//
+// 目标值类型是 [Optional] 时，输入 nil 得到 null 状态的 Optional ，其他值转换为 Optional 内部的类型。
 var Conv = func() conv.Conv {
+	// 给 Optional 转换其内部的值时，需使用完整的 Conv ，这里先声明，在最后赋值。
+	var self conv.Conv
+
 	_convConf.CustomConverters = func() []conv.ConvertFunc {
 		var (
 			typFilePart   = reflect.TypeOf(&FilePart{})
@@ -186,6 +210,12 @@ var Conv = func() conv.Conv {
 				return
 			}
 
+			// 由 UploadStorage 存储的文件，不能通过 FileHeader 读取数据。
+			if f.file != nil {
+				err = errFileHeaderFromStorage
+				return
+			}
+
 			return f.FileHeader, nil
 		}
 
@@ -203,6 +233,36 @@ var Conv = func() conv.Conv {
 			return f.ReadAll()
 		}
 
+		// 判断是否是可以由 *FilePart 转换得到的文件类型。
+		isFileTarget := func(typ reflect.Type) bool {
+			return typ == typFilePart || typ == typFileHeader || typ == typByteSlice
+		}
+
+		// *FilePart -> []*FilePart / []*multipart.FileHeader / [][]byte ：得到只有一个元素的 slice 。
+		filePartToSlice := func(value interface{}, typ reflect.Type) (result interface{}, err error) {
+			f, ok := value.(*FilePart)
+			if !ok || typ.Kind() != reflect.Slice || !isFileTarget(typ.Elem()) {
+				return
+			}
+
+			return self.ConvertType([]*FilePart{f}, typ)
+		}
+
+		// []*FilePart -> 非 slice 的类型（含 []byte ）：同名的文件有多个，但目标只能接收一个时，取最后一个。
+		// 目标为 slice 时，由 Conv 逐个转换元素。
+		lastFilePart := func(value interface{}, typ reflect.Type) (result interface{}, err error) {
+			fs, ok := value.([]*FilePart)
+			if !ok || len(fs) == 0 || typ.Kind() == reflect.Interface {
+				return
+			}
+
+			if typ.Kind() == reflect.Slice && typ != typByteSlice {
+				return
+			}
+
+			return self.ConvertType(fs[len(fs)-1], typ)
+		}
+
 		// *FilePart as JSON -> any
 		jsonFilePartToAny := func(value interface{}, typ reflect.Type) (result interface{}, err error) {
 			f, ok := value.(*FilePart)
@@ -215,7 +275,44 @@ var Conv = func() conv.Conv {
 			return
 		}
 
+		// any -> Optional[T]
+		toOptional := func(value interface{}, typ reflect.Type) (result interface{}, err error) {
+			// *Optional[T] ：转换到 Optional[T] 再取地址。 nil 在调用 converter 前已被转为 nil 指针。
+			if typ.Kind() == reflect.Ptr {
+				if _, ok := optionalElemType(typ.Elem()); !ok {
+					return
+				}
+
+				v, err := self.ConvertType(value, typ.Elem())
+				if err != nil {
+					return nil, err
+				}
+
+				p := reflect.New(typ.Elem())
+				p.Elem().Set(reflect.ValueOf(v))
+				return p.Interface(), nil
+			}
+
+			if _, ok := optionalElemType(typ); !ok {
+				return
+			}
+
+			if reflect.TypeOf(value) == typ {
+				return value, nil
+			}
+
+			p := reflect.New(typ)
+			err = p.Interface().(optionalField).setConverted(value, self.ConvertType)
+			if err != nil {
+				return
+			}
+			return p.Elem().Interface(), nil
+		}
+
 		return []conv.ConvertFunc{
+			toOptional,
+			lastFilePart,
+			filePartToSlice,
 			filePartToFilePart,
 			filePartToFileHeader,
 			filePartToBytes,
@@ -223,5 +320,6 @@ var Conv = func() conv.Conv {
 		}
 	}()
 
-	return conv.Conv{Conf: _convConf}
+	self = conv.Conv{Conf: _convConf}
+	return self
 }()
@@ -69,3 +69,38 @@ func TestConv_filePartToFilePart(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, part, res)
 }
+
+func TestConv_fileParts(t *testing.T) {
+	newPart := func(name string) *FilePart {
+		f, err := NewFilePart(webapitest.CreateMultipartFileHeader("f", name, []byte(name), nil))
+		require.NoError(t, err)
+		return f
+	}
+	a, b := newPart("a"), newPart("b")
+
+	t.Run("single-to-slice", func(t *testing.T) {
+		res, err := Conv.ConvertType(a, reflect.TypeOf([]*FilePart{}))
+		require.NoError(t, err)
+		require.Equal(t, []*FilePart{a}, res)
+
+		res, err = Conv.ConvertType(a, reflect.TypeOf([][]byte{}))
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("a")}, res)
+	})
+
+	t.Run("slice-to-slice", func(t *testing.T) {
+		res, err := Conv.ConvertType([]*FilePart{a, b}, reflect.TypeOf([]*multipart.FileHeader{}))
+		require.NoError(t, err)
+		require.Equal(t, []*multipart.FileHeader{a.FileHeader, b.FileHeader}, res)
+	})
+
+	t.Run("slice-to-single", func(t *testing.T) {
+		res, err := Conv.ConvertType([]*FilePart{a, b}, reflect.TypeOf(a))
+		require.NoError(t, err)
+		require.Equal(t, b, res)
+
+		res, err = Conv.ConvertType([]*FilePart{a, b}, reflect.TypeOf([]byte{}))
+		require.NoError(t, err)
+		require.Equal(t, []byte("b"), res)
+	})
+}
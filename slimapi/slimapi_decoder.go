@@ -2,9 +2,14 @@ package slimapi
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/cmstar/go-errx"
 	"github.com/cmstar/go-webapi"
@@ -12,12 +17,17 @@ import (
 
 // NewSlimApiDecoder 返回用于 SlimAPI 协议的 [webapi.ApiDecoder] 实现。
 func NewSlimApiDecoder() webapi.ArgumentDecoderPipeline {
-	return webapi.NewArgumentDecoderPipeline(StructArgumentDecoder)
+	return webapi.NewArgumentDecoderPipeline(LongPollArgumentDecoder, StreamArgumentDecoder, StructArgumentDecoder, NamedArgumentDecoder)
 }
 
 // StructArgumentDecoder 是一个 [webapi.ArgumentDecoder] ，
 // 定义了 SlimAPI 协议的参数解析过程，用于方法参数表中 struct 类型的参数。
 //
+// 没有名称的 struct 参数，其字段从全部参数中读取；
+// 有名称（见 [webapi.ApiMetaParamNames] ）的 struct 参数，从同名的参数中读取，其值需为对象（ JSON 或 multipart 中的 JSON 分部），
+// 例如 func(from, to Location) 使用名称 "from", "to" ，可接收 {"from":{...},"to":{...}} 。
+// [webapi.LongPoll] 不由此解析，见 [LongPollArgumentDecoder] 。
+//
 // 这是一个单例。
 var StructArgumentDecoder = slimApiMethodStructArgDecoder{}
 
@@ -25,38 +35,118 @@ type slimApiMethodStructArgDecoder struct{}
 
 // DecodeArg implements [webapi.ApiDecoder.DecodeArg].
 func (d slimApiMethodStructArgDecoder) DecodeArg(state *webapi.ApiState, index int, argType reflect.Type) (ok bool, v any, err error) {
-	if argType.Kind() != reflect.Struct {
+	// LongPoll 只能来自元参数，若从请求参数中读取，调用方可以绕过等待时间的上限。
+	if argType.Kind() != reflect.Struct || argType == typeLongPoll {
 		return false, nil, nil
 	}
 
-	paramMap, err := d.paramMap(state)
+	paramMap, bodyMap, err := d.cachedParamMap(state)
 	if err != nil {
-		return false, nil, webapi.CreateBadRequestError(state, err, "bad request")
+		return false, nil, paramMapError(state, err)
+	}
+
+	if name := state.Method.ParamName(index); name != "" {
+		paramMap, err = d.namedObject(paramMap, name)
+		if err != nil {
+			return false, nil, webapi.CreateBadRequestError(state, err, "bad request")
+		}
+	} else {
+		// 参数表被多个参数共用，下面的处理会修改参数表，需复制一份。
+		paramMap = maps.Clone(paramMap)
 	}
 
+	applyFromTags(state, paramMap, bodyMap, argType)
+	applyDefaultTags(paramMap, argType)
+
 	val, err := Conv.ConvertType(paramMap, argType)
 	if err != nil {
 		return false, nil, webapi.CreateBadRequestError(state, err, "bad request")
 	}
 
+	// 按 validate tag 校验，未通过的字段明细放在回执的 Data 上。
+	if errs := webapi.ValidateStruct(val); len(errs) > 0 {
+		e := webapi.CreateBadRequestError(state, errs, "%s", errs.Error())
+		e.Data = errs
+		return false, nil, e
+	}
+
 	return true, val, nil
 }
 
+// 读取参数表，结果被缓存在 ApiState 上，方法有多个参数时，只读取一次。
+func (d slimApiMethodStructArgDecoder) cachedParamMap(state *webapi.ApiState) (params, body map[string]any, err error) {
+	if v, ok := state.GetCustomData(customData_ParamMap); ok {
+		cached := v.([2]map[string]any)
+		return cached[0], cached[1], nil
+	}
+
+	params, body, err = d.paramMap(state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state.SetCustomData(customData_ParamMap, [2]map[string]any{params, body})
+	return params, body, nil
+}
+
+// 将读取参数表时的错误转换为返回给请求者的错误： body 超过大小限制时， Code 为 413 ；
+// 文件未通过 UploadPolicy 的校验时，使用 UploadPolicyError 的 Code 和描述；其余为 400 。
+func paramMapError(state *webapi.ApiState, err error) error {
+	if e, ok := webapi.AsBodyTooLargeError(state, err); ok {
+		return e
+	}
+
+	var policyErr *UploadPolicyError
+	if errors.As(err, &policyErr) {
+		e := webapi.CreateBadRequestError(state, err, "%s", policyErr.Error())
+		e.Code = policyErr.Code
+		return e
+	}
+	return webapi.CreateBadRequestError(state, err, "bad request")
+}
+
+// 获取有名称的 struct 参数对应的对象，返回其副本。参数不存在或为 null 时，返回空的 map 。
+func (d slimApiMethodStructArgDecoder) namedObject(params map[string]any, name string) (map[string]any, error) {
+	_, v, _ := findParamIgnoreCase(params, name)
+	if fs, ok := v.([]*FilePart); ok {
+		v = fs[len(fs)-1] // 同名的多个 part ，只取最后一个。
+	}
+
+	if f, ok := v.(*FilePart); ok && f.IsJson() {
+		v = f.JsonValue()
+	}
+
+	switch obj := v.(type) {
+	case nil:
+		return make(map[string]any), nil
+	case map[string]any:
+		return maps.Clone(obj), nil
+	}
+	return nil, fmt.Errorf("parameter '%s' must be an object, got %T", name, v)
+}
+
 // paramMap 将各类参数存入 map[string]any 。
 //  1. 参数是大小写不敏感的。
 //  2. URL 上的参数（query）总是会被读取。
 //  3. 表单参数会与 query 合并在一起，同名（大小写不敏感）参数的值会被用逗号拼接起来。
 //  4. JSON 参数会与 query 合并在一起，同名的参数， JSON 的值会将 query 的值覆盖掉。
-func (d slimApiMethodStructArgDecoder) paramMap(state *webapi.ApiState) (map[string]any, error) {
+//
+// params 是合并后的参数； body 仅包含来自 body 的参数，用于 from:"body" 的字段， GET 请求时为空。
+// 若方法以流的方式读取 body （见 [StreamArgumentDecoder] ），则不读取 body ，如同 GET 请求。
+func (d slimApiMethodStructArgDecoder) paramMap(state *webapi.ApiState) (params, body map[string]any, err error) {
 	format := getRequestFormat(state)
 	if format == "" {
 		webapi.PanicApiError(state, nil, "missing request format")
 	}
 
+	if hasStreamArg(state.Method) {
+		format = meta_RequestFormat_Get
+	}
+
 	switch format {
 	case meta_RequestFormat_Get:
 		m := d.readQueryInLowercase(state)
-		return m, nil
+		return m, make(map[string]any), nil
 
 	case meta_RequestFormat_Post:
 		req := state.RawRequest
@@ -69,8 +159,7 @@ func (d slimApiMethodStructArgDecoder) paramMap(state *webapi.ApiState) (map[str
 			return d.readMultiPartForm(state)
 		}
 
-		m := d.readForm(state, contentType)
-		return m, nil
+		return d.readForm(state)
 
 	case meta_RequestFormat_Json:
 		return d.readJsonBody(state)
@@ -79,7 +168,7 @@ func (d slimApiMethodStructArgDecoder) paramMap(state *webapi.ApiState) (map[str
 		webapi.PanicApiError(state, nil, "unsupported format: %v", format)
 	}
 
-	return nil, nil // never run
+	return nil, nil, nil // never run
 }
 
 // 读取 URL 上的参数，包含 query-string 和路由参数。
@@ -102,31 +191,26 @@ func (d slimApiMethodStructArgDecoder) readQueryInLowercase(state *webapi.ApiSta
 	return m
 }
 
-func (d slimApiMethodStructArgDecoder) readForm(state *webapi.ApiState, contentType string) map[string]any {
-	// 将整个 body 作为 query-string 读取。不知道 body 实际上会上送什么样的数据，做一层防御，限制读取数据的最大大小。
-	reader := io.LimitReader(state.RawRequest.Body, maxMemorySizeParseRequestBody)
-	buf := new(strings.Builder)
-	_, err := io.Copy(buf, reader)
+func (d slimApiMethodStructArgDecoder) readForm(state *webapi.ApiState) (params, body map[string]any, err error) {
+	// 将整个 body 作为 query-string 读取。 body 的大小由 webapi.LimitRequestBody 限制，没有设置限制时整个读取。
+	raw, err := io.ReadAll(state.RawRequest.Body)
 	if err != nil {
-		// 这里一般不会出错。若出错了就比较严重了，直接 panic 。
-		webapi.PanicApiError(state, err, "error on reading the '%s' body", contentType)
+		err = errx.Wrap("slimApiDecoder: read body", err)
+		return nil, nil, err
 	}
 
-	lowercaseParams := d.readQueryInLowercase(state)
-	form := buf.String()
+	form := string(raw)
 	query := webapi.ParseQueryString(form)
+	body = make(map[string]any, len(query.Named))
 	for k, v := range query.Named {
-		k = strings.ToLower(k)
-		old, ok := lowercaseParams[k]
-		if ok {
-			lowercaseParams[k] = old.(string) + "," + v
-		} else {
-			lowercaseParams[k] = v
-		}
+		body[k] = v // Named 的 key 已是小写。
 	}
 
+	params = d.readQueryInLowercase(state)
+	mergeFormParams(params, body)
+
 	setRequestBodyDescription(state, form)
-	return lowercaseParams
+	return params, body, nil
 }
 
 // 解析 multipart/form-data 类型的请求。以下内容会被放在返回的 map 里：
@@ -134,76 +218,440 @@ func (d slimApiMethodStructArgDecoder) readForm(state *webapi.ApiState, contentT
 //   - body 中的 text/plain 类型的 part : Content-Disposition 的 name 作为 key ，内容作为 value ，类型为 string 。
 //   - body 中的 application/json 类型的 part ： Content-Disposition 的 name 作为 key ，内容作为 value ，类型为 JSON 反序列化后的 map[string]any 。
 //     此类型的 part 可用于解决上传文件的同事传递复杂结构参数的需求。
+//   - body 中的文件： name 作为 key ，值为 [*FilePart] ；同名的文件有多个时，值为按顺序排列的 []*FilePart 。
 //
-// 如果同名的 part 有多个，仅保留最后一个。
-func (d slimApiMethodStructArgDecoder) readMultiPartForm(state *webapi.ApiState) (map[string]any, error) {
-	req := state.RawRequest
-
-	err := req.ParseMultipartForm(maxMemorySizeParseRequestBody)
+// 同名的文本 part 有多个时，使用逗号拼接。
+func (d slimApiMethodStructArgDecoder) readMultiPartForm(state *webapi.ApiState) (params, body map[string]any, err error) {
+	// 文件的存储方式见 ApiMetaUploadStorage 。
+	values, files, err := parseMultipartForm(state)
 	if err != nil {
 		err = errx.Wrap("slimApiDecoder: parse multipart-form", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	// URL 上的参数（ query ）。
-	lowercaseParams := d.readQueryInLowercase(state)
+	body = make(map[string]any)
 
 	// body 中的 text/plain 类型的 part 。
-	for k, vs := range req.PostForm {
-		k = strings.ToLower(k)
-		v := strings.Join(vs, ",")
-
-		// Form 里的参数是区分大小写的，需要以大小写不敏感的方式将它们并起来。
-		old, ok := lowercaseParams[k]
-		if ok {
-			lowercaseParams[k] = old.(string) + "," + v
+	// Form 里的参数是区分大小写的，需要以大小写不敏感的方式将它们并起来。
+	for k, vs := range values {
+		mergeFormParams(body, map[string]any{strings.ToLower(k): strings.Join(vs, ",")})
+	}
+
+	// body 中的文件类型的 part ，已转换成 *FilePart ，其受 Conv 对象的支持。
+	// 同名的文件有多个时，按上传的顺序存放为 []*FilePart 。
+	for name, fileParts := range files {
+		if len(fileParts) == 1 {
+			body[strings.ToLower(name)] = fileParts[0]
 		} else {
-			lowercaseParams[k] = v
+			body[strings.ToLower(name)] = fileParts
 		}
 	}
 
-	// body 中的文件类型的 part 。
-	for name, fileHeader := range req.MultipartForm.File {
-		// 同名文件只取最后一个。
-		file := fileHeader[len(fileHeader)-1]
+	// URL 上的参数（ query ）。
+	params = d.readQueryInLowercase(state)
+	mergeFormParams(params, body)
 
-		// 转换成 *FilePart ，其受 Conv 对象的支持。
-		filePart, err := NewFilePart(file)
-		if err != nil {
-			err = errx.Wrap("slimApiDecoder: parse multipart-form", err)
-			return nil, err
+	setRequestBodyDescription(state, params)
+	return params, body, nil
+}
+
+// 将 src 中的表单参数合并到 dst ，key 均已是小写。同名的字符串值使用逗号拼接，其他类型的值（如文件）直接覆盖。
+func mergeFormParams(dst, src map[string]any) {
+	for k, v := range src {
+		old, ok := dst[k].(string)
+		s, isString := v.(string)
+		if ok && isString {
+			dst[k] = old + "," + s
+		} else {
+			dst[k] = v
 		}
-		lowercaseParams[strings.ToLower(name)] = filePart
 	}
-
-	setRequestBodyDescription(state, lowercaseParams)
-	return lowercaseParams, nil
 }
 
 // 将整个 HTTP body 作为一个 JSON 处理。要求其必须是一个 JSON object ，即包裹在“{}”里，可以表示为 key-value 结构。
 // JSON 的 key 会和 URL 上的参数合并，若一个参数同时出现在 body 和 URL 上，仅取 body 上的值。
-func (d slimApiMethodStructArgDecoder) readJsonBody(state *webapi.ApiState) (map[string]any, error) {
-	body, err := io.ReadAll(state.RawRequest.Body)
+func (d slimApiMethodStructArgDecoder) readJsonBody(state *webapi.ApiState) (params, body map[string]any, err error) {
+	raw, err := io.ReadAll(state.RawRequest.Body)
 	if err != nil {
 		err = errx.Wrap("slimApiDecoder: read body", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	lowercaseParam := d.readQueryInLowercase(state)
-	fromBody := make(map[string]any)
-	err = json.Unmarshal(body, &fromBody)
+	params = d.readQueryInLowercase(state)
+	body = make(map[string]any)
+	err = json.Unmarshal(raw, &body)
 	if err != nil {
 		err = errx.Wrap("slimApiDecoder: json unmarshal", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	// json.Unmarshal 接收 []byte 而这里接收 string ，转换有点开销，但目前没啥好方案解决。
-	setRequestBodyDescription(state, string(body))
+	setRequestBodyDescription(state, string(raw))
 
-	for k, v := range fromBody {
+	for k, v := range body {
 		// 采用先删再加的方式，使 JSON 字段尽量维持原来的样子。
-		delete(lowercaseParam, strings.ToLower(k))
-		lowercaseParam[k] = v
+		delete(params, strings.ToLower(k))
+		params[k] = v
+	}
+	return params, body, nil
+}
+
+// NamedArgumentDecoder 是一个 [webapi.ArgumentDecoder] ，用于方法参数表中有名称（见 [webapi.ApiMetaParamNames] ）的非 struct 参数，
+// 例如 func(id int, name string) 使用名称 "id", "name" 注册后，可接收 ?id=1&name=abc 。
+//
+// 参数值从 [StructArgumentDecoder] 所使用的参数表中，按名称（大小写不敏感）读取，并使用 [Conv] 转换为参数的类型。
+// 参数不存在或为 null 时，使用参数类型的零值；参数类型为接口时，则返回错误。没有名称的参数不被处理。
+//
+// 这是一个单例。
+var NamedArgumentDecoder = slimApiMethodNamedArgDecoder{}
+
+type slimApiMethodNamedArgDecoder struct{}
+
+// DecodeArg implements [webapi.ApiDecoder.DecodeArg].
+func (d slimApiMethodNamedArgDecoder) DecodeArg(state *webapi.ApiState, index int, argType reflect.Type) (ok bool, v any, err error) {
+	name := state.Method.ParamName(index)
+	if name == "" || argType.Kind() == reflect.Struct {
+		return false, nil, nil
+	}
+
+	paramMap, _, err := StructArgumentDecoder.cachedParamMap(state)
+	if err != nil {
+		return false, nil, paramMapError(state, err)
+	}
+
+	_, value, _ := findParamIgnoreCase(paramMap, name)
+	if value == nil {
+		// 管道不接受 nil ，接口类型的参数无法使用零值。
+		if argType.Kind() == reflect.Interface {
+			err = fmt.Errorf("parameter '%s' is required", name)
+			return false, nil, webapi.CreateBadRequestError(state, err, "%s", err.Error())
+		}
+		return true, reflect.Zero(argType).Interface(), nil
+	}
+
+	v, err = Conv.ConvertType(value, argType)
+	if err != nil {
+		return false, nil, webapi.CreateBadRequestError(state, err, "bad request")
+	}
+	return true, v, nil
+}
+
+// FromTagName 是用于声明参数来源的 struct tag 的名称。
+//
+// 默认情况下，参数从 query 、路由和 body 合并后的结果中读取（见 [StructArgumentDecoder] ）。
+// 带有此 tag 的字段，仅从 tag 指定的来源读取，其他来源中的同名参数被忽略。格式为 from:"SOURCE" 或 from:"SOURCE=NAME" ：
+//   - header HTTP 头，多个同名的头使用逗号拼接。
+//   - cookie Cookie 。
+//   - route 路由参数。
+//   - query URL 上的 query-string 。
+//   - body 请求的 body ，即表单、 multipart 或 JSON 中的参数。
+//
+// NAME 是参数在来源中的名称，省略时使用字段名称。 header 、 route 和 query 的名称是大小写不敏感的， cookie 则是大小写敏感的。
+// 此 tag 仅对方法参数表中 struct 的字段（含内嵌 struct 展开的字段）生效，不作用于更深层嵌套的 struct 。
+const FromTagName = "from"
+
+// 参数来源，即 from tag 的 SOURCE 部分。
+const (
+	fromHeader = "header"
+	fromCookie = "cookie"
+	fromRoute  = "route"
+	fromQuery  = "query"
+	fromBody   = "body"
+)
+
+// 解析 from tag 。 ok 表示字段有此 tag 。 tag 格式错误时 panic 。
+func parseFromTag(structType reflect.Type, f reflect.StructField) (source, name string, ok bool) {
+	tag, ok := f.Tag.Lookup(FromTagName)
+	if !ok {
+		return "", "", false
+	}
+
+	source, name, _ = strings.Cut(tag, "=")
+	switch source {
+	case fromHeader, fromCookie, fromRoute, fromQuery, fromBody:
+	default:
+		panic(fmt.Sprintf("from tag of %v.%s: unknown source %q", structType, f.Name, source))
+	}
+
+	if name == "" {
+		name = f.Name
+	}
+	return source, name, true
+}
+
+// checkFromTags 校验方法参数表中 struct 参数的 from tag ，格式错误时 panic 。
+// 用于 [webapi.BasicApiMethodRegisterOp.CheckMethod] ，使错误的 tag 在注册方法时即暴露。
+func checkFromTags(m webapi.ApiMethod) {
+	methodType := m.Value.Type()
+	for i := 0; i < methodType.NumIn(); i++ {
+		in := methodType.In(i)
+		if in.Kind() != reflect.Struct {
+			continue
+		}
+
+		eachParamField(in, func(name string, f reflect.StructField) {
+			parseFromTag(in, f)
+		})
+	}
+}
+
+// applyFromTags 按 typ 上的 from tag ，用指定来源的参数替换 params 中对应字段的参数。
+func applyFromTags(state *webapi.ApiState, params, body map[string]any, typ reflect.Type) {
+	eachParamField(typ, func(fieldName string, f reflect.StructField) {
+		source, name, ok := parseFromTag(typ, f)
+		if !ok {
+			return
+		}
+
+		// JSON 的 key 保留原始的大小写，可能有多个参数与字段对应，需全部移除，否则其他来源的参数可冒充指定来源的。
+		for key := range params {
+			if strings.EqualFold(key, fieldName) {
+				delete(params, key)
+			}
+		}
+
+		if v, ok := readParamFrom(state, body, source, name); ok {
+			params[fieldName] = v
+		}
+	})
+}
+
+// 从指定的来源读取参数。
+func readParamFrom(state *webapi.ApiState, body map[string]any, source, name string) (any, bool) {
+	req := state.RawRequest
+
+	switch source {
+	case fromHeader:
+		values := req.Header.Values(name)
+		if len(values) == 0 {
+			return nil, false
+		}
+		return strings.Join(values, ","), true
+
+	case fromCookie:
+		c, err := req.Cookie(name)
+		if err != nil {
+			return nil, false
+		}
+		return c.Value, true
+
+	case fromRoute:
+		for _, v := range webapi.AllRouteParams(req) {
+			if strings.EqualFold(v.Key, name) {
+				return v.Value, true
+			}
+		}
+		return nil, false
+
+	case fromQuery:
+		v, ok := state.Query.Get(name)
+		return v, ok
+
+	default: // fromBody
+		_, v, ok := findParamIgnoreCase(body, name)
+		return v, ok
+	}
+}
+
+// DefaultTagName 是用于声明参数默认值的 struct tag 的名称。
+//
+// 当请求中没有给出字段对应的参数（或 JSON 中的值为 null ）时，使用 tag 的值作为参数值，再和其他参数一样经过类型转换。
+// 故默认值的书写方式和 GET 参数一致，如数组使用 ~ 分割： `default:"1~2~3"` ；时间使用 [ParseTime] 支持的格式。
+// 嵌套的 struct （非指针）、 JSON 数组中的 struct 元素上的默认值也会生效。
+const DefaultTagName = "default"
+
+// 缓存每个类型（含其嵌套的类型）是否带有 default tag 。 key 为 reflect.Type ， value 为 bool 。
+var defaultTagCache sync.Map
+
+// applyDefaultTags 将 typ 上 default tag 声明的默认值填入 params 中缺失的参数。 params 中的 key 是大小写不敏感的。
+func applyDefaultTags(params map[string]any, typ reflect.Type) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct || !hasDefaultTag(typ, nil) {
+		return
+	}
+
+	eachParamField(typ, func(name string, f reflect.StructField) {
+		_, isOptional := optionalElemType(f.Type)
+
+		// JSON 的 key 保留原始的大小写，可能有多个参数与字段对应，逐一处理。
+		present := false
+		for key, v := range params {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+
+			// 对于 Optional ， null 是有意义的值，不视为缺失。
+			if v != nil || isOptional {
+				params[key] = applyDefaultTagsToValue(v, f.Type)
+				present = true
+			} else {
+				delete(params, key)
+			}
+		}
+
+		if present {
+			return
+		}
+
+		if tag, ok := f.Tag.Lookup(DefaultTagName); ok {
+			params[name] = tag
+			return
+		}
+
+		// 对于值类型的 struct ，即便参数缺失，其内部字段的默认值也应生效。指针和 Optional 则保持缺失。
+		if f.Type.Kind() == reflect.Struct && !isOptional && hasDefaultTag(f.Type, nil) {
+			sub := make(map[string]any)
+			applyDefaultTags(sub, f.Type)
+			params[name] = sub
+		}
+	})
+}
+
+// 对已存在的参数值，处理其内部的默认值。仅 JSON 来源的值（ map[string]any 、 []any ）有内部结构。
+// 参数表中的值可能被多个参数共用（如 [maps.Clone] 复制的参数表），故不修改 v ，而是返回填入默认值后的副本；
+// 无需填入默认值时，原样返回 v 。
+func applyDefaultTagsToValue(v any, typ reflect.Type) any {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if !hasDefaultTag(typ, nil) {
+		return v
+	}
+
+	if elem, ok := optionalElemType(typ); ok {
+		return applyDefaultTagsToValue(v, elem)
+	}
+
+	if fs, ok := v.([]*FilePart); ok && len(fs) > 0 {
+		res := slices.Clone(fs)
+		if typ.Kind() == reflect.Slice {
+			for i, f := range fs {
+				res[i] = applyDefaultTagsToValue(f, typ.Elem()).(*FilePart)
+			}
+		} else {
+			last := len(fs) - 1
+			res[last] = applyDefaultTagsToValue(fs[last], typ).(*FilePart)
+		}
+		return res
+	}
+
+	if f, ok := v.(*FilePart); ok {
+		if !f.IsJson() {
+			return f
+		}
+		res := *f
+		res.jsonValue = applyDefaultTagsToValue(f.jsonValue, typ)
+		return &res
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		if m, ok := v.(map[string]any); ok {
+			m = maps.Clone(m)
+			applyDefaultTags(m, typ)
+			return m
+		}
+
+	case reflect.Slice, reflect.Array:
+		if arr, ok := v.([]any); ok {
+			res := make([]any, len(arr))
+			for i, elem := range arr {
+				res[i] = applyDefaultTagsToValue(elem, typ.Elem())
+			}
+			return res
+		}
+
+	case reflect.Map:
+		if m, ok := v.(map[string]any); ok {
+			res := make(map[string]any, len(m))
+			for k, elem := range m {
+				res[k] = applyDefaultTagsToValue(elem, typ.Elem())
+			}
+			return res
+		}
+	}
+	return v
+}
+
+// 判断类型及其嵌套的类型上是否有 default tag 。 visiting 用于处理递归定义的类型。
+func hasDefaultTag(typ reflect.Type, visiting []reflect.Type) bool {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+
+	if elem, ok := optionalElemType(typ); ok {
+		return hasDefaultTag(elem, visiting)
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+
+	if v, ok := defaultTagCache.Load(typ); ok {
+		return v.(bool)
+	}
+
+	for _, v := range visiting {
+		if v == typ {
+			return false
+		}
+	}
+	visiting = append(visiting, typ)
+
+	res := false
+	eachParamField(typ, func(name string, f reflect.StructField) {
+		if res {
+			return
+		}
+
+		_, ok := f.Tag.Lookup(DefaultTagName)
+		res = ok || hasDefaultTag(f.Type, visiting)
+	})
+
+	// 递归的类型，在上层计算完成之前，下层的结果可能是不完整的，只缓存最外层的结果。
+	if len(visiting) == 1 {
+		defaultTagCache.Store(typ, res)
+	}
+	return res
+}
+
+// eachParamField 遍历作为请求参数的 struct 的公开字段，内嵌的 struct 会被展开。字段名称即参数名称（大小写不敏感）。
+func eachParamField(typ reflect.Type, fn func(name string, f reflect.StructField)) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				eachParamField(ft, fn)
+				continue
+			}
+		}
+
+		if f.IsExported() {
+			fn(f.Name, f)
+		}
+	}
+}
+
+// 以大小写不敏感的方式查找参数。
+func findParamIgnoreCase(params map[string]any, name string) (key string, value any, found bool) {
+	if v, ok := params[name]; ok {
+		return name, v, true
+	}
+
+	for k, v := range params {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
 	}
-	return lowercaseParam, nil
+	return "", nil, false
 }
@@ -21,7 +21,7 @@ import (
 )
 
 func Test_slimApiDecoder_Decode(t *testing.T) {
-	p := slimApiDecoderTestProvider{t}
+	p := slimApiDecoderTestProvider{t: t}
 
 	p.testOne(testOneArgs{methodName: "Empty"})
 
@@ -190,6 +190,183 @@ func Test_slimApiDecoder_Decode(t *testing.T) {
 		},
 	})
 
+	p.testOne(testOneArgs{
+		methodName: "Default",
+		tag:        "absent",
+		runMethods: RUN_ALL,
+		expected: []any{
+			defaultIn{
+				I:     3,
+				S:     "abc",
+				Sl:    []int{1, 2},
+				T:     Time(time.Date(2022, 11, 03, 7, 30, 6, 0, time.UTC)),
+				P:     &defaultPtrValue,
+				Inner: defaultInner{N: 7},
+			},
+		},
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "Default",
+		tag:        "present",
+		runMethods: RUN_ALL,
+		requestBody: map[string]any{
+			"i":  0, // 给出了零值，不使用默认值。
+			"S":  "",
+			"SL": "4",
+			"T":  "2022-04-17 21:18:25",
+			"p":  1,
+		},
+		expected: []any{
+			defaultIn{
+				Sl:    []int{4},
+				T:     Time(time.Date(2022, 4, 17, 21, 18, 25, 0, time.UTC)),
+				P:     &defaultPtrPresent,
+				Inner: defaultInner{N: 7},
+			},
+		},
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "Default",
+		tag:        "nested",
+		runMethods: RUN_JSON | RUN_MULTIPART_FORM,
+		requestBody: map[string]any{
+			"Inner": map[string]any{"S": "s"},
+			"Items": []any{
+				map[string]any{},
+				map[string]any{"n": 1},
+			},
+		},
+		expected: []any{
+			defaultIn{
+				I:     3,
+				S:     "abc",
+				Sl:    []int{1, 2},
+				T:     Time(time.Date(2022, 11, 03, 7, 30, 6, 0, time.UTC)),
+				P:     &defaultPtrValue,
+				Inner: defaultInner{N: 7, S: "s"},
+				Items: []defaultInner{{N: 7}, {N: 1}},
+			},
+		},
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "Default",
+		tag:        "null",
+		runMethods: RUN_JSON,
+		requestBody: map[string]any{
+			"I":     nil, // null 视同缺失。
+			"Inner": nil,
+		},
+		expected: []any{
+			defaultIn{
+				I:     3,
+				S:     "abc",
+				Sl:    []int{1, 2},
+				T:     Time(time.Date(2022, 11, 03, 7, 30, 6, 0, time.UTC)),
+				P:     &defaultPtrValue,
+				Inner: defaultInner{N: 7},
+			},
+		},
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "Optional",
+		runMethods: RUN_ALL,
+		requestBody: map[string]any{
+			"a": 1,
+		},
+		expected: []any{
+			optionalIn{
+				A: OptionalOf(1),
+				B: OptionalOf("b"),
+			},
+		},
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "Optional",
+		tag:        "null",
+		runMethods: RUN_JSON,
+		requestBody: map[string]any{
+			"a": nil,
+			"b": nil, // Optional 的 null 不视为缺失，不使用默认值。
+		},
+		expected: []any{
+			optionalIn{
+				A: OptionalNull[int](),
+				B: OptionalNull[string](),
+			},
+		},
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "Named",
+		runMethods: RUN_ALL,
+		paramNames: []string{"", "id", "NAME", "tags", "ptr", "absent"},
+		requestBody: map[string]any{
+			"Id":   "12",
+			"name": "abc",
+			"Tags": "1~2",
+			"ptr":  3,
+			"I":    4, // 没有名称的 struct 参数，依然从全部参数中读取。
+		},
+		expected: []any{
+			simpleIn{I: 4},
+			12,
+			"abc",
+			[]int{1, 2},
+			&namedPtrValue,
+			0,
+		},
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "Named",
+		tag:        "convert-error",
+		runMethods: RUN_GET,
+		paramNames: []string{"", "id"},
+		requestBody: map[string]any{
+			"id": "x",
+		},
+		errPattern: "bad request",
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "NamedAny",
+		runMethods: RUN_GET,
+		paramNames: []string{"v"},
+		errPattern: "parameter 'v' is required",
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "NamedStructs",
+		runMethods: RUN_JSON | RUN_MULTIPART_FORM,
+		paramNames: []string{"from", "", "to"},
+		requestBody: map[string]any{
+			"From": map[string]any{"I": 1, "StringField": "a"},
+			"to":   map[string]any{"i": 2},
+			"F":    1.5,
+		},
+		expected: []any{
+			simpleIn{I: 1, StringField: "a"},
+			struct{ F float32 }{1.5},
+			simpleIn{I: 2},
+		},
+	})
+
+	p.testOne(testOneArgs{
+		methodName: "NamedStructs",
+		tag:        "not-object",
+		runMethods: RUN_GET,
+		paramNames: []string{"from", "", "to"},
+		requestBody: map[string]any{
+			"from": "x",
+		},
+		errPattern: "bad request",
+	})
+
 	p.testOne(testOneArgs{
 		methodName: "WithApiState",
 		runMethods: RUN_ALL,
@@ -251,8 +428,120 @@ func Test_slimApiDecoder_Decode(t *testing.T) {
 	})
 }
 
+func Test_slimApiDecoder_Decode_from(t *testing.T) {
+	p := slimApiDecoderTestProvider{t: t}
+	setup := func(s webapitest.NewStateSetup) webapitest.NewStateSetup {
+		s.Headers = map[string]string{
+			"x-token": "t",
+			"Cookie":  "sid=s; other=c",
+		}
+		s.RouteParams = map[string]string{"ID": "5"}
+		return s
+	}
+
+	t.Run("get", func(t *testing.T) {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler,
+			urlBase+"?token=spoof&sid=spoof&id=9&page=2&name=n&other=o",
+			setup(webapitest.NewStateSetup{}))
+		p.doTestDecode(state, "From", meta_RequestFormat_Get, []any{
+			fromIn{Token: "t", Sid: "s", Id: 5, Page: 2, Other: "o"},
+		}, "")
+	})
+
+	t.Run("form", func(t *testing.T) {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler,
+			urlBase+"?name=q",
+			setup(webapitest.NewStateSetup{
+				HttpMethod:  http.MethodPost,
+				ContentType: webapi.ContentTypeForm,
+				BodyString:  "NAME=n&other=o&page=2",
+			}))
+		p.doTestDecode(state, "From", meta_RequestFormat_Post, []any{
+			fromIn{Token: "t", Sid: "s", Id: 5, Page: 1, Name: "n", Other: "o"},
+		}, "")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler,
+			urlBase+"?name=q&page=3",
+			setup(webapitest.NewStateSetup{
+				HttpMethod:  http.MethodPost,
+				ContentType: webapi.ContentTypeJson,
+				BodyString:  `{"Name":"n","page":4,"Token":"x","other":"o"}`,
+			}))
+		p.doTestDecode(state, "From", meta_RequestFormat_Json, []any{
+			fromIn{Token: "t", Sid: "s", Id: 5, Page: 3, Name: "n", Other: "o"},
+		}, "")
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, urlBase+"?token=spoof", webapitest.NewStateSetup{})
+		p.doTestDecode(state, "From", meta_RequestFormat_Get, []any{
+			fromIn{Page: 1},
+		}, "")
+	})
+
+	t.Run("duplicate-case", func(t *testing.T) {
+		// JSON 的 key 保留原始的大小写，全部同名参数都不能冒充 header 和 cookie 。
+		for i := 0; i < 20; i++ {
+			state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, urlBase, webapitest.NewStateSetup{
+				HttpMethod:  http.MethodPost,
+				ContentType: webapi.ContentTypeJson,
+				BodyString:  `{"Token":"spoof1","TOKEN":"spoof2","token":"spoof3","Sid":"s1","SID":"s2"}`,
+			})
+			p.doTestDecode(state, "From", meta_RequestFormat_Json, []any{
+				fromIn{Page: 1},
+			}, "")
+		}
+	})
+
+	t.Run("bad-tag", func(t *testing.T) {
+		type S struct {
+			A int `from:"what"`
+		}
+		typ := reflect.TypeOf(S{})
+		require.PanicsWithValue(t, `from tag of slimapi.S.A: unknown source "what"`, func() { parseFromTag(typ, typ.Field(0)) })
+
+		// 在注册方法时即发现错误。
+		h := NewSlimApiHandler("")
+		require.PanicsWithValue(t, `from tag of slimapi.S.A: unknown source "what"`, func() {
+			h.RegisterMethod(webapi.ApiMethod{Name: "M", Value: reflect.ValueOf(func(S) {})})
+		})
+	})
+}
+
+func Test_slimApiDecoder_Decode_defaultShared(t *testing.T) {
+	p := slimApiDecoderTestProvider{t: t}
+	state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, urlBase, webapitest.NewStateSetup{
+		HttpMethod:  http.MethodPost,
+		ContentType: webapi.ContentTypeJson,
+		BodyString:  `{"Inner":{"S":"x"},"Items":[{"S":"y"}]}`,
+	})
+	p.doTestDecode(state, "DefaultShared", meta_RequestFormat_Json, []any{
+		defaultSharedIn{Inner: defaultInner{N: 7, S: "x"}, Items: []defaultInner{{N: 7, S: "y"}}},
+		noDefaultIn{Inner: noDefaultInner{S: "x"}, Items: []noDefaultInner{{S: "y"}}},
+	}, "")
+}
+
+func Test_slimApiDecoder_Decode_defaultDuplicateCase(t *testing.T) {
+	p := slimApiDecoderTestProvider{t: t}
+
+	// 多个仅大小写不同的参数都为 null 时，都视为缺失；map 的遍历顺序是随机的，多试几次。
+	for i := 0; i < 20; i++ {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, urlBase, webapitest.NewStateSetup{
+			HttpMethod:  http.MethodPost,
+			ContentType: webapi.ContentTypeJson,
+			BodyString:  `{"S":null,"s":null,"Inner":{"S":"x"},"INNER":{"S":"x"}}`,
+		})
+		p.doTestDecode(state, "DefaultShared", meta_RequestFormat_Json, []any{
+			defaultSharedIn{Inner: defaultInner{N: 7, S: "x"}},
+			noDefaultIn{Inner: noDefaultInner{S: "x"}},
+		}, "")
+	}
+}
+
 func TestGG(t *testing.T) {
-	p := slimApiDecoderTestProvider{t}
+	p := slimApiDecoderTestProvider{t: t}
 	p.testOne(testOneArgs{
 		methodName: "Complex",
 		runMethods: RUN_JSON | RUN_MULTIPART_FORM, // 嵌套复杂类型不支持 GET 。
@@ -288,7 +577,8 @@ const urlBase = "http://temp.org/path/"
 
 // 用来封装测试需要的方法，公开方法作为 ApiState.Method ，非公开方法则是辅助方法。
 type slimApiDecoderTestProvider struct {
-	t *testing.T
+	t          *testing.T
+	paramNames []string // 方法的参数名称，见 webapi.ApiMetaParamNames 。
 }
 
 /*
@@ -315,6 +605,71 @@ func (slimApiDecoderTestProvider) Ptr(struct{ Ptr *string })   {}
 func (slimApiDecoderTestProvider) Slice(struct{ Sl []uint64 }) {}
 func (slimApiDecoderTestProvider) Time(timeIn)                 {}
 
+type defaultInner struct {
+	N int `default:"7"`
+	S string
+}
+
+type defaultIn struct {
+	I     int    `default:"3"`
+	S     string `default:"abc"`
+	Sl    []int  `default:"1~2"`
+	T     Time   `default:"2022-11-03 07:30:06"`
+	P     *int   `default:"5"`
+	Inner defaultInner
+	Items []defaultInner
+}
+
+var (
+	defaultPtrValue   = 5
+	defaultPtrPresent = 1
+)
+
+func (slimApiDecoderTestProvider) Default(defaultIn) {}
+
+type defaultSharedIn struct {
+	Inner defaultInner
+	Items []defaultInner
+}
+
+type noDefaultInner struct {
+	N int
+	S string
+}
+
+type noDefaultIn struct {
+	Inner noDefaultInner
+	Items []noDefaultInner
+}
+
+// 两个参数共用参数表中嵌套的对象，前者的默认值不能影响后者。
+func (slimApiDecoderTestProvider) DefaultShared(defaultSharedIn, noDefaultIn) {}
+
+type optionalIn struct {
+	A Optional[int]
+	B Optional[string] `default:"b"`
+	C Optional[int]
+}
+
+func (slimApiDecoderTestProvider) Optional(optionalIn) {}
+
+type fromIn struct {
+	Token string `from:"header=X-Token"`
+	Sid   string `from:"cookie=sid"`
+	Id    int    `from:"route"`
+	Page  int    `from:"query" default:"1"`
+	Name  string `from:"body"`
+	Other string
+}
+
+func (slimApiDecoderTestProvider) From(fromIn) {}
+
+var namedPtrValue = 3
+
+func (slimApiDecoderTestProvider) Named(simpleIn, int, string, []int, *int, int)        {}
+func (slimApiDecoderTestProvider) NamedAny(any)                                         {}
+func (slimApiDecoderTestProvider) NamedStructs(simpleIn, struct{ F float32 }, simpleIn) {}
+
 type complexIn struct {
 	F3Slice []*simpleIn
 	MM      map[string][]int
@@ -367,10 +722,12 @@ type testOneArgs struct {
 	expected        []any             // 预期的解析结果，顺序需和 methodName 对应方法的入参一致。可以用 ExpectedSpecialType 指代特定类型。
 	errPattern      string            // 断言 ApiState.Error 的消息。
 	panicMsgPattern string            // 正则，用于验证 panic 的消息；若预期不会 panic ，则为空。
+	paramNames      []string          // 方法的参数名称，见 webapi.ApiMetaParamNames 。
 }
 
 // 测试一个方法。
 func (p slimApiDecoderTestProvider) testOne(args testOneArgs) {
+	p.paramNames = args.paramNames
 	checkRecoveredError := func(t *testing.T, recovered any) {
 		require.NotNil(t, recovered, "should panic")
 		apiErr, ok := recovered.(webapi.ApiError)
@@ -593,6 +950,10 @@ func (p slimApiDecoderTestProvider) doTestDecode(
 		Provider: "",
 	}
 
+	if p.paramNames != nil {
+		state.Method.Meta = webapi.NewApiMeta(map[string]any{webapi.ApiMetaParamNames: p.paramNames})
+	}
+
 	decoder := NewSlimApiDecoder()
 	decoder.Decode(state)
 
@@ -0,0 +1,238 @@
+package slimapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/cmstar/go-webapi"
+)
+
+// DescribeMethodName 是 [RegisterDescribeMethod] 注册的元方法的名称。
+// 例如 handler 通过 "/api/{~method}" 注册时，可通过 /api/~describe 访问。
+const DescribeMethodName = "~describe"
+
+// MethodDescription 描述一个已注册的方法，是 [DescribeMethods] 的输出。
+type MethodDescription struct {
+	Name     string // 方法的名称。
+	Provider string // 方法提供者的名称，可为空。
+
+	// Params 是请求参数，由参数表中全部 struct 参数的字段，及有名称（见 [webapi.ApiMetaParamNames] ）的参数合并而成。
+	// 参数名称是大小写不敏感的。
+	Params []FieldDescription
+
+	// Data 描述回执中 Data 字段的类型。方法没有返回值时为 nil 。
+	// 对于流式输出的方法，描述的是流中每段数据的 Data ；对于 [webapi.JsonArray] ，描述的是整个数组。
+	// 返回 [*webapi.FileResponse] 的方法，其 Type 为 file ，回执是文件本身而不是信封。
+	Data *FieldDescription
+
+	// Streaming 对于流式输出的方法，为回执的 Content-Type ，如 text/event-stream ；否则为空。
+	Streaming string `json:",omitempty"`
+
+	// RequestStream 对于以流的方式读取请求 body 的方法（见 [StreamArgumentDecoder] ），为 body 的 Content-Type ，
+	// 即 application/octet-stream 、 multipart/form-data 或 application/x-ndjson ，此时 Params 需通过 query 给出；否则为空。
+	RequestStream string `json:",omitempty"`
+
+	// Meta 是注册方法时附带的元数据（ [webapi.ApiMethod.Meta] ）。
+	// 不能被 JSON 序列化的值（如 [*UploadPolicy] ）通常是管线的内部对象，不对外暴露，被略去。
+	Meta map[string]any `json:",omitempty"`
+}
+
+// FieldDescription 描述一个字段或值的类型。
+type FieldDescription struct {
+	// Name 是字段名称。描述非字段的值（如 [MethodDescription.Data] ）时为空。
+	Name string `json:",omitempty"`
+
+	// Type 是面向调用方的类型名称，可以是：
+	//   - 基础类型： bool/int/number/string 。
+	//   - time 时间，格式为 yyyy-MM-dd HH:mm:ss 或 RFC3339 。
+	//   - file 以 multipart/form-data 上传的文件，或下载的文件（ [*webapi.FileResponse] ）。
+	//   - object 对象，其字段记录在 Fields 上。
+	//   - any 任意值。
+	//   - T[] 元素为 T 的数组，如 int[] 、 object[] 。
+	//   - map<string,T> 值为 T 的字典。
+	Type string
+
+	// GoType 是 Go 中的类型名称，如 []int 、 main.User 。
+	GoType string
+
+	// From 是参数的来源，格式为 SOURCE=NAME ，如 header=X-Token ，见 [FromTagName] 。
+	// 仅方法参数的字段上有此值；没有 from tag 时为空，表示参数可来自 query 、路由或 body 。
+	From string `json:",omitempty"`
+
+	// Fields 在 Type 为对象（或对象的数组、字典）时，记录对象的字段。
+	// 对于递归定义的类型，已在上层出现过的类型不再展开。
+	Fields []FieldDescription `json:",omitempty"`
+}
+
+// RegisterDescribeMethod 在 register 上注册名为 [DescribeMethodName] 的元方法，
+// 其返回 [DescribeMethods] 的结果，用于调用方查阅已注册的方法。
+//
+// 此方法需要显式开启，以免对外暴露方法列表。
+func RegisterDescribeMethod(register webapi.ApiMethodRegister) {
+	register.RegisterMethod(webapi.ApiMethod{
+		Name: DescribeMethodName,
+		Value: reflect.ValueOf(func() []MethodDescription {
+			return DescribeMethods(register)
+		}),
+	})
+}
+
+// DescribeMethods 返回 register 上已注册的方法的描述，按名称（大小写不敏感）升序排列。
+// 名称以“~”开头的元方法不包含在内。
+func DescribeMethods(register webapi.ApiMethodRegister) []MethodDescription {
+	res := make([]MethodDescription, 0)
+	for _, m := range webapi.ListMethods(register) {
+		if isMetaMethod(m.Name) {
+			continue
+		}
+		res = append(res, describeMethod(m))
+	}
+	return res
+}
+
+// isMetaMethod 判断方法是否是元方法。以“~”开头的名称保留给元方法。
+func isMetaMethod(name string) bool {
+	return strings.HasPrefix(name, "~")
+}
+
+func describeMethod(m webapi.ApiMethod) MethodDescription {
+	typ := m.Value.Type()
+	res := MethodDescription{
+		Name:     m.Name,
+		Provider: m.Provider,
+		Params:   make([]FieldDescription, 0),
+	}
+
+	res.RequestStream = requestStreamContentType(typ)
+
+	for i := 0; i < typ.NumIn(); i++ {
+		in := typ.In(i)
+
+		// 有名称的参数，作为一个参数字段。
+		if name := m.ParamName(i); name != "" && !isInjectedArgType(in) {
+			d := describeType(in, openApiSchemaModeRequest, nil)
+			d.Name = name
+			res.Params = append(res.Params, d)
+			continue
+		}
+
+		if in.Kind() != reflect.Struct || isInjectedArgType(in) {
+			continue
+		}
+
+		eachStructField(in, openApiSchemaModeRequest, func(name string, f reflect.StructField) {
+			d := describeType(f.Type, openApiSchemaModeRequest, nil)
+			d.Name = name
+			if source, fromName, ok := parseFromTag(in, f); ok {
+				d.From = source + "=" + fromName
+			}
+			res.Params = append(res.Params, d)
+		})
+	}
+
+	if typ.NumOut() > 0 && typ.Out(0) != typeError {
+		dataType := typ.Out(0)
+		if dataType.Implements(typeStreamingResponse) {
+			res.Streaming = streamingContentType(dataType)
+			dataType = streamingEnvelopeDataType(dataType)
+		}
+
+		if dataType != nil {
+			d := describeType(dataType, openApiSchemaModeResponse, nil)
+			res.Data = &d
+		}
+	}
+
+	for _, k := range m.Meta.Keys() {
+		v, _ := m.Meta.Get(k)
+		if _, err := json.Marshal(v); err != nil {
+			continue
+		}
+
+		if res.Meta == nil {
+			res.Meta = make(map[string]any)
+		}
+		res.Meta[k] = v
+	}
+
+	return res
+}
+
+// visiting 记录上层已展开的 struct ，用于处理递归定义的类型。
+func describeFields(typ reflect.Type, mode openApiSchemaMode, visiting []reflect.Type) []FieldDescription {
+	res := make([]FieldDescription, 0)
+	eachStructField(typ, mode, func(name string, f reflect.StructField) {
+		d := describeType(f.Type, mode, visiting)
+		d.Name = name
+		res = append(res, d)
+	})
+	return res
+}
+
+func describeType(typ reflect.Type, mode openApiSchemaMode, visiting []reflect.Type) FieldDescription {
+	res := FieldDescription{GoType: typ.String()}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if elem, ok := optionalElemType(typ); ok {
+		d := describeType(elem, mode, visiting)
+		d.GoType = res.GoType
+		return d
+	}
+
+	switch typ {
+	case typeTime, typeSlimApiTime:
+		res.Type = "time"
+		return res
+
+	case typeFilePart.Elem(), typeFileHeader.Elem(), typeFileResponse.Elem():
+		res.Type = "file"
+		return res
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		res.Type = "bool"
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		res.Type = "int"
+
+	case reflect.Float32, reflect.Float64:
+		res.Type = "number"
+
+	case reflect.String:
+		res.Type = "string"
+
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			res.Type = "string"
+			break
+		}
+
+		elem := describeType(typ.Elem(), mode, visiting)
+		res.Type = elem.Type + "[]"
+		res.Fields = elem.Fields
+
+	case reflect.Map:
+		elem := describeType(typ.Elem(), mode, visiting)
+		res.Type = "map<string," + elem.Type + ">"
+		res.Fields = elem.Fields
+
+	case reflect.Struct:
+		res.Type = "object"
+		for _, v := range visiting {
+			if v == typ {
+				return res
+			}
+		}
+		res.Fields = describeFields(typ, mode, append(visiting, typ))
+
+	default:
+		res.Type = "any"
+	}
+
+	return res
+}
@@ -0,0 +1,188 @@
+package slimapi
+
+import (
+	"mime/multipart"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeMethods(t *testing.T) {
+	h := NewSlimApiHandler("")
+	h.RegisterMethods(openApiTestProvider{})
+	RegisterDescribeMethod(h)
+
+	webapi.NewEngine().Handle("/", h, nil).
+		SetMethodMeta("Do", "Deprecated", true).
+		SetMethodMeta("Do", "Func", func() {}).
+		SetMethodMeta("Empty", ApiMetaUploadPolicy, &UploadPolicy{MaxFileSize: 1})
+
+	res := DescribeMethods(h)
+	require.Len(t, res, 5)
+
+	var names []string
+	for _, v := range res {
+		names = append(names, v.Name)
+	}
+	require.Equal(t, []string{"Do", "Empty", "Error", "Events", "Lines"}, names)
+
+	t.Run("empty", func(t *testing.T) {
+		d := res[1]
+		require.Equal(t, "openApiTestProvider", d.Provider)
+		require.Empty(t, d.Params)
+		require.Nil(t, d.Data)
+		require.Empty(t, d.Streaming)
+		require.Nil(t, d.Meta) // 不能被 JSON 序列化的元数据被略去。
+	})
+
+	t.Run("error", func(t *testing.T) {
+		d := res[2]
+		require.Empty(t, d.Params)
+		require.Nil(t, d.Data)
+	})
+
+	t.Run("do", func(t *testing.T) {
+		d := res[0]
+		require.Equal(t, map[string]any{"Deprecated": true}, d.Meta)
+
+		params := make(map[string]FieldDescription)
+		for _, v := range d.Params {
+			params[v.Name] = v
+		}
+		require.Len(t, params, 14)
+
+		require.Equal(t, FieldDescription{Name: "E", Type: "string", GoType: "string"}, params["E"])
+		require.Equal(t, FieldDescription{Name: "Other", Type: "bool", GoType: "bool"}, params["Other"])
+		require.Equal(t, FieldDescription{Name: "Tags", Type: "string[]", GoType: "[]string"}, params["Tags"])
+		require.Equal(t, FieldDescription{Name: "Map", Type: "map<string,number>", GoType: "map[string]float64"}, params["Map"])
+		require.Equal(t, FieldDescription{Name: "File", Type: "file", GoType: "*slimapi.FilePart"}, params["File"])
+		require.Equal(t, FieldDescription{Name: "Header", Type: "file", GoType: "*multipart.FileHeader"}, params["Header"])
+		require.Equal(t, FieldDescription{Name: "Files", Type: "file[]", GoType: "[]*slimapi.FilePart"}, params["Files"])
+		require.Equal(t, FieldDescription{Name: "Raw", Type: "string", GoType: "[]uint8"}, params["Raw"])
+		require.Equal(t, FieldDescription{Name: "Opt", Type: "int", GoType: "slimapi.Optional[int]"}, params["Opt"])
+		require.Equal(t, FieldDescription{Name: "Token", Type: "string", GoType: "string", From: "header=X-Token"}, params["Token"])
+		require.Equal(t, FieldDescription{Name: "Id", Type: "int", GoType: "int", From: "route=Id"}, params["Id"])
+
+		// 请求参数使用字段名称。
+		require.Equal(t, FieldDescription{
+			Name: "Renamed", Type: "object", GoType: "*slimapi.openApiTestRenamed",
+			Fields: []FieldDescription{
+				{Name: "A", Type: "string", GoType: "string"},
+				{Name: "B", Type: "int", GoType: "int"},
+				{Name: "Time", Type: "time", GoType: "time.Time"},
+			},
+		}, params["Renamed"])
+
+		// 递归的类型只展开一层。
+		require.Equal(t, FieldDescription{
+			Name: "Node", Type: "object", GoType: "slimapi.openApiTestNode",
+			Fields: []FieldDescription{
+				{Name: "Value", Type: "int", GoType: "int"},
+				{Name: "Children", Type: "object[]", GoType: "[]*slimapi.openApiTestNode"},
+			},
+		}, params["Node"])
+
+		// 回执使用 JSON 序列化的名称。
+		require.Equal(t, &FieldDescription{
+			Type: "object", GoType: "slimapi.openApiTestRenamed",
+			Fields: []FieldDescription{
+				{Name: "a", Type: "string", GoType: "string"},
+				{Name: "Time", Type: "time", GoType: "time.Time"},
+			},
+		}, d.Data)
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		d := res[3]
+		require.Equal(t, webapi.ContentTypeEventStream, d.Streaming)
+		require.Equal(t, "object", d.Data.Type)
+
+		d = res[4]
+		require.Equal(t, webapi.ContentTypeNdJson, d.Streaming)
+		require.Equal(t, "int[]", d.Data.Type)
+	})
+}
+
+func TestDescribeMethods_named(t *testing.T) {
+	d := describeMethod(webapi.ApiMethod{
+		Name:  "N",
+		Value: reflect.ValueOf(func(*webapi.ApiState, int, struct{ A string }, *FilePart) {}),
+		Meta:  webapi.NewApiMeta(map[string]any{webapi.ApiMetaParamNames: []string{"state", "id", "", "file"}}),
+	})
+	require.Equal(t, []FieldDescription{
+		{Name: "id", Type: "int", GoType: "int"},
+		{Name: "A", Type: "string", GoType: "string"},
+		{Name: "file", Type: "file", GoType: "*slimapi.FilePart"},
+	}, d.Params)
+}
+
+func TestDescribeMethods_stream(t *testing.T) {
+	d := describeMethod(webapi.ApiMethod{Value: reflect.ValueOf(func(*multipart.Reader, struct{ A string }) {})})
+	require.Equal(t, webapi.ContentTypeMultipartForm, d.RequestStream)
+	require.Equal(t, []FieldDescription{{Name: "A", Type: "string", GoType: "string"}}, d.Params)
+
+	d = describeMethod(webapi.ApiMethod{Value: reflect.ValueOf(func() {})})
+	require.Empty(t, d.RequestStream)
+}
+
+func TestDescribeMethods_fileResponse(t *testing.T) {
+	d := describeMethod(webapi.ApiMethod{Value: reflect.ValueOf(func() (*webapi.FileResponse, error) { return nil, nil })})
+	require.Equal(t, &FieldDescription{Type: "file", GoType: "*webapi.FileResponse"}, d.Data)
+	require.Empty(t, d.Streaming)
+}
+
+func TestDescribeMethods_sseEvent(t *testing.T) {
+	d := describeMethod(webapi.ApiMethod{
+		Value: reflect.ValueOf(func(id webapi.LastEventId, req struct{ A int }) webapi.EventStream[webapi.SseEvent[int]] { return nil }),
+		Meta:  webapi.NewApiMeta(map[string]any{webapi.ApiMetaParamNames: []string{"id"}}),
+	})
+	require.Equal(t, &FieldDescription{Type: "int", GoType: "int"}, d.Data)
+	require.Equal(t, webapi.ContentTypeEventStream, d.Streaming)
+
+	// LastEventId 不是请求参数。
+	require.Len(t, d.Params, 1)
+	require.Equal(t, "A", d.Params[0].Name)
+}
+
+func TestDescribeMethods_jsonArray(t *testing.T) {
+	d := describeMethod(webapi.ApiMethod{Value: reflect.ValueOf(func() webapi.JsonArray[int] { return nil })})
+	require.Equal(t, &FieldDescription{Type: "int[]", GoType: "[]int"}, d.Data)
+	require.Equal(t, webapi.ContentTypeJsonArrayStream, d.Streaming)
+}
+
+func TestDescribeMethods_longPoll(t *testing.T) {
+	d := describeMethod(webapi.ApiMethod{
+		Value: reflect.ValueOf(func(lp webapi.LongPoll, req struct{ A int }) (webapi.LongPollResult[int], error) {
+			return webapi.LongPollResult[int]{}, nil
+		}),
+	})
+
+	// LongPoll 不是请求参数。
+	require.Len(t, d.Params, 1)
+	require.Equal(t, "A", d.Params[0].Name)
+}
+
+func TestRegisterDescribeMethod(t *testing.T) {
+	h := NewSlimApiHandler("")
+	h.RegisterMethods(openApiTestProvider{})
+	RegisterDescribeMethod(h)
+
+	e := webapi.NewEngine()
+	e.Handle("/api/{~method}", h, nil)
+	s := httptest.NewServer(e)
+	defer s.Close()
+
+	invoker := NewSlimApiInvoker[struct{}, []MethodDescription](s.URL + "/api/" + DescribeMethodName)
+	res := invoker.MustDo(struct{}{})
+	require.Len(t, res, 5)
+	require.Equal(t, "Do", res[0].Name)
+	require.Equal(t, "Renamed", res[0].Params[5].Name)
+
+	// 元方法不出现在 OpenAPI 文档中。
+	doc := NewOpenApiDocument(h, OpenApiOp{})
+	require.Len(t, doc.Paths, 5)
+	require.NotContains(t, doc.Paths, "/"+DescribeMethodName)
+}
@@ -0,0 +1,61 @@
+package slimapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/cmstar/go-webapi"
+)
+
+//go:embed explorer.html
+var explorerHtml string
+
+// explorerConfigPlaceholder 是 explorer.html 中用于注入配置的占位符。
+const explorerConfigPlaceholder = "/*CONFIG*/null"
+
+// ExplorerOp 用于 [ExplorerHandlerFunc] ，提供页面的配置。
+type ExplorerOp struct {
+	// Title 是页面的标题。为空时使用 "SlimAPI Explorer" 。
+	Title string
+
+	// PathPrefix 是方法路径的前缀，页面请求方法时使用 PathPrefix + "/" + 方法名称 作为 URL 。
+	// 例如通过 "/api/{~method}" 注册 handler 时，应指定为 "/api" 。
+	// 可以是完整的 URL ，以访问其他服务器上的 API （需服务器允许跨域）。
+	PathPrefix string
+}
+
+// ExplorerHandlerFunc 返回一个输出 API 调试页面的 [http.HandlerFunc] 。
+// 页面是独立的 HTML ，不依赖任何外部资源。通过页面可以：
+//   - 浏览 register 上注册的方法，及其参数和返回值的类型（同 [DescribeMethods] ）。
+//   - 填写参数，以 JSON/表单/GET/multipart 格式发起请求。
+//   - 在浏览器中计算 SlimAuth 签名（需 HTTPS 或 localhost 以使用 WebCrypto ）。
+//   - 查看原始的回执，流式输出（ SSE/NDJSON ）的数据在收到时即显示。
+//
+// 方法列表在每次请求页面时生成。
+// 页面会暴露全部方法的定义，应仅在开发、测试环境中开启。可通过 [webapi.ApiEngine.HandleGet] 将其挂在指定的路径上：
+//
+//	e.HandleGet("/explorer", slimapi.ExplorerHandlerFunc(handler, slimapi.ExplorerOp{PathPrefix: "/api"}))
+func ExplorerHandlerFunc(register webapi.ApiMethodRegister, op ExplorerOp) http.HandlerFunc {
+	if op.Title == "" {
+		op.Title = "SlimAPI Explorer"
+	}
+	op.PathPrefix = strings.TrimRight(op.PathPrefix, "/")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// json.Marshal 默认转义 <>& ，输出的内容可安全地放在 <script> 里。
+		config, err := json.Marshal(struct {
+			Title      string
+			PathPrefix string
+			Methods    []MethodDescription
+		}{op.Title, op.PathPrefix, DescribeMethods(register)})
+		if err != nil {
+			panic(err)
+		}
+
+		page := strings.Replace(explorerHtml, explorerConfigPlaceholder, string(config), 1)
+		w.Header().Set(webapi.HttpHeaderContentType, "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}
+}
@@ -0,0 +1,54 @@
+package slimapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplorerHandlerFunc(t *testing.T) {
+	require.Contains(t, explorerHtml, explorerConfigPlaceholder)
+
+	h := NewSlimApiHandler("")
+	h.RegisterMethods(openApiTestProvider{})
+
+	e := webapi.NewEngine()
+	e.HandleGet("/explorer", ExplorerHandlerFunc(h, ExplorerOp{Title: "</script><b>", PathPrefix: "/api/"}))
+	s := httptest.NewServer(e)
+	defer s.Close()
+
+	resp, err := s.Client().Get(s.URL + "/explorer")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/html; charset=utf-8", resp.Header.Get(webapi.HttpHeaderContentType))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	page := string(body)
+
+	// 页面不引用外部资源。
+	require.NotRegexp(t, `(src|href)="https?:`, page)
+
+	// 标题被转义，不能截断 <script> 。
+	require.Equal(t, 2, strings.Count(page, "</script>"))
+
+	m := regexp.MustCompile(`(?s)<script id="config" type="application/json">(.*?)</script>`).FindStringSubmatch(page)
+	require.Len(t, m, 2)
+
+	var config struct {
+		Title      string
+		PathPrefix string
+		Methods    []MethodDescription
+	}
+	require.NoError(t, json.Unmarshal([]byte(m[1]), &config))
+	require.Equal(t, "</script><b>", config.Title)
+	require.Equal(t, "/api", config.PathPrefix)
+	require.Len(t, config.Methods, 5)
+	require.Equal(t, "Do", config.Methods[0].Name)
+}
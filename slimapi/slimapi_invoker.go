@@ -3,12 +3,16 @@ package slimapi
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"iter"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cmstar/go-errx"
 	"github.com/cmstar/go-webapi"
@@ -17,15 +21,41 @@ import (
 // SlimApiInvoker 用于调用一个 SlimAPI 。
 //
 // TParam 是输入参数的类型； TData 对应输出的 [webapi.ApiResponse.Data] 。
-// 若 API 返回 SSE/NDJSON 格式，则 TData 对应每一批次数据的 Data 字段。
+// 若 API 返回 SSE/NDJSON 格式，则 TData 对应每一批次数据的 Data 字段；若返回 [webapi.JsonArray] ，则 TData 对应数组的元素。
 type SlimApiInvoker[TParam, TData any] struct {
-	// 目标 URL 。
+	// 目标 URL 。若指定了 Balancer ，则为追加在节点基础 URL 后面的相对路径。
 	Uri string
 
+	// 若不为 nil ，则请求通过此 [LoadBalancer] 在多个节点间分发，并在可重试的失败发生时自动转移到其他节点。
+	Balancer *LoadBalancer
+
+	// Client 是用于发送请求的 [http.Client] ，为 nil 时使用一个零值的 http.Client 。
+	// 其 Timeout 包含读取 body 的时间，对流式响应和长轮询也生效。超时被视为调用方的原因，不会使 Balancer 剔除节点。
+	Client *http.Client
+
 	// 若不为 nil ，则在 [http.Client.Do] 之前，调用此函数对当前请求进行处理。
+	// 它在 Middlewares 之后、请求发送之前的最后时刻执行，因此看到的是经过全部中间件处理后的请求。
 	RequestSetup func(r *http.Request) error
+
+	// Middlewares 是包裹在每次请求外层的中间件，可用于日志、统计、添加 HTTP 头等。
+	// 第一个元素位于最外层，最先执行。
+	Middlewares InvokeChain
+
+	// SseRetries 是 SSE 流意外中断（读取出错，或没有收到 END 事件就结束）后，自动重连的最大连续次数，为 0 时不重连。
+	// 重连时重新发送请求，并在 Last-Event-ID 头中给出最后收到的事件的 id ，服务端据此从断点继续输出（见 [webapi.LastEventId] ）。
+	// 重连前等待服务端最后给出的 retry 时间，没有时为 [DefaultSseRetryDelay] 。每收到一个事件，重新计数。
+	SseRetries int
+
+	// LongPollWait 是 [SlimApiInvoker.DoLongPoll] 每次请求要求服务端等待的时长，按秒向上取整后放在 ~wait 元参数中。
+	// 小于等于 0 时不给出，服务端等待其允许的最长时间（见 [webapi.LongPollMaxWait] ）。
+	LongPollWait time.Duration
+
+	ctx context.Context // 由 WithContext 设置，为 nil 时使用 context.Background() 。
 }
 
+// DefaultSseRetryDelay 是服务端没有给出 retry 时， [SlimApiInvoker] 重连 SSE 流前等待的时间。
+const DefaultSseRetryDelay = 3 * time.Second
+
 // SlimApiInvoker 创建一个 [SlimApiInvoker] 实例。
 func NewSlimApiInvoker[TParam, TData any](uri string) *SlimApiInvoker[TParam, TData] {
 	if uri == "" {
@@ -37,6 +67,33 @@ func NewSlimApiInvoker[TParam, TData any](uri string) *SlimApiInvoker[TParam, TD
 	}
 }
 
+// NewBalancedSlimApiInvoker 创建一个通过 [LoadBalancer] 在多个节点间分发请求的 [SlimApiInvoker] 实例。
+// path 是追加在节点的基础 URL 后面的相对路径，如基础 URL 为 http://10.0.0.1/api ， path 为 Plus ，
+// 则请求的地址为 http://10.0.0.1/api/Plus 。
+func NewBalancedSlimApiInvoker[TParam, TData any](balancer *LoadBalancer, path string) *SlimApiInvoker[TParam, TData] {
+	if balancer == nil {
+		panic("balancer must be provided")
+	}
+
+	return &SlimApiInvoker[TParam, TData]{
+		Uri:      path,
+		Balancer: balancer,
+	}
+}
+
+// WithContext 返回一个副本，其发出的请求均使用给定的 ctx ，可用于取消调用或设置超时。若 ctx 为 nil ，则 panic 。
+//
+// ctx 被取消或超时后，正在进行的请求及流式响应的读取被中断， SSE 重连、长轮询等不再继续。
+// 指定了 Balancer 时，由此导致的错误不会使节点被剔除，也不会转移到其他节点。
+func (x SlimApiInvoker[TParam, TData]) WithContext(ctx context.Context) SlimApiInvoker[TParam, TData] {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	x.ctx = ctx
+	return x
+}
+
 // MustDoRaw 执行请求，并返回原始的 [webapi.ApiResponse] ，不会判断对应的 Code 值。
 //
 // 这是 [SlimApiInvoker.DoRaw] 的 panic 版本。
@@ -54,40 +111,75 @@ func (x SlimApiInvoker[TParam, TData]) MustDoRaw(params TParam) webapi.ApiRespon
 //
 // 若获得 SSE/NDJSON 流式响应，则返回错误。此时应使用 [SlimApiInvoker.DoRawStream] 等支持流式响应的方法。
 func (x SlimApiInvoker[TParam, TData]) DoRaw(params TParam) (res webapi.ApiResponse[TData], err error) {
-	response, err := x.request(params)
-	if err != nil {
-		// err 已经是包装过的，无需再包装。
-		return
-	}
+	ctx, err := x.invoke(params, "")
+	return x.rawResponse(ctx, err)
+}
 
-	defer func() {
-		// 对于流式输出的 API ，由于方法提前返回错误，这里未读取 body 就直接将其关闭，会影响当前连接的复用，但好过在流式内容上卡住。
-		e := response.Body.Close()
-		if err == nil {
-			err = e
+// DoRawSeq 以 Content-Type: application/x-ndjson 方式发送请求， items 中的每一项序列化为 body 中的一行 JSON ，
+// 在请求发送的过程中逐项读取，用于调用参数为 iter.Seq2[T, error] 的方法（见 [StreamArgumentDecoder] ）。
+// 返回原始的 [webapi.ApiResponse] ，不会判断对应的 Code 值。
+//
+// items 给出错误时，请求被中断，返回该错误。由于 body 不能重新读取，指定了 Balancer 时也不会转移到其他节点重试。
+//
+// 若获得 SSE/NDJSON 流式响应，则返回错误。
+func (x SlimApiInvoker[TParam, TData]) DoRawSeq(items iter.Seq2[TParam, error]) (res webapi.ApiResponse[TData], err error) {
+	pr, pw := io.Pipe()
+	go func() {
+		encoder := json.NewEncoder(pw)
+		for item, err := range items {
+			if err == nil {
+				err = encoder.Encode(item)
+			}
+
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
 		}
-		// Drop e if err is not nil.
+		pw.Close()
 	}()
 
-	contentType := x.getContentType(response.Header.Get(webapi.HttpHeaderContentType))
-	if contentType == webapi.ContentTypeEventStream || contentType == webapi.ContentTypeNdJson {
-		err = fmt.Errorf(`request "%s": streaming response %s, use DoRawStream/MustDoStream instead`, x.Uri, contentType)
-		return
-	}
+	// 请求提前结束时（如服务端返回错误），使写入方不再阻塞。
+	defer pr.Close()
 
-	out, err := io.ReadAll(response.Body)
+	ctx, err := x.invokeBody(pr, webapi.ContentTypeNdJson, "")
+	return x.rawResponse(ctx, err)
+}
+
+// DoSeq 同 [SlimApiInvoker.DoRawSeq] ，并在 [webapi.ApiResponse.Code] 为 0 时返回 [webapi.ApiResponse.Data] 。
+// 若 Code 不是 0 ，则返回 [errx.BizError] 。
+func (x SlimApiInvoker[TParam, TData]) DoSeq(items iter.Seq2[TParam, error]) (data TData, err error) {
+	res, err := x.DoRawSeq(items)
 	if err != nil {
-		err = x.wrapErr(err)
 		return
 	}
+	return x.responseData(res)
+}
 
-	err = json.Unmarshal(out, &res)
+// 处理 invoke 的结果，返回非流式响应的 [webapi.ApiResponse] 。
+func (x SlimApiInvoker[TParam, TData]) rawResponse(ctx *InvokeContext, err error) (res webapi.ApiResponse[TData], _ error) {
 	if err != nil {
-		err = x.wrapErr(err)
-		return
+		// err 已经是包装过的，无需再包装。
+		return res, err
 	}
 
-	return
+	if ctx.Streaming {
+		// 对于流式输出的 API ，由于方法提前返回错误，这里未读取 body 就直接将其关闭，会影响当前连接的复用，但好过在流式内容上卡住。
+		_ = ctx.Response.Body.Close()
+		err = fmt.Errorf(`request "%s": streaming response %s, use DoRawStream/MustDoStream instead`, x.Uri, x.getContentType(ctx.Response.Header.Get(webapi.HttpHeaderContentType)))
+		return res, err
+	}
+
+	return x.apiResponse(ctx)
+}
+
+// 读取 ctx.ApiResponse 。中间件可能未调用下一环节就返回，或设置了其他类型的值，此时返回错误。
+func (x SlimApiInvoker[TParam, TData]) apiResponse(ctx *InvokeContext) (webapi.ApiResponse[TData], error) {
+	res, ok := ctx.ApiResponse.(webapi.ApiResponse[TData])
+	if !ok {
+		return res, x.wrapErr(fmt.Errorf("InvokeContext.ApiResponse should be %T, got %T", res, ctx.ApiResponse))
+	}
+	return res, nil
 }
 
 // MustDo 执行请求，并在 [webapi.ApiResponse.Code] 为 0 时返回 [webapi.ApiResponse.Data] 。
@@ -111,7 +203,11 @@ func (x SlimApiInvoker[TParam, TData]) Do(params TParam) (data TData, err error)
 	if err != nil {
 		return
 	}
+	return x.responseData(res)
+}
 
+// 在 Code 为 0 时返回 Data ，否则返回 [errx.BizError] 。
+func (x SlimApiInvoker[TParam, TData]) responseData(res webapi.ApiResponse[TData]) (data TData, err error) {
 	if res.Code != 0 {
 		cause := fmt.Errorf(`request "%s": (%d) %s`, x.Uri, res.Code, res.Message)
 		err = errx.NewBizError(res.Code, res.Message, cause)
@@ -147,101 +243,250 @@ func (x SlimApiInvoker[TParam, TData]) MustDoStream(params TParam) iter.Seq[TDat
 	}
 }
 
-// DoRawStream 执行请求，并返回流式结果的迭代器。
+// DoRawStream 执行请求，并返回流式结果的迭代器。请求在开始迭代时发送。
 //
 // 规则：
 //   - 若在获取第一个 [webapi.ApiResponse] 前出错（如 HTTP 请求错误），迭代器仅返回一项，错误放在该项的 error 上。
 //   - 若 HTTP 响应不是流式结果，而是标准的 SlimAPI 格式，迭代器仅返回一项，包含对应的 ApiResponse ，同时 error 为 nil。
 //   - 若流式响应处理过程中，出现格式错误，错误将放在迭代器结果的 error 上，迭代停止。
+//   - 对于 [webapi.JsonArray] ，数组的每个元素作为一项 Code 为 0 的 ApiResponse 给出；若文档的 Code 不为 0 ，最后一项带有其 Code 和 Message 。
+//   - SSE 流意外中断时，按 SseRetries 自动重连；不再重连时，若中断是由错误引起的，错误将放在迭代器结果的 error 上。
 func (x SlimApiInvoker[TParam, TData]) DoRawStream(params TParam) iter.Seq2[webapi.ApiResponse[TData], error] {
-	response, err := x.request(params)
-	if err != nil {
-		// err 已经是包装过的，无需再包装。
-		return func(yield func(webapi.ApiResponse[TData], error) bool) {
-			yield(webapi.ApiResponse[TData]{}, err)
+	seq := x.DoRawEventStream(params)
+	return func(yield func(webapi.ApiResponse[TData], error) bool) {
+		for ev, err := range seq {
+			if !yield(ev.Data, err) {
+				return
+			}
 		}
 	}
+}
 
-	contentType := x.getContentType(response.Header.Get(webapi.HttpHeaderContentType))
+// DoRawEventStream 同 [SlimApiInvoker.DoRawStream] ，但每一项还给出 SSE 事件的 id 、名称和 retry 。
+// 对于 NDJSON 流和非流式响应，这些字段为零值。
+func (x SlimApiInvoker[TParam, TData]) DoRawEventStream(params TParam) iter.Seq2[webapi.SseEvent[webapi.ApiResponse[TData]], error] {
+	return func(yield func(webapi.SseEvent[webapi.ApiResponse[TData]], error) bool) {
+		stream := &sseStreamState{}
+		failures := 0
+		for {
+			received := stream.received
+			done, err := x.readStream(params, stream, yield)
+			if done {
+				return
+			}
 
-	// 非流式输出，结果作为单次响应返回。
-	if contentType != webapi.ContentTypeEventStream && contentType != webapi.ContentTypeNdJson {
-		var err error
-		var res webapi.ApiResponse[TData]
-		defer func() {
-			e := response.Body.Close()
-			if err == nil {
-				err = e
+			if stream.received > received {
+				failures = 0
 			}
-			// Drop e if err is not nil.
-		}()
+			failures++
 
-		out, err := io.ReadAll(response.Body)
-		if err != nil {
-			err = x.wrapErr(err)
-			return func(yield func(webapi.ApiResponse[TData], error) bool) {
-				yield(webapi.ApiResponse[TData]{}, err)
+			// 只有已经建立的 SSE 流可以重连。
+			if !stream.started || failures > x.SseRetries {
+				if err != nil {
+					yield(webapi.SseEvent[webapi.ApiResponse[TData]]{}, err)
+				}
+				return
 			}
-		}
 
-		err = json.Unmarshal(out, &res)
-		if err != nil {
-			err = x.wrapErr(err)
-			return func(yield func(webapi.ApiResponse[TData], error) bool) {
-				yield(webapi.ApiResponse[TData]{}, err)
+			if err := x.sleep(stream.retryDelay()); err != nil {
+				yield(webapi.SseEvent[webapi.ApiResponse[TData]]{}, err)
+				return
 			}
 		}
+	}
+}
 
-		return func(yield func(webapi.ApiResponse[TData], error) bool) {
-			yield(res, nil)
-		}
+// 记录 SSE 流的读取状态，用于重连。
+type sseStreamState struct {
+	started  bool          // 是否已经获得了 SSE 流。
+	lastId   string        // 最后收到的 id 。
+	retry    time.Duration // 服务端最后给出的 retry 。
+	received int           // 已收到的事件数。
+}
+
+func (x *sseStreamState) retryDelay() time.Duration {
+	if x.retry > 0 {
+		return x.retry
 	}
+	return DefaultSseRetryDelay
+}
 
-	return func(yield func(webapi.ApiResponse[TData], error) bool) {
-		body := response.Body
-		defer body.Close()
-
-		switch contentType {
-		case webapi.ContentTypeEventStream:
-			x.yieldFromSSE(body, yield)
-		case webapi.ContentTypeNdJson:
-			x.yieldFromNdJSON(body, yield)
-		}
+// readStream 发送一次请求并读取结果。
+// done 表示不需要重连：流已正常结束、响应不是 SSE 流、出现了格式错误（已通过 yield 给出）或 yield 返回了 false ；
+// 否则 err 为请求或读取中的错误， SSE 流没有收到 END 事件就结束时， err 为 nil 。
+func (x SlimApiInvoker[TParam, TData]) readStream(params TParam, stream *sseStreamState, yield func(webapi.SseEvent[webapi.ApiResponse[TData]], error) bool) (done bool, err error) {
+	ctx, err := x.invoke(params, stream.lastId)
+	if err != nil {
+		// err 已经是包装过的，无需再包装。
+		return false, err
 	}
+
+	// 非流式输出，结果作为单次响应返回。
+	if !ctx.Streaming {
+		res, err := x.apiResponse(ctx)
+		yield(webapi.SseEvent[webapi.ApiResponse[TData]]{Data: res}, err)
+		return true, nil
+	}
+
+	body := ctx.Response.Body
+	defer body.Close()
+
+	rawContentType := ctx.Response.Header.Get(webapi.HttpHeaderContentType)
+	if isJsonArrayStream(rawContentType) {
+		x.yieldFromJsonArray(body, func(res webapi.ApiResponse[TData], err error) bool {
+			return yield(webapi.SseEvent[webapi.ApiResponse[TData]]{Data: res}, err)
+		})
+		return true, nil
+	}
+
+	switch x.getContentType(rawContentType) {
+	case webapi.ContentTypeEventStream:
+		stream.started = true
+		return x.yieldFromSSE(body, stream, yield)
+
+	case webapi.ContentTypeNdJson:
+		x.yieldFromNdJSON(body, func(res webapi.ApiResponse[TData], err error) bool {
+			return yield(webapi.SseEvent[webapi.ApiResponse[TData]]{Data: res}, err)
+		})
+	}
+	return true, nil
 }
 
-// 执行请求，并返回状态码 200 的 Response ；否则返回错误。
-func (x SlimApiInvoker[TParam, TData]) request(params TParam) (res *http.Response, errWrapped error) {
+// invoke 构建请求，并经过 Middlewares 执行。 lastEventId 不为空时，放在 Last-Event-ID 头上，用于 SSE 流的重连。
+// 若返回的 [InvokeContext.Streaming] 为 true ，则调用方负责关闭 [InvokeContext.Response] 的 body 。
+func (x SlimApiInvoker[TParam, TData]) invoke(params TParam, lastEventId string) (*InvokeContext, error) {
 	in, err := json.Marshal(params)
 	if err != nil {
 		return nil, x.wrapErr(err)
 	}
+	return x.invokeBody(bytes.NewBuffer(in), webapi.ContentTypeJson, lastEventId)
+}
 
-	request, err := http.NewRequest(http.MethodPost, x.Uri, bytes.NewBuffer(in))
+// invokeBody 同 invoke ，但使用给定的 body 和 Content-Type 。
+func (x SlimApiInvoker[TParam, TData]) invokeBody(body io.Reader, contentType, lastEventId string) (*InvokeContext, error) {
+	request, err := http.NewRequestWithContext(x.context(), http.MethodPost, x.Uri, body)
 	if err != nil {
 		return nil, x.wrapErr(err)
 	}
+	request.Header.Set(webapi.HttpHeaderContentType, contentType)
+	if lastEventId != "" {
+		request.Header.Set(webapi.HttpHeaderLastEventId, lastEventId)
+	}
 
-	request.Header.Set(webapi.HttpHeaderContentType, webapi.ContentTypeJson)
-	if x.RequestSetup != nil {
-		err = x.RequestSetup(request)
-		if err != nil {
-			return nil, x.wrapErr(err)
+	ctx := &InvokeContext{
+		Uri:     x.Uri,
+		Request: request,
+	}
+
+	err = x.Middlewares.Then(x.send)(ctx)
+	if err != nil {
+		// 中间件可能在拿到流式响应后才返回错误，此时 body 没有别的地方会关闭了。
+		if ctx.Streaming && ctx.Response != nil {
+			_ = ctx.Response.Body.Close()
 		}
+		return nil, err
 	}
 
-	response, err := new(http.Client).Do(request)
+	return ctx, nil
+}
+
+// send 是 Middlewares 的最内层：发送请求，要求状态码为 200 ，并读取非流式响应的结果，填入 ctx 。
+func (x SlimApiInvoker[TParam, TData]) send(ctx *InvokeContext) (err error) {
+	response, err := x.roundTrip(ctx)
 	if err != nil {
-		return nil, x.wrapErr(err)
+		return x.wrapErr(err)
 	}
+	ctx.Response = response
 
 	if response.StatusCode != http.StatusOK {
 		b, _ := io.ReadAll(response.Body)
 		_ = response.Body.Close()
-		return nil, x.wrapErr(fmt.Errorf("unexpected HTTP status %d: %s", response.StatusCode, string(b)))
+		return x.wrapErr(fmt.Errorf("unexpected HTTP status %d: %s", response.StatusCode, string(b)))
 	}
 
-	return response, nil
+	rawContentType := response.Header.Get(webapi.HttpHeaderContentType)
+	contentType := x.getContentType(rawContentType)
+	if contentType == webapi.ContentTypeEventStream || contentType == webapi.ContentTypeNdJson || isJsonArrayStream(rawContentType) {
+		ctx.Streaming = true
+		return nil
+	}
+
+	defer func() {
+		e := response.Body.Close()
+		if err == nil && e != nil {
+			err = x.wrapErr(e)
+		}
+		// Drop e if err is not nil.
+	}()
+
+	out, err := io.ReadAll(response.Body)
+	if err != nil {
+		return x.wrapErr(err)
+	}
+	ctx.ResponseBody = out
+
+	var res webapi.ApiResponse[TData]
+	err = json.Unmarshal(out, &res)
+	if err != nil {
+		return x.wrapErr(err)
+	}
+
+	ctx.ApiResponse = res
+	ctx.Code = res.Code
+	ctx.Message = res.Message
+	return nil
+}
+
+// roundTrip 执行 RequestSetup 并发送请求。若指定了 Balancer ，则经由 Balancer 选择节点发送。
+func (x SlimApiInvoker[TParam, TData]) roundTrip(ctx *InvokeContext) (*http.Response, error) {
+	client := x.Client
+	if client == nil {
+		client = new(http.Client)
+	}
+
+	if x.Balancer != nil {
+		response, req, endpoint, err := x.Balancer.roundTrip(client, ctx.Request, x.Uri, x.RequestSetup)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx.Request = req
+		ctx.Endpoint = endpoint
+		return response, nil
+	}
+
+	if x.RequestSetup != nil {
+		err := x.RequestSetup(ctx.Request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return client.Do(ctx.Request)
+}
+
+func (x SlimApiInvoker[TParam, TData]) context() context.Context {
+	if x.ctx == nil {
+		return context.Background()
+	}
+	return x.ctx
+}
+
+// sleep 等待给定的时长。 context 在此期间被取消或超时时，提前返回包装过的错误。
+func (x SlimApiInvoker[TParam, TData]) sleep(d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-x.context().Done():
+		return x.wrapErr(x.context().Err())
+	}
 }
 
 func (x SlimApiInvoker[TParam, TData]) wrapErr(cause error) error {
@@ -256,71 +501,96 @@ func (x SlimApiInvoker[TParam, TData]) getContentType(ct string) string {
 	return ct
 }
 
-func (x SlimApiInvoker[TParam, TData]) yieldFromSSE(r io.Reader, yield func(webapi.ApiResponse[TData], error) bool) {
-	var eventName string
+// yieldFromSSE 读取 SSE 流，并在 stream 上记录 id 和 retry 。返回值同 readStream 。
+func (x SlimApiInvoker[TParam, TData]) yieldFromSSE(r io.Reader, stream *sseStreamState, yield func(webapi.SseEvent[webapi.ApiResponse[TData]], error) bool) (done bool, err error) {
+	var fields webapi.SseFields
 	var dataLines []string
 	flush := func() bool {
 		if len(dataLines) == 0 {
-			eventName = ""
+			fields = webapi.SseFields{}
 			return true
 		}
 
-		ev := eventName // Clone before reset.
+		// 事件的 id 是到此为止最后收到的 id ，不一定在当前事件中给出。
+		ev := fields // Clone before reset.
+		ev.Id = stream.lastId
 		raw := strings.Join(dataLines, "\n")
 
 		// Reset buffer data.
 		dataLines = dataLines[:0]
-		eventName = ""
+		fields = webapi.SseFields{}
 
-		return x.dispatchStreamEnvelope(ev, raw, yield)
+		return x.dispatchStreamEnvelope(ev, raw, stream, yield)
 	}
 
 	br := bufio.NewReader(r)
 	for {
 		line, err := br.ReadString('\n')
 		if err != nil && err != io.EOF {
-			_ = yield(webapi.ApiResponse[TData]{}, err)
-			return
+			return false, err
 		}
 
 		// 移除换行符，需适配 Windows 风格的 \r\n ，从右边开始，先移除 \n ，再移除 \r 。
 		line = strings.TrimRight(strings.TrimRight(line, "\n"), "\r")
 		if line == "" {
-			if !flush() || err == io.EOF {
-				return
+			if !flush() {
+				return true, nil
+			}
+			if err == io.EOF {
+				return false, nil
 			}
 			continue
 		}
 
-		if strings.HasPrefix(line, "event:") {
-			eventName = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
-		} else if strings.HasPrefix(line, "data:") {
-			dataLines = append(dataLines, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		// 以冒号开头的是注释。
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		switch name {
+		case "event":
+			fields.Event = value
+		case "data":
+			dataLines = append(dataLines, value)
+		case "id":
+			// 按 SSE 规范，含有 NUL 的 id 被忽略。
+			if !strings.ContainsRune(value, 0) {
+				stream.lastId = value
+			}
+		case "retry":
+			if ms, e := strconv.ParseInt(value, 10, 64); e == nil && ms >= 0 {
+				fields.Retry = time.Duration(ms) * time.Millisecond
+				stream.retry = fields.Retry
+			}
 		}
 
+		// 流没有以空行结束时，仍处理最后一个事件。
 		if err == io.EOF {
-			if len(dataLines) > 0 || eventName != "" {
-				if !flush() {
-					return
-				}
+			if !flush() {
+				return true, nil
 			}
-			return
+			return false, nil
 		}
 	}
 }
 
-func (x SlimApiInvoker[TParam, TData]) dispatchStreamEnvelope(eventName string, rawJSON string, yield func(webapi.ApiResponse[TData], error) bool) bool {
+// 处理一个事件。返回 false 表示迭代应结束：收到了 END 事件、格式错误或 yield 返回了 false 。
+func (x SlimApiInvoker[TParam, TData]) dispatchStreamEnvelope(fields webapi.SseFields, rawJSON string, stream *sseStreamState, yield func(webapi.SseEvent[webapi.ApiResponse[TData]], error) bool) bool {
 	var res webapi.ApiResponse[TData]
 	if err := json.Unmarshal([]byte(rawJSON), &res); err != nil {
-		_ = yield(webapi.ApiResponse[TData]{}, err)
+		_ = yield(webapi.SseEvent[webapi.ApiResponse[TData]]{}, err)
 		return false
 	}
 
-	if eventName == "END" || res.Code == webapi.EventStreamEndCode {
+	if fields.Event == "END" || res.Code == webapi.EventStreamEndCode {
 		return false
 	}
 
-	return yield(res, nil)
+	stream.received++
+	return yield(webapi.SseEvent[webapi.ApiResponse[TData]]{
+		Id:    fields.Id,
+		Event: fields.Event,
+		Retry: fields.Retry,
+		Data:  res,
+	}, nil)
 }
 
 func (x SlimApiInvoker[TParam, TData]) yieldFromNdJSON(r io.Reader, yield func(webapi.ApiResponse[TData], error) bool) {
@@ -356,3 +626,106 @@ func (x SlimApiInvoker[TParam, TData]) yieldFromNdJSON(r io.Reader, yield func(w
 		}
 	}
 }
+
+// 逐项读取 [webapi.JsonArray] 的输出，数组中的每个元素作为一个 Code 为 0 的 [webapi.ApiResponse] 给出；
+// 若文档的 Code 不为 0 ，则在最后给出一个带有 Code 和 Message 的 ApiResponse 。
+func (x SlimApiInvoker[TParam, TData]) yieldFromJsonArray(r io.Reader, yield func(webapi.ApiResponse[TData], error) bool) {
+	dec := json.NewDecoder(r)
+	fail := func(err error) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		yield(webapi.ApiResponse[TData]{}, err)
+	}
+
+	if err := expectJsonDelim(dec, '{'); err != nil {
+		fail(err)
+		return
+	}
+
+	var res webapi.ApiResponse[TData]
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		// 同 encoding/json ，字段名称不区分大小写。
+		switch key := tok.(string); {
+		case strings.EqualFold(key, "Data"):
+			tok, err := dec.Token()
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			if tok == nil {
+				continue
+			}
+
+			if tok != json.Delim('[') {
+				fail(fmt.Errorf("Data must be an array, got %v", tok))
+				return
+			}
+
+			for dec.More() {
+				var item TData
+				if err := dec.Decode(&item); err != nil {
+					fail(err)
+					return
+				}
+
+				if !yield(webapi.ApiResponse[TData]{Data: item}, nil) {
+					return
+				}
+			}
+
+			if err := expectJsonDelim(dec, ']'); err != nil {
+				fail(err)
+				return
+			}
+
+		case strings.EqualFold(key, "Code"):
+			err = dec.Decode(&res.Code)
+		case strings.EqualFold(key, "Message"):
+			err = dec.Decode(&res.Message)
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+
+		if err != nil {
+			fail(err)
+			return
+		}
+	}
+
+	if err := expectJsonDelim(dec, '}'); err != nil {
+		fail(err)
+		return
+	}
+
+	if res.Code != 0 {
+		yield(res, nil)
+	}
+}
+
+// 读取下一个 token ，要求其为 delim 。
+func expectJsonDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok != delim {
+		return fmt.Errorf("expect '%v', got %v", delim, tok)
+	}
+	return nil
+}
+
+// 判断 Content-Type 是否为 [webapi.ContentTypeJsonArrayStream] 。
+func isJsonArrayStream(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == webapi.ContentTypeJson && params["stream"] == "array"
+}
@@ -0,0 +1,76 @@
+package slimapi
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cmstar/go-errx"
+)
+
+// SseEventMessage 是没有名称的事件的名称，同 SSE 规范。NDJSON 流及非流式响应中的各段数据，也视为此名称的事件。
+const SseEventMessage = "message"
+
+// SseEventHandlers 以事件的名称为 key ，记录各事件的处理函数，函数的参数是事件所在信封的 Data 字段的原文。
+// 用于通过 [SlimApiInvoker.DoEvents] 读取各段数据类型不同的流，例如服务端方法返回 webapi.EventStream[webapi.SseEvent[any]] ，
+// 以 Event 字段区分 progress 、 partial 、 result 等事件。
+//
+// 通常使用 [OnSseEvent] 注册处理函数，以获得已解析为具体类型的数据：
+//
+//	handlers := slimapi.SseEventHandlers{}
+//	slimapi.OnSseEvent(handlers, "progress", func(p Progress) error { ... })
+//	slimapi.OnSseEvent(handlers, "result", func(r Result) error { ... })
+//	err := invoker.DoEvents(req, handlers)
+//
+// 同浏览器的 EventSource ，没有注册处理函数的事件被忽略。
+type SseEventHandlers map[string]func(data json.RawMessage) error
+
+// OnSseEvent 在 handlers 上注册名称为 event 的事件的处理函数，事件的 Data 被 JSON 反序列化为 T 后交给 fn 。
+// 没有名称的事件，使用 [SseEventMessage] 注册。返回 handlers 本身。
+func OnSseEvent[T any](handlers SseEventHandlers, event string, fn func(data T) error) SseEventHandlers {
+	handlers[event] = func(data json.RawMessage) error {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf(`event "%s": %w`, event, err)
+		}
+		return fn(v)
+	}
+	return handlers
+}
+
+// DoEvents 执行请求，按事件的名称，将流中的每段数据依次交给 handlers 中对应的处理函数，直到流结束。
+// 各段数据的类型由处理函数决定，故 TData 不被使用。
+//
+// 出现以下情况时，停止读取并返回错误：
+//   - 请求或读取出错，同 [SlimApiInvoker.DoRawEventStream] ，SSE 流意外中断时按 SseRetries 自动重连。
+//   - 某段的 [webapi.ApiResponse.Code] 不为 0 ，返回 [errx.BizError] 。
+//   - 处理函数返回错误（包括 [OnSseEvent] 中 Data 不能被解析），原样返回该错误。
+func (x SlimApiInvoker[TParam, TData]) DoEvents(params TParam, handlers SseEventHandlers) error {
+	// 字段与 TData 无关，可直接转换，复制全部配置。
+	raw := SlimApiInvoker[TParam, json.RawMessage](x)
+
+	count := 1
+	for ev, err := range raw.DoRawEventStream(params) {
+		if err != nil {
+			return err
+		}
+
+		res := ev.Data
+		if res.Code != 0 {
+			cause := fmt.Errorf(`request "%s", seq %d: (%d) %s`, x.Uri, count, res.Code, res.Message)
+			return errx.NewBizError(res.Code, res.Message, cause)
+		}
+
+		name := ev.Event
+		if name == "" {
+			name = SseEventMessage
+		}
+
+		if handler, ok := handlers[name]; ok {
+			if err := handler(res.Data); err != nil {
+				return err
+			}
+		}
+		count++
+	}
+	return nil
+}
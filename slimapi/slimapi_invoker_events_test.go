@@ -0,0 +1,99 @@
+package slimapi
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cmstar/go-errx"
+	"github.com/cmstar/go-webapi"
+	"github.com/stretchr/testify/require"
+)
+
+type invokerEventsTestProvider struct{}
+
+type invokerEventsTestResult struct {
+	Total int
+	Items []string
+}
+
+// 输出各段类型不同的事件； Fail 不为 0 时，最后输出一个错误。
+func (invokerEventsTestProvider) Run(req struct{ Fail int }) webapi.EventStream[webapi.SseEvent[any]] {
+	return func(yield func(webapi.SseEvent[any], error) bool) {
+		events := []webapi.SseEvent[any]{
+			{Event: "progress", Data: 50},
+			{Event: "partial", Data: "a"},
+			{Data: "hello"},
+			{Event: "unknown", Data: true},
+			{Event: "progress", Data: 100},
+			{Event: "result", Data: invokerEventsTestResult{Total: 1, Items: []string{"a"}}},
+		}
+		for _, ev := range events {
+			if !yield(ev, nil) {
+				return
+			}
+		}
+
+		if req.Fail != 0 {
+			yield(webapi.SseEvent[any]{}, errx.NewBizError(req.Fail, "failed", nil))
+		}
+	}
+}
+
+func TestSlimApiInvoker_DoEvents(t *testing.T) {
+	e := webapi.NewEngine()
+	e.Handle("/{~method}", NewSlimApiHandler(""), nil).RegisterMethods(invokerEventsTestProvider{})
+	s := httptest.NewServer(e)
+	defer s.Close()
+
+	type request = struct{ Fail int }
+	invoker := NewSlimApiInvoker[request, any](s.URL + "/Run")
+
+	t.Run("dispatch", func(t *testing.T) {
+		var got []any
+		handlers := SseEventHandlers{}
+		OnSseEvent(handlers, "progress", func(p int) error {
+			got = append(got, p)
+			return nil
+		})
+		OnSseEvent(handlers, "partial", func(v string) error {
+			got = append(got, "partial:"+v)
+			return nil
+		})
+		OnSseEvent(handlers, SseEventMessage, func(v string) error {
+			got = append(got, "message:"+v)
+			return nil
+		})
+		OnSseEvent(handlers, "result", func(r invokerEventsTestResult) error {
+			got = append(got, r)
+			return nil
+		})
+
+		require.NoError(t, invoker.DoEvents(request{}, handlers))
+		require.Equal(t, []any{50, "partial:a", "message:hello", 100, invokerEventsTestResult{Total: 1, Items: []string{"a"}}}, got)
+	})
+
+	t.Run("biz-error", func(t *testing.T) {
+		err := invoker.DoEvents(request{Fail: 9}, SseEventHandlers{})
+
+		var bizErr errx.BizError
+		require.True(t, errors.As(err, &bizErr))
+		require.Equal(t, 9, bizErr.Code())
+		require.Equal(t, "failed", bizErr.Message())
+	})
+
+	t.Run("handler-error", func(t *testing.T) {
+		n := 0
+		handlers := OnSseEvent(SseEventHandlers{}, "progress", func(p int) error {
+			n++
+			return errors.New("stop")
+		})
+		require.EqualError(t, invoker.DoEvents(request{}, handlers), "stop")
+		require.Equal(t, 1, n)
+	})
+
+	t.Run("bad-data", func(t *testing.T) {
+		handlers := OnSseEvent(SseEventHandlers{}, "partial", func(v int) error { return nil })
+		require.ErrorContains(t, invoker.DoEvents(request{}, handlers), `event "partial": json: cannot unmarshal`)
+	})
+}
@@ -0,0 +1,81 @@
+package slimapi
+
+import (
+	"encoding/json"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmstar/go-webapi"
+)
+
+// MinLongPollInterval 是 [SlimApiInvoker.DoLongPoll] 在没有新数据时，两次请求的开始时间的最小间隔。
+// 服务端过早地返回（如等待时长被配置得很短，或代理提前结束了请求）时，以此避免密集地请求。
+const MinLongPollInterval = time.Second
+
+// DoLongPoll 反复请求服务端的长轮询方法（返回 webapi.LongPollResult[TData] ，见 [webapi.WaitLongPoll] ），返回新数据的迭代器。
+// 请求在开始迭代时发送。每次请求在 ~cursor 元参数中给出上一次收到的游标，第一次请求使用 cursor ；
+// 服务端等待超时、没有新数据时，使用原游标再次请求，与上一次请求的开始时间至少间隔 [MinLongPollInterval] 。
+//
+// 迭代器的每项是 Changed 为 true 的结果，其 Cursor 可在迭代中断后作为 cursor 再次调用，从断点继续。
+// 等待时长见 LongPollWait 。
+//
+// 出现以下情况时，给出错误，迭代结束：
+//   - 请求出错，或响应是流式的，同 [SlimApiInvoker.Do] 。
+//   - [webapi.ApiResponse.Code] 不为 0 ，给出 [errx.BizError] 。
+func (x SlimApiInvoker[TParam, TData]) DoLongPoll(params TParam, cursor string) iter.Seq2[webapi.LongPollResult[TData], error] {
+	return func(yield func(webapi.LongPollResult[TData], error) bool) {
+		// 不能以 LongPollResult[TData] 实例化 SlimApiInvoker （泛型实例化会形成循环），故先读取 Data 的原文。
+		// 字段与 TData 无关，可直接转换，复制全部配置。
+		poll := SlimApiInvoker[TParam, json.RawMessage](x)
+
+		for {
+			poll.Uri = x.longPollUri(cursor)
+			start := time.Now()
+			raw, err := poll.Do(params)
+
+			var res webapi.LongPollResult[TData]
+			if err == nil {
+				err = json.Unmarshal(raw, &res)
+				if err != nil {
+					err = poll.wrapErr(err)
+				}
+			}
+
+			if err != nil {
+				yield(res, err)
+				return
+			}
+
+			if !res.Changed {
+				if err := poll.sleep(MinLongPollInterval - time.Since(start)); err != nil {
+					yield(res, err)
+					return
+				}
+				continue
+			}
+
+			if !yield(res, nil) {
+				return
+			}
+			cursor = res.Cursor
+		}
+	}
+}
+
+// 在 Uri 上追加长轮询的元参数。
+func (x SlimApiInvoker[TParam, TData]) longPollUri(cursor string) string {
+	query := url.Values{meta_Param_Cursor: {cursor}}
+	if x.LongPollWait > 0 {
+		seconds := (x.LongPollWait + time.Second - 1) / time.Second
+		query.Set(meta_Param_Wait, strconv.FormatInt(int64(seconds), 10))
+	}
+
+	sep := "?"
+	if strings.Contains(x.Uri, "?") {
+		sep = "&"
+	}
+	return x.Uri + sep + query.Encode()
+}
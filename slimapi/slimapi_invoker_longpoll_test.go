@@ -0,0 +1,158 @@
+package slimapi
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cmstar/go-errx"
+	"github.com/cmstar/go-webapi"
+	"github.com/stretchr/testify/require"
+)
+
+// 以数据的个数作为游标的存储。
+type invokerLongPollTestStore struct {
+	mu      sync.Mutex
+	items   []string
+	changed chan struct{} // 有新数据时关闭并替换。
+}
+
+func newInvokerLongPollTestStore() *invokerLongPollTestStore {
+	return &invokerLongPollTestStore{changed: make(chan struct{})}
+}
+
+func (s *invokerLongPollTestStore) Add(item string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, item)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// 阻塞直到有 cursor 之后的数据，或 ctx 结束。
+func (s *invokerLongPollTestStore) WaitAfter(ctx context.Context, cursor string) ([]string, string, error) {
+	n, _ := strconv.Atoi(cursor)
+	for {
+		s.mu.Lock()
+		items, changed := s.items, s.changed
+		s.mu.Unlock()
+
+		if len(items) > n {
+			return items[n:], strconv.Itoa(len(items)), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+type invokerLongPollTestProvider struct {
+	store *invokerLongPollTestStore
+	polls *atomic.Int32
+}
+
+func (p invokerLongPollTestProvider) Poll(ctx context.Context, lp webapi.LongPoll, req struct{ Fail int }) (webapi.LongPollResult[[]string], error) {
+	p.polls.Add(1)
+	if req.Fail != 0 {
+		return webapi.LongPollResult[[]string]{}, errx.NewBizError(req.Fail, "failed", nil)
+	}
+
+	return webapi.WaitLongPoll(ctx, lp, func(ctx context.Context) ([]string, string, error) {
+		return p.store.WaitAfter(ctx, lp.Cursor)
+	})
+}
+
+func TestSlimApiInvoker_DoLongPoll(t *testing.T) {
+	p := invokerLongPollTestProvider{newInvokerLongPollTestStore(), new(atomic.Int32)}
+	e := webapi.NewEngine()
+	e.Handle("/{~method}", NewSlimApiHandler(""), nil).
+		RegisterMethods(p).
+		SetLongPollMaxWait("Poll", 20*time.Millisecond)
+	s := httptest.NewServer(e)
+	defer s.Close()
+
+	type request = struct{ Fail int }
+	invoker := NewSlimApiInvoker[request, []string](s.URL + "/Poll")
+	invoker.LongPollWait = time.Minute // 被服务端限制为 20ms 。
+
+	t.Run("poll", func(t *testing.T) {
+		p.store.Add("a")
+		p.store.Add("b")
+
+		var got []webapi.LongPollResult[[]string]
+		var start time.Time
+		for res, err := range invoker.DoLongPoll(request{}, "") {
+			require.NoError(t, err)
+			got = append(got, res)
+			if len(got) == 1 {
+				p.polls.Store(0)
+				start = time.Now()
+				time.AfterFunc(100*time.Millisecond, func() { p.store.Add("c") })
+				continue
+			}
+			break
+		}
+
+		require.Equal(t, []webapi.LongPollResult[[]string]{
+			{Changed: true, Cursor: "2", Data: []string{"a", "b"}},
+			{Changed: true, Cursor: "3", Data: []string{"c"}},
+		}, got)
+
+		// 等待超时后再次请求，但服务端很快返回时，不会密集地请求。
+		require.Equal(t, int32(2), p.polls.Load())
+		require.GreaterOrEqual(t, time.Since(start), MinLongPollInterval)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		for res, err := range invoker.DoLongPoll(request{}, "1") {
+			require.NoError(t, err)
+			require.Equal(t, webapi.LongPollResult[[]string]{Changed: true, Cursor: "3", Data: []string{"b", "c"}}, res)
+			break
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// 没有新数据，在请求或两次请求之间的等待中超时。
+		start := time.Now()
+		n := 0
+		for _, err := range invoker.WithContext(ctx).DoLongPoll(request{}, "3") {
+			n++
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+		require.Equal(t, 1, n)
+		require.Less(t, time.Since(start), MinLongPollInterval)
+	})
+
+	t.Run("biz-error", func(t *testing.T) {
+		n := 0
+		for _, err := range invoker.DoLongPoll(request{Fail: 9}, "") {
+			n++
+			var bizErr errx.BizError
+			require.True(t, errors.As(err, &bizErr))
+			require.Equal(t, 9, bizErr.Code())
+		}
+		require.Equal(t, 1, n)
+	})
+}
+
+func TestSlimApiInvoker_longPollUri(t *testing.T) {
+	invoker := NewSlimApiInvoker[int, int]("http://temp.org/Poll")
+	require.Equal(t, "http://temp.org/Poll?~cursor=", invoker.longPollUri(""))
+
+	invoker.LongPollWait = 1500 * time.Millisecond
+	require.Equal(t, "http://temp.org/Poll?~cursor=5&~wait=2", invoker.longPollUri("5"))
+
+	invoker.Uri = "http://temp.org/?Poll"
+	require.Equal(t, "http://temp.org/?Poll&~cursor=5&~wait=2", invoker.longPollUri("5"))
+}
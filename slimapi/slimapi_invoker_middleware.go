@@ -0,0 +1,130 @@
+package slimapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cmstar/go-logx"
+)
+
+// InvokeContext 记录 [SlimApiInvoker] 一次调用过程中的数据，在 [InvokeMiddleware] 之间传递。
+type InvokeContext struct {
+	// Uri 是本次调用的目标 URL 。
+	Uri string
+
+	// Request 是即将发送的 HTTP 请求。中间件可在调用下一环节之前对其进行修改，如添加 HTTP 头。
+	// 若使用了 [LoadBalancer] ，调用前其 URL 是相对路径；下一环节返回后，会被替换为最终发送到节点的请求。
+	Request *http.Request
+
+	// Endpoint 在使用了 [LoadBalancer] 时，记录最终处理请求的节点的基础 URL 。
+	Endpoint string
+
+	// Response 是原始的 HTTP 回执，在下一环节返回后可用；若请求未能完成，则为 nil 。
+	// 对于非流式响应，其 body 已被读取并关闭，内容记录在 ResponseBody 上。
+	Response *http.Response
+
+	// ResponseBody 记录非流式响应的 HTTP body 原文。流式响应时为 nil 。
+	ResponseBody []byte
+
+	// ApiResponse 记录非流式响应解析得到的 [webapi.ApiResponse] ，其类型为 webapi.ApiResponse[TData] （非指针）。
+	// 流式响应时为 nil 。中间件若不调用下一环节而直接返回 nil ，需自行设置此字段，否则调用方得到错误。
+	ApiResponse any
+
+	// Code 与 Message 对应 ApiResponse 的同名字段，便于不关心 TData 的中间件读取。
+	Code    int
+	Message string
+
+	// Streaming 表示是否获得了 SSE/NDJSON 或 [webapi.JsonArray] 流式响应。
+	// 流式响应的 body 由调用方在迭代时读取，中间件不应读取或关闭它。
+	Streaming bool
+}
+
+// InvokeHandler 执行一次调用，并将结果填入给定的 [InvokeContext] 。
+type InvokeHandler func(ctx *InvokeContext) error
+
+// InvokeMiddleware 用于包装 [InvokeHandler] ，以在请求的前后追加处理过程。
+//
+// 在调用 next 之前，可读取和修改 [InvokeContext.Request] ；在 next 返回之后，
+// 可读取 [InvokeContext.Response] 和 [InvokeContext.ApiResponse] 等结果。
+type InvokeMiddleware func(next InvokeHandler) InvokeHandler
+
+// InvokeChain 是一组 [InvokeMiddleware] 。第一个元素位于最外层，最先执行。
+type InvokeChain []InvokeMiddleware
+
+// Then 使用当前链条包装给定的 h ，返回包装后的 [InvokeHandler] 。
+func (c InvokeChain) Then(h InvokeHandler) InvokeHandler {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i](h)
+	}
+	return h
+}
+
+// InvokeHeaders 返回一个 [InvokeMiddleware] ，它在请求发送前，将给定的 HTTP 头设置到请求上。
+// 同名的头会被覆盖。
+func InvokeHeaders(headers map[string]string) InvokeMiddleware {
+	return func(next InvokeHandler) InvokeHandler {
+		return func(ctx *InvokeContext) error {
+			for k, v := range headers {
+				ctx.Request.Header.Set(k, v)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// InvokeTiming 返回一个 [InvokeMiddleware] ，它在每次调用结束后，将调用的耗时传给 report 。
+// 对于流式响应，耗时截止到获得 HTTP 回执，不包含读取流的时间。
+func InvokeTiming(report func(ctx *InvokeContext, elapsed time.Duration)) InvokeMiddleware {
+	return func(next InvokeHandler) InvokeHandler {
+		return func(ctx *InvokeContext) error {
+			start := time.Now()
+			err := next(ctx)
+			report(ctx, time.Since(start))
+			return err
+		}
+	}
+}
+
+// InvokeLogging 返回一个 [InvokeMiddleware] ，它在每次调用结束后，将调用的概要信息输出到 logger 。
+//
+// 日志级别：
+//   - 调用出错（如网络错误、 HTTP 状态码不是 200 ）时，为 [logx.LevelError] 。
+//   - [webapi.ApiResponse.Code] 不为 0 时，为 [logx.LevelWarn] 。
+//   - 其余情况为 [logx.LevelInfo] 。
+//
+// 日志的消息为 invoke ，调用出错时为 invoke failed 。
+// 输出字段为： Uri/StatusCode/Code/Message/Duration/Error ，其中 StatusCode 、 Code/Message 、 Error 仅在有值时输出。
+// Duration 的单位是毫秒。
+func InvokeLogging(logger logx.Logger) InvokeMiddleware {
+	return func(next InvokeHandler) InvokeHandler {
+		return func(ctx *InvokeContext) error {
+			start := time.Now()
+			err := next(ctx)
+			elapsed := time.Since(start)
+
+			level := logx.LevelInfo
+			message := "invoke"
+			kv := []any{"Uri", ctx.Uri}
+
+			if ctx.Response != nil {
+				kv = append(kv, "StatusCode", ctx.Response.StatusCode)
+			}
+
+			if ctx.Code != 0 {
+				level = logx.LevelWarn
+				kv = append(kv, "Code", ctx.Code, "Message", ctx.Message)
+			}
+
+			kv = append(kv, "Duration", elapsed.Milliseconds())
+
+			if err != nil {
+				level = logx.LevelError
+				message = "invoke failed"
+				kv = append(kv, "Error", err.Error())
+			}
+
+			logger.Log(level, message, kv...)
+			return err
+		}
+	}
+}
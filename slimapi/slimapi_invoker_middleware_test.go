@@ -0,0 +1,199 @@
+package slimapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/cmstar/go-webapi/webapitest"
+	"github.com/stretchr/testify/require"
+)
+
+func newMiddlewareTestServer() *httptest.Server {
+	h := NewSlimApiHandler("")
+	h.RegisterMethods(integrationTestMethodProvider{})
+	h.RegisterMethod(webapi.ApiMethod{
+		Name: "Header",
+		Value: reflect.ValueOf(func(state *webapi.ApiState) string {
+			return state.RawRequest.Header.Get("X-Test")
+		}),
+	})
+
+	e := webapi.NewEngine()
+	e.Handle("/{~method}", h, nil)
+	return httptest.NewServer(e)
+}
+
+func TestInvokeChain_Then(t *testing.T) {
+	var trace []string
+	m := func(name string) InvokeMiddleware {
+		return func(next InvokeHandler) InvokeHandler {
+			return func(ctx *InvokeContext) error {
+				trace = append(trace, name+">")
+				err := next(ctx)
+				trace = append(trace, "<"+name)
+				return err
+			}
+		}
+	}
+
+	h := InvokeChain{m("a"), m("b")}.Then(func(ctx *InvokeContext) error {
+		trace = append(trace, "h")
+		return nil
+	})
+	require.NoError(t, h(&InvokeContext{}))
+	require.Equal(t, []string{"a>", "b>", "h", "<b", "<a"}, trace)
+}
+
+func TestSlimApiInvoker_Middlewares(t *testing.T) {
+	s := newMiddlewareTestServer()
+	defer s.Close()
+
+	t.Run("headers", func(t *testing.T) {
+		invoker := NewSlimApiInvoker[struct{}, string](s.URL + "/Header")
+		invoker.Middlewares = InvokeChain{InvokeHeaders(map[string]string{"X-Test": "v"})}
+		require.Equal(t, "v", invoker.MustDo(struct{}{}))
+	})
+
+	t.Run("request-setup-after-middlewares", func(t *testing.T) {
+		invoker := NewSlimApiInvoker[struct{}, string](s.URL + "/Header")
+		invoker.Middlewares = InvokeChain{InvokeHeaders(map[string]string{"X-Test": "m"})}
+		invoker.RequestSetup = func(r *http.Request) error {
+			r.Header.Set("X-Test", r.Header.Get("X-Test")+"s")
+			return nil
+		}
+		require.Equal(t, "ms", invoker.MustDo(struct{}{}))
+	})
+
+	t.Run("see-response", func(t *testing.T) {
+		var got *InvokeContext
+		invoker := NewSlimApiInvoker[ShowErrorRequest, string](s.URL + "/ShowError")
+		invoker.Middlewares = InvokeChain{
+			func(next InvokeHandler) InvokeHandler {
+				return func(ctx *InvokeContext) error {
+					err := next(ctx)
+					got = ctx
+					return err
+				}
+			},
+		}
+
+		_, err := invoker.Do(ShowErrorRequest{Type: ShowError_BizError999, E: "msg", S: "s"})
+		require.Error(t, err)
+		require.NotNil(t, got)
+		require.Equal(t, 200, got.Response.StatusCode)
+		require.False(t, got.Streaming)
+		require.Equal(t, 999, got.Code)
+		require.Equal(t, "msg", got.Message)
+		require.Equal(t, `{"Code":999,"Message":"msg","Data":"s"}`, string(got.ResponseBody))
+		require.Equal(t, webapi.ApiResponse[string]{Code: 999, Message: "msg", Data: "s"}, got.ApiResponse)
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		var got *InvokeContext
+		invoker := NewSlimApiInvoker[struct{}, string](s.URL + "/NdJsonWithError")
+		invoker.Middlewares = InvokeChain{
+			func(next InvokeHandler) InvokeHandler {
+				return func(ctx *InvokeContext) error {
+					err := next(ctx)
+					got = ctx
+					return err
+				}
+			},
+		}
+
+		n := 0
+		for range invoker.DoRawStream(struct{}{}) {
+			n++
+		}
+		require.Equal(t, 3, n)
+		require.True(t, got.Streaming)
+		require.Nil(t, got.ApiResponse)
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		invoker := NewSlimApiInvoker[struct{}, string](s.URL + "/Header")
+		invoker.Middlewares = InvokeChain{
+			func(next InvokeHandler) InvokeHandler {
+				return func(ctx *InvokeContext) error {
+					return errors.New("aborted")
+				}
+			},
+		}
+		_, err := invoker.Do(struct{}{})
+		require.EqualError(t, err, "aborted")
+	})
+
+	t.Run("short-circuit", func(t *testing.T) {
+		for _, v := range []any{nil, "other"} {
+			invoker := NewSlimApiInvoker[struct{}, string](s.URL + "/Header")
+			invoker.Middlewares = InvokeChain{
+				func(next InvokeHandler) InvokeHandler {
+					return func(ctx *InvokeContext) error {
+						ctx.ApiResponse = v
+						return nil
+					}
+				},
+			}
+			msg := fmt.Sprintf(`request "%s/Header": InvokeContext.ApiResponse should be webapi.ApiResponse[string], got %T`, s.URL, v)
+
+			_, err := invoker.Do(struct{}{})
+			require.EqualError(t, err, msg)
+
+			n := 0
+			for _, err := range invoker.DoRawStream(struct{}{}) {
+				n++
+				require.EqualError(t, err, msg)
+			}
+			require.Equal(t, 1, n)
+		}
+	})
+
+	t.Run("timing", func(t *testing.T) {
+		var elapsed time.Duration
+		invoker := NewSlimApiInvoker[struct{}, string](s.URL + "/Header")
+		invoker.Middlewares = InvokeChain{
+			InvokeTiming(func(ctx *InvokeContext, d time.Duration) {
+				elapsed = d
+			}),
+		}
+		invoker.MustDo(struct{}{})
+		require.Greater(t, elapsed, time.Duration(0))
+	})
+
+	t.Run("logging", func(t *testing.T) {
+		logger := webapitest.NewLogRecorder()
+		invoker := NewSlimApiInvoker[ShowErrorRequest, string](s.URL + "/ShowError")
+		invoker.Middlewares = InvokeChain{InvokeLogging(logger)}
+
+		invoker.Do(ShowErrorRequest{})
+		invoker.Do(ShowErrorRequest{Type: ShowError_BizError999, E: "msg"})
+
+		bad := NewSlimApiInvoker[ShowErrorRequest, string]("bad-url")
+		bad.Middlewares = InvokeChain{InvokeLogging(logger)}
+		bad.Do(ShowErrorRequest{})
+
+		m := logger.Map()
+		require.Len(t, m, 3)
+
+		require.Equal(t, "INFO", m[0]["level"])
+		require.Equal(t, "invoke", m[0]["message"])
+		require.Equal(t, "200", m[0]["StatusCode"])
+		require.NotContains(t, m[0], "Code")
+
+		require.Equal(t, "WARN", m[1]["level"])
+		require.Equal(t, "invoke", m[1]["message"])
+		require.Equal(t, "999", m[1]["Code"])
+		require.Equal(t, "msg", m[1]["Message"])
+
+		require.Equal(t, "ERROR", m[2]["level"])
+		require.Equal(t, "invoke failed", m[2]["message"])
+		require.Equal(t, "bad-url", m[2]["Uri"])
+		require.Regexp(t, `request "bad-url"`, m[2]["Error"])
+	})
+}
@@ -1,8 +1,16 @@
 package slimapi
 
 import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/cmstar/go-errx"
 	"github.com/cmstar/go-webapi"
@@ -47,6 +55,22 @@ func TestSlimApiInvoker_Do(t *testing.T) {
 		require.Regexp(t, `request "bad-url":`, err.Error())
 	})
 
+	t.Run("context", func(t *testing.T) {
+		invoker := NewSlimApiInvoker[PlusRequest, int](s.URL + "/Plus")
+		require.Panics(t, func() { invoker.WithContext(nil) })
+
+		b := 2
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := invoker.WithContext(ctx).Do(PlusRequest{A: 1, B: &b})
+		require.ErrorIs(t, err, context.Canceled)
+
+		// 副本不影响原对象。
+		result, err := invoker.Do(PlusRequest{A: 1, B: &b})
+		require.NoError(t, err)
+		require.Equal(t, 3, result)
+	})
+
 	t.Run("error on streaming response", func(t *testing.T) {
 		invoker := NewSlimApiInvoker[struct{}, string](s.URL + "/ServerSendEventWithError")
 		_, err := invoker.Do(struct{}{})
@@ -132,6 +156,23 @@ func TestSlimApiInvoker_DoRawStream(t *testing.T) {
 		require.Equal(t, []string{"a", "b"}, got)
 	})
 
+	t.Run("json-array", func(t *testing.T) {
+		invoker := NewSlimApiInvoker[struct{}, string](s.URL + "/JsonArrayWithError")
+		var got []webapi.ApiResponse[string]
+		for v, err := range invoker.DoRawStream(struct{}{}) {
+			require.NoError(t, err)
+			got = append(got, v)
+		}
+		require.Equal(t, []webapi.ApiResponse[string]{
+			{Data: "a"},
+			{Data: "b"},
+			{Code: 500, Message: "internal error"},
+		}, got)
+
+		_, err := invoker.Do(struct{}{})
+		require.ErrorContains(t, err, "streaming response application/json, use DoRawStream/MustDoStream instead")
+	})
+
 	t.Run("non streaming endpoint", func(t *testing.T) {
 		invoker := NewSlimApiInvoker[PlusRequest, int](s.URL + "/Plus")
 		b := 2
@@ -146,3 +187,217 @@ func TestSlimApiInvoker_DoRawStream(t *testing.T) {
 		require.Equal(t, 1, n)
 	})
 }
+
+func TestSlimApiInvoker_DoRawEventStream(t *testing.T) {
+	// 按顺序返回 responses 中的 SSE body ，记录每次请求的 Last-Event-ID 。body 为空字符串时返回 HTTP 500 。
+	newServer := func(t *testing.T, responses ...string) (url string, lastIds func() []string) {
+		var mu sync.Mutex
+		var ids []string
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			n := len(ids)
+			ids = append(ids, r.Header.Get(webapi.HttpHeaderLastEventId))
+			mu.Unlock()
+
+			if n >= len(responses) || responses[n] == "" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set(webapi.HttpHeaderContentType, webapi.ContentTypeEventStream)
+			io.WriteString(w, responses[n])
+		}))
+		t.Cleanup(s.Close)
+
+		return s.URL, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return ids
+		}
+	}
+
+	type event = webapi.SseEvent[webapi.ApiResponse[int]]
+
+	t.Run("reconnect", func(t *testing.T) {
+		url, lastIds := newServer(t,
+			"retry: 10\nid: 1\nevent: e\ndata: {\"Code\":0,\"Data\":1}\n\n",
+			": comment\nid: 2\n\n",
+			"data: {\"Code\":0,\"Data\":2}\n\nevent: END\ndata: {\"Code\":1000}\n\n",
+		)
+
+		invoker := NewSlimApiInvoker[struct{}, int](url)
+		invoker.SseRetries = 2
+
+		var got []event
+		for ev, err := range invoker.DoRawEventStream(struct{}{}) {
+			require.NoError(t, err)
+			got = append(got, ev)
+		}
+
+		require.Equal(t, []string{"", "1", "2"}, lastIds())
+		require.Equal(t, []event{
+			{Id: "1", Event: "e", Retry: 10 * time.Millisecond, Data: webapi.ApiResponse[int]{Data: 1}},
+			{Id: "2", Data: webapi.ApiResponse[int]{Data: 2}},
+		}, got)
+	})
+
+	t.Run("give-up", func(t *testing.T) {
+		url, lastIds := newServer(t, "retry: 1\n\n", "\n", "\n", "\n")
+
+		invoker := NewSlimApiInvoker[struct{}, int](url)
+		invoker.SseRetries = 2
+		for range invoker.DoRawEventStream(struct{}{}) {
+			require.Fail(t, "should not yield")
+		}
+		require.Len(t, lastIds(), 3)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		url, lastIds := newServer(t, "retry: 1\nid: a\ndata: {\"Code\":0,\"Data\":1}\n\n", "")
+
+		invoker := NewSlimApiInvoker[struct{}, int](url)
+		invoker.SseRetries = 1
+
+		var got []int
+		var lastErr error
+		for ev, err := range invoker.DoRawStream(struct{}{}) {
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			got = append(got, ev.Data)
+		}
+
+		require.Equal(t, []int{1}, got)
+		require.ErrorContains(t, lastErr, "unexpected HTTP status 500")
+		require.Equal(t, []string{"", "a"}, lastIds())
+	})
+
+	t.Run("context", func(t *testing.T) {
+		url, lastIds := newServer(t, "retry: 60000\nid: 1\ndata: {\"Code\":0,\"Data\":1}\n\n")
+
+		invoker := NewSlimApiInvoker[struct{}, int](url)
+		invoker.SseRetries = 1
+
+		// 等待重连时， context 被取消，不再等待。
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		var lastErr error
+		for _, err := range invoker.WithContext(ctx).DoRawStream(struct{}{}) {
+			lastErr = err
+		}
+		require.ErrorIs(t, lastErr, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 5*time.Second)
+		require.Len(t, lastIds(), 1)
+	})
+
+	t.Run("no-retry", func(t *testing.T) {
+		url, lastIds := newServer(t, "id: 1\ndata: {\"Code\":0,\"Data\":1}\n\n")
+
+		invoker := NewSlimApiInvoker[struct{}, int](url)
+		n := 0
+		for _, err := range invoker.DoRawStream(struct{}{}) {
+			require.NoError(t, err)
+			n++
+		}
+		require.Equal(t, 1, n)
+		require.Len(t, lastIds(), 1)
+	})
+}
+
+type invokerSeqTestProvider struct{}
+
+func (invokerSeqTestProvider) Sum(items iter.Seq2[PlusRequest, error]) (int, error) {
+	sum := 0
+	for v, err := range items {
+		if err != nil {
+			return 0, err
+		}
+		sum += v.A
+	}
+	return sum, nil
+}
+
+func TestSlimApiInvoker_DoSeq(t *testing.T) {
+	e := webapi.NewEngine()
+	e.Handle("/{~method}", NewSlimApiHandler(""), nil).RegisterMethods(invokerSeqTestProvider{})
+	s := httptest.NewServer(e)
+	defer s.Close()
+
+	invoker := NewSlimApiInvoker[PlusRequest, int](s.URL + "/Sum")
+
+	t.Run("ok", func(t *testing.T) {
+		res, err := invoker.DoSeq(func(yield func(PlusRequest, error) bool) {
+			for i := 1; i <= 100; i++ {
+				if !yield(PlusRequest{A: i}, nil) {
+					return
+				}
+			}
+		})
+		require.NoError(t, err)
+		require.Equal(t, 5050, res)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		res, err := invoker.DoSeq(func(yield func(PlusRequest, error) bool) {})
+		require.NoError(t, err)
+		require.Equal(t, 0, res)
+	})
+
+	t.Run("source-error", func(t *testing.T) {
+		_, err := invoker.DoRawSeq(func(yield func(PlusRequest, error) bool) {
+			if yield(PlusRequest{A: 1}, nil) {
+				yield(PlusRequest{}, errors.New("source error"))
+			}
+		})
+		require.ErrorContains(t, err, "source error")
+	})
+
+	t.Run("bad-item", func(t *testing.T) {
+		raw := NewSlimApiInvoker[any, int](s.URL + "/Sum")
+		res, err := raw.DoRawSeq(func(yield func(any, error) bool) {
+			if yield(map[string]int{"A": 1}, nil) {
+				yield("x", nil)
+			}
+		})
+		require.NoError(t, err)
+		require.Equal(t, webapi.ErrorCodeBadRequest, res.Code)
+		require.Equal(t, "bad item 2", res.Message)
+	})
+}
+
+func TestSlimApiInvoker_yieldFromJsonArray(t *testing.T) {
+	read := func(body string) (res []webapi.ApiResponse[int], lastErr error) {
+		x := SlimApiInvoker[struct{}, int]{Uri: "x"}
+		x.yieldFromJsonArray(strings.NewReader(body), func(v webapi.ApiResponse[int], err error) bool {
+			if err != nil {
+				lastErr = err
+				return false
+			}
+			res = append(res, v)
+			return true
+		})
+		return
+	}
+
+	// 字段的顺序和大小写不影响结果，未知的字段被忽略。
+	res, err := read(`{"code":0,"X":{"a":[1]},"data":[1, 2]}`)
+	require.NoError(t, err)
+	require.Equal(t, []webapi.ApiResponse[int]{{Data: 1}, {Data: 2}}, res)
+
+	res, err = read(`{"Data":null,"Code":3,"Message":"m"}`)
+	require.NoError(t, err)
+	require.Equal(t, []webapi.ApiResponse[int]{{Code: 3, Message: "m"}}, res)
+
+	// 被中断的文档。
+	res, err = read(`{"Data":[1,2`)
+	require.Equal(t, []webapi.ApiResponse[int]{{Data: 1}, {Data: 2}}, res)
+	require.Error(t, err)
+
+	_, err = read(`{"Data":1}`)
+	require.ErrorContains(t, err, "Data must be an array")
+
+	_, err = read(`[]`)
+	require.Error(t, err)
+}
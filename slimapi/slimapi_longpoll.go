@@ -0,0 +1,60 @@
+package slimapi
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/cmstar/go-webapi"
+)
+
+// LongPollArgumentDecoder 是一个 [webapi.ArgumentDecoder] ，用于赋值 [webapi.LongPoll] ，其字段来自以下元参数，
+// 可以在 URL 的 query 上（如 ?~method=Poll&~cursor=5&~wait=20 ），也可以是路由参数（如 /api/{~method}/{~cursor} ）：
+//   - ~cursor 对应 [webapi.LongPoll.Cursor] 。
+//   - ~wait 是等待的秒数，为非负整数，超过 [webapi.LongPollMaxWait] 时使用后者；没有给出时，等待 [webapi.LongPollMaxWait] 。
+//
+// ~wait 的格式不正确时，返回 [webapi.BadRequestError] 。
+//
+// 这是一个单例。
+var LongPollArgumentDecoder = slimApiLongPollArgDecoder{}
+
+var typeLongPoll = reflect.TypeOf(webapi.LongPoll{})
+
+type slimApiLongPollArgDecoder struct{}
+
+var _ webapi.ArgumentDecoder = (*slimApiLongPollArgDecoder)(nil)
+
+// DecodeArg implements [webapi.ApiDecoder.DecodeArg].
+func (d slimApiLongPollArgDecoder) DecodeArg(state *webapi.ApiState, index int, argType reflect.Type) (ok bool, v any, err error) {
+	if argType != typeLongPoll {
+		return false, nil, nil
+	}
+
+	maxWait := webapi.LongPollMaxWait(state)
+	lp := webapi.LongPoll{
+		Cursor: d.metaParam(state, meta_Param_Cursor),
+		Wait:   maxWait,
+	}
+
+	if s := d.metaParam(state, meta_Param_Wait); s != "" {
+		seconds, err := strconv.Atoi(s)
+		if err != nil || seconds < 0 {
+			return false, nil, webapi.CreateBadRequestError(state, err, "bad %s", meta_Param_Wait)
+		}
+
+		// 比较秒数，避免换算为 time.Duration 时溢出。
+		if seconds <= int(maxWait/time.Second) {
+			lp.Wait = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return true, lp, nil
+}
+
+// 读取元参数，优先使用 query ，其次是路由参数。
+func (d slimApiLongPollArgDecoder) metaParam(state *webapi.ApiState, name string) string {
+	if v, ok := state.Query.Get(name); ok {
+		return v
+	}
+	return webapi.GetRouteParam(state.RawRequest, name)
+}
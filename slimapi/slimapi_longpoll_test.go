@@ -0,0 +1,82 @@
+package slimapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/cmstar/go-webapi/webapitest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLongPollArgumentDecoder(t *testing.T) {
+	decode := func(uri string, routeParams map[string]string, meta *webapi.ApiMeta) (bool, any, error) {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, uri, webapitest.NewStateSetup{RouteParams: routeParams})
+		state.Method.Meta = meta
+		return LongPollArgumentDecoder.DecodeArg(state, 0, typeLongPoll)
+	}
+
+	t.Run("absent", func(t *testing.T) {
+		ok, v, err := decode("http://temp.org/?Poll", nil, nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, webapi.LongPoll{Wait: webapi.DefaultLongPollMaxWait}, v)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		ok, v, err := decode("http://temp.org/?~method=Poll&~cursor=5&~wait=10", nil, nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, webapi.LongPoll{Cursor: "5", Wait: 10 * time.Second}, v)
+	})
+
+	t.Run("route", func(t *testing.T) {
+		ok, v, err := decode("http://temp.org/Poll/7", map[string]string{meta_Param_Cursor: "7"}, nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, webapi.LongPoll{Cursor: "7", Wait: webapi.DefaultLongPollMaxWait}, v)
+	})
+
+	t.Run("max-wait", func(t *testing.T) {
+		meta := webapi.NewApiMeta(map[string]any{webapi.ApiMetaLongPollMaxWait: 20 * time.Second})
+		_, v, err := decode("http://temp.org/?Poll&~wait=100", nil, meta)
+		require.NoError(t, err)
+		require.Equal(t, webapi.LongPoll{Wait: 20 * time.Second}, v)
+
+		_, v, err = decode("http://temp.org/?Poll&~wait=99999999999999", nil, meta)
+		require.NoError(t, err)
+		require.Equal(t, webapi.LongPoll{Wait: 20 * time.Second}, v)
+	})
+
+	t.Run("no-wait", func(t *testing.T) {
+		_, v, err := decode("http://temp.org/?Poll&~wait=0", nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, webapi.LongPoll{}, v)
+	})
+
+	t.Run("bad-wait", func(t *testing.T) {
+		for _, wait := range []string{"x", "-1", "1.5"} {
+			_, _, err := decode("http://temp.org/?Poll&~wait="+wait, nil, nil)
+			require.IsType(t, webapi.BadRequestError{}, err, wait)
+			require.EqualError(t, err, "bad ~wait", wait)
+		}
+	})
+
+	t.Run("other-type", func(t *testing.T) {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, "http://temp.org/?Poll&~wait=x", webapitest.NewStateSetup{})
+		ok, v, err := LongPollArgumentDecoder.DecodeArg(state, 0, reflect.TypeOf(struct{ Cursor string }{}))
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Nil(t, v)
+	})
+
+	t.Run("struct-decoder", func(t *testing.T) {
+		// LongPoll 不能从请求参数中读取。
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, "http://temp.org/?Poll&Cursor=1&Wait=100", webapitest.NewStateSetup{})
+		ok, v, err := StructArgumentDecoder.DecodeArg(state, 0, typeLongPoll)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Nil(t, v)
+	})
+}
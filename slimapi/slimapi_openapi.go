@@ -0,0 +1,675 @@
+package slimapi
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cmstar/go-webapi"
+)
+
+// OpenApiVersion 是 [NewOpenApiDocument] 生成的文档所遵循的 OpenAPI 规范版本。
+const OpenApiVersion = "3.1.0"
+
+// OpenApiOp 用于 [NewOpenApiDocument] ，提供文档的基本信息。
+type OpenApiOp struct {
+	Title       string   // 文档标题，对应 info.title 。为空时使用 "SlimAPI" 。
+	Version     string   // API 的版本，对应 info.version 。为空时使用 "1.0.0" 。
+	Description string   // 文档描述，对应 info.description 。
+	Servers     []string // 服务器的基础 URL ，对应 servers 。
+
+	// PathPrefix 是方法路径的前缀。每个方法的路径为 PathPrefix + "/" + 方法名称。
+	// 例如通过 "/api/{~method}" 注册 handler 时，应指定为 "/api" 。
+	PathPrefix string
+}
+
+// OpenApiDocument 是 OpenAPI 文档的根对象。仅包含 SlimAPI 用到的字段。
+type OpenApiDocument struct {
+	OpenApi    string                      `json:"openapi"`
+	Info       OpenApiInfo                 `json:"info"`
+	Servers    []OpenApiServer             `json:"servers,omitempty"`
+	Paths      map[string]*OpenApiPathItem `json:"paths"`
+	Components OpenApiComponents           `json:"components"`
+}
+
+// OpenApiInfo 对应 OpenAPI 的 Info Object 。
+type OpenApiInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenApiServer 对应 OpenAPI 的 Server Object 。
+type OpenApiServer struct {
+	Url string `json:"url"`
+}
+
+// OpenApiPathItem 对应 OpenAPI 的 Path Item Object 。 SlimAPI 的方法均以 POST 方式描述。
+type OpenApiPathItem struct {
+	Post *OpenApiOperation `json:"post,omitempty"`
+}
+
+// OpenApiOperation 对应 OpenAPI 的 Operation Object 。
+type OpenApiOperation struct {
+	OperationId string                      `json:"operationId"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*OpenApiParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenApiRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenApiResponse `json:"responses"`
+}
+
+// OpenApiParameter 对应 OpenAPI 的 Parameter Object 。
+type OpenApiParameter struct {
+	Name   string         `json:"name"`
+	In     string         `json:"in"` // query/header/cookie 。
+	Schema *OpenApiSchema `json:"schema"`
+}
+
+// OpenApiRequestBody 对应 OpenAPI 的 Request Body Object 。
+type OpenApiRequestBody struct {
+	Content map[string]*OpenApiMediaType `json:"content"`
+}
+
+// OpenApiResponse 对应 OpenAPI 的 Response Object 。
+type OpenApiResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenApiMediaType `json:"content,omitempty"`
+}
+
+// OpenApiMediaType 对应 OpenAPI 的 Media Type Object 。
+type OpenApiMediaType struct {
+	Schema *OpenApiSchema `json:"schema"`
+}
+
+// OpenApiComponents 对应 OpenAPI 的 Components Object 。
+type OpenApiComponents struct {
+	Schemas map[string]*OpenApiSchema `json:"schemas,omitempty"`
+}
+
+// OpenApiSchema 对应 OpenAPI 的 Schema Object 。仅包含 SlimAPI 用到的字段。
+type OpenApiSchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	ContentEncoding      string                    `json:"contentEncoding,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Items                *OpenApiSchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenApiSchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenApiSchema            `json:"additionalProperties,omitempty"`
+}
+
+// NewOpenApiDocument 根据 register 上已注册的方法，生成描述这些方法的 OpenAPI 文档。
+//
+// 每个方法被描述为一个 POST 操作：
+//   - 方法参数表中的 struct 参数（及有名称的参数，见 [webapi.ApiMetaParamNames] ）合并为请求的 body ，可用 application/json 或 application/x-www-form-urlencoded 格式上送；
+//     若含有 [*FilePart] 或 [*multipart.FileHeader] 类型（或以其为元素的 slice ）的字段，则还可用 multipart/form-data 格式上送，
+//     文件字段被描述为二进制数据（或其数组）。
+//   - 回执被描述为 [webapi.ApiResponse] 信封，其 Data 字段为方法返回值的具体类型。
+//   - 返回 [webapi.EventStream] 或 [webapi.NdJson] 的方法，回执的 Content-Type 分别为 text/event-stream 和 application/x-ndjson ，
+//     其 schema 描述流中每段数据的信封。
+//
+// 名称以“~”开头的元方法（如 [DescribeMethodName] ）不会出现在文档中。
+//
+// 具名的 struct 被放在 components.schemas 中，通过 $ref 引用。
+// 由于请求参数的名称与字段名称一致（大小写不敏感），而回执使用 JSON 序列化的名称，
+// 若 struct 上存在改变字段名称的 json tag ，则其作为请求参数时使用名称带 Input 后缀的独立定义。
+func NewOpenApiDocument(register webapi.ApiMethodRegister, op OpenApiOp) *OpenApiDocument {
+	doc := &OpenApiDocument{
+		OpenApi: OpenApiVersion,
+		Info: OpenApiInfo{
+			Title:       op.Title,
+			Version:     op.Version,
+			Description: op.Description,
+		},
+		Paths: make(map[string]*OpenApiPathItem),
+	}
+
+	if doc.Info.Title == "" {
+		doc.Info.Title = "SlimAPI"
+	}
+
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+
+	for _, v := range op.Servers {
+		doc.Servers = append(doc.Servers, OpenApiServer{Url: v})
+	}
+
+	g := newOpenApiSchemaGenerator()
+	prefix := strings.TrimRight(op.PathPrefix, "/")
+	for _, m := range webapi.ListMethods(register) {
+		if isMetaMethod(m.Name) {
+			continue
+		}
+
+		doc.Paths[prefix+"/"+m.Name] = &OpenApiPathItem{
+			Post: g.operation(m),
+		}
+	}
+
+	if len(g.schemas) > 0 {
+		doc.Components.Schemas = g.schemas
+	}
+	return doc
+}
+
+// OpenApiHandlerFunc 返回一个输出 OpenAPI 文档（ JSON 格式）的 [http.HandlerFunc] 。
+// 文档在第一次被请求时生成，此后不再变化，故应在完成全部方法的注册后再开始接收请求。
+//
+// 可通过 [webapi.ApiEngine.HandleGet] 将其挂在指定的路径上：
+//
+//	e.HandleGet("/openapi.json", slimapi.OpenApiHandlerFunc(handler, slimapi.OpenApiOp{PathPrefix: "/api"}))
+func OpenApiHandlerFunc(register webapi.ApiMethodRegister, op OpenApiOp) http.HandlerFunc {
+	var once sync.Once
+	var body []byte
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			var err error
+			body, err = json.Marshal(NewOpenApiDocument(register, op))
+			if err != nil {
+				panic(err) // 文档里只有基础类型，不会出错。
+			}
+		})
+
+		w.Header().Set(webapi.HttpHeaderContentType, webapi.ContentTypeJson)
+		w.Write(body)
+	}
+}
+
+// 生成 schema 的模式。请求参数和回执的字段命名规则不同。
+type openApiSchemaMode int
+
+const (
+	openApiSchemaModeResponse openApiSchemaMode = iota // 回执，使用 JSON 序列化的名称。
+	openApiSchemaModeRequest                           // 请求参数，使用字段名称。
+)
+
+var (
+	typeFileHeader        = reflect.TypeOf((*multipart.FileHeader)(nil))
+	typeFilePart          = reflect.TypeOf((*FilePart)(nil))
+	typeTime              = reflect.TypeOf(time.Time{})
+	typeSlimApiTime       = reflect.TypeOf(Time{})
+	typeStreamingResponse = reflect.TypeOf((*webapi.StreamingResponse)(nil)).Elem()
+	typeJsonArrayWriter   = reflect.TypeOf((*webapi.JsonArrayWriter)(nil)).Elem()
+	typeFileResponse      = reflect.TypeOf((*webapi.FileResponse)(nil))
+	typeError             = reflect.TypeOf((*error)(nil)).Elem()
+	typeApiState          = reflect.TypeOf((*webapi.ApiState)(nil))
+	typeLastEventId       = reflect.TypeOf(webapi.LastEventId(""))
+	typeContext           = reflect.TypeOf((*context.Context)(nil)).Elem()
+	typeSseEventData      = reflect.TypeOf((*webapi.SseEventData)(nil)).Elem()
+	typeJsonMarshaler     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+	// components.schemas 的名称只允许这些字符。
+	regexpInvalidSchemaNameChar = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+type openApiSchemaGenerator struct {
+	schemas map[string]*OpenApiSchema
+
+	// 记录每个 struct 在各模式下对应的 components.schemas 的名称。
+	names [2]map[reflect.Type]string
+
+	// 记录 components.schemas 中的名称被哪个类型占用，用于处理不同包下的同名类型。
+	owners map[string]reflect.Type
+}
+
+func newOpenApiSchemaGenerator() *openApiSchemaGenerator {
+	return &openApiSchemaGenerator{
+		schemas: make(map[string]*OpenApiSchema),
+		names: [2]map[reflect.Type]string{
+			make(map[reflect.Type]string),
+			make(map[reflect.Type]string),
+		},
+		owners: make(map[string]reflect.Type),
+	}
+}
+
+func (g *openApiSchemaGenerator) operation(m webapi.ApiMethod) *OpenApiOperation {
+	body, parameters := g.requestBody(m)
+	op := &OpenApiOperation{
+		OperationId: m.Name,
+		Parameters:  parameters,
+		RequestBody: body,
+		Responses: map[string]*OpenApiResponse{
+			"200": g.response(m.Value.Type()),
+		},
+	}
+
+	if m.Provider != "" {
+		op.Tags = []string{m.Provider}
+	}
+	return op
+}
+
+// 合并方法参数表中的 struct 参数及有名称的参数，生成请求的 body 。没有这些参数时 body 为 nil 。
+// 若方法以流的方式读取 body （见 [StreamArgumentDecoder] ），则这些参数放在 parameters 中，作为 query 参数。
+// 带有 from tag 的字段，来自 query 、 header 、 cookie 的，放在 parameters 中；
+// 来自路由的，由于方法的路径是固定的，无法描述，被忽略。
+func (g *openApiSchemaGenerator) requestBody(m webapi.ApiMethod) (body *OpenApiRequestBody, parameters []*OpenApiParameter) {
+	methodType := m.Value.Type()
+	params := &OpenApiSchema{Type: "object", Properties: make(map[string]*OpenApiSchema)}
+	files := make(map[string]*OpenApiSchema)
+	hasParam := false
+
+	for i := 0; i < methodType.NumIn(); i++ {
+		in := methodType.In(i)
+
+		// 有名称的参数，作为 body 中的一个字段。
+		if name := m.ParamName(i); name != "" && !isInjectedArgType(in) {
+			hasParam = true
+			if isFileType(in) {
+				files[name] = g.schema(in, openApiSchemaModeRequest)
+			} else {
+				params.Properties[name] = g.schema(in, openApiSchemaModeRequest)
+			}
+			continue
+		}
+
+		// 长轮询的元参数放在 query 上。
+		if in == typeLongPoll {
+			parameters = append(parameters,
+				&OpenApiParameter{Name: meta_Param_Cursor, In: fromQuery, Schema: &OpenApiSchema{Type: "string"}},
+				&OpenApiParameter{Name: meta_Param_Wait, In: fromQuery, Schema: &OpenApiSchema{Type: "integer"}},
+			)
+			continue
+		}
+
+		if in.Kind() != reflect.Struct {
+			continue
+		}
+
+		hasParam = true
+		eachStructField(in, openApiSchemaModeRequest, func(name string, f reflect.StructField) {
+			if source, fromName, ok := parseFromTag(in, f); ok && source != fromBody {
+				if source != fromRoute {
+					parameters = append(parameters, &OpenApiParameter{
+						Name:   fromName,
+						In:     source,
+						Schema: g.schema(f.Type, openApiSchemaModeRequest),
+					})
+				}
+				return
+			}
+
+			if isFileType(f.Type) {
+				files[name] = g.schema(f.Type, openApiSchemaModeRequest)
+				return
+			}
+			params.Properties[name] = g.schema(f.Type, openApiSchemaModeRequest)
+		})
+	}
+
+	// body 以流的方式读取时，其他参数来自 query 。
+	if streamType := requestStreamContentType(methodType); streamType != "" {
+		names := slices.Sorted(maps.Keys(params.Properties))
+		for _, name := range names {
+			parameters = append(parameters, &OpenApiParameter{Name: name, In: fromQuery, Schema: params.Properties[name]})
+		}
+
+		schema := &OpenApiSchema{Type: "string", Format: "binary"}
+		switch streamType {
+		case webapi.ContentTypeMultipartForm:
+			schema = &OpenApiSchema{Type: "object"}
+		case webapi.ContentTypeNdJson:
+			// 每行一项，给出项的 schema 。
+			for i := 0; i < methodType.NumIn(); i++ {
+				if itemType, ok := streamItemType(methodType.In(i)); ok {
+					schema = g.schema(itemType, openApiSchemaModeRequest)
+					break
+				}
+			}
+		}
+		body = &OpenApiRequestBody{
+			Content: map[string]*OpenApiMediaType{streamType: {Schema: schema}},
+		}
+		return body, parameters
+	}
+
+	if !hasParam {
+		return nil, nil
+	}
+
+	body = &OpenApiRequestBody{
+		Content: map[string]*OpenApiMediaType{
+			webapi.ContentTypeJson: {Schema: params},
+			webapi.ContentTypeForm: {Schema: params},
+		},
+	}
+
+	if len(files) > 0 {
+		multipartParams := &OpenApiSchema{Type: "object", Properties: make(map[string]*OpenApiSchema)}
+		for k, v := range params.Properties {
+			multipartParams.Properties[k] = v
+		}
+		for k, v := range files {
+			multipartParams.Properties[k] = v
+		}
+		body.Content[webapi.ContentTypeMultipartForm] = &OpenApiMediaType{Schema: multipartParams}
+	}
+
+	return body, parameters
+}
+
+// 生成方法回执的描述。
+func (g *openApiSchemaGenerator) response(methodType reflect.Type) *OpenApiResponse {
+	var dataType reflect.Type
+	if methodType.NumOut() > 0 && methodType.Out(0) != typeError {
+		dataType = methodType.Out(0)
+	}
+
+	if dataType != nil && dataType.Implements(typeStreamingResponse) {
+		contentType := streamingContentType(dataType)
+		if dataType.Implements(typeJsonArrayWriter) {
+			return &OpenApiResponse{
+				Description: "The ApiResponse envelope, written incrementally; Data comes first.",
+				Content: map[string]*OpenApiMediaType{
+					contentType: {Schema: g.envelope(streamingEnvelopeDataType(dataType))},
+				},
+			}
+		}
+
+		return &OpenApiResponse{
+			Description: "A stream, each block of which is an ApiResponse envelope.",
+			Content: map[string]*OpenApiMediaType{
+				contentType: {Schema: g.envelope(streamingDataType(dataType))},
+			},
+		}
+	}
+
+	// 文件本身作为 body ，仅出错时输出信封。
+	if dataType == typeFileResponse {
+		return &OpenApiResponse{
+			Description: "The file; the ApiResponse envelope if an error occurred.",
+			Content: map[string]*OpenApiMediaType{
+				webapi.ContentTypeBinary: {Schema: &OpenApiSchema{Type: "string", Format: "binary"}},
+				webapi.ContentTypeJson:   {Schema: g.envelope(nil)},
+			},
+		}
+	}
+
+	return &OpenApiResponse{
+		Description: "The ApiResponse envelope.",
+		Content: map[string]*OpenApiMediaType{
+			webapi.ContentTypeJson: {Schema: g.envelope(dataType)},
+		},
+	}
+}
+
+// 生成 [webapi.ApiResponse] 信封， dataType 为 nil 时， Data 为 null 。
+func (g *openApiSchemaGenerator) envelope(dataType reflect.Type) *OpenApiSchema {
+	data := &OpenApiSchema{Type: "null"}
+	if dataType != nil {
+		data = g.schema(dataType, openApiSchemaModeResponse)
+	}
+
+	return &OpenApiSchema{
+		Type: "object",
+		Properties: map[string]*OpenApiSchema{
+			"Code":    {Type: "integer", Description: "0 for success, otherwise an error code."},
+			"Message": {Type: "string"},
+			"Data":    data,
+		},
+	}
+}
+
+// 返回流式输出的类型声明的 Content-Type 。 ContentType 方法在类型的零值上调用，对于指针类型，使用指向零值的指针；
+// 对于接口类型，或方法在零值上 panic 时，无法得知具体的格式，返回 [webapi.ContentTypeBinary] 。
+func streamingContentType(typ reflect.Type) (contentType string) {
+	defer func() {
+		if recover() != nil {
+			contentType = webapi.ContentTypeBinary
+		}
+	}()
+
+	var v reflect.Value
+	switch typ.Kind() {
+	case reflect.Interface:
+		return webapi.ContentTypeBinary
+	case reflect.Ptr:
+		v = reflect.New(typ.Elem())
+	default:
+		v = reflect.Zero(typ)
+	}
+	return v.Interface().(webapi.StreamingResponse).ContentType()
+}
+
+// 返回流式输出的类型在信封中的 Data 的类型：对于 [webapi.JsonArray] ，为元素类型的 slice ；对于其他类型，同 streamingDataType 。
+func streamingEnvelopeDataType(typ reflect.Type) reflect.Type {
+	dataType := streamingDataType(typ)
+	if dataType != nil && typ.Implements(typeJsonArrayWriter) {
+		return reflect.SliceOf(dataType)
+	}
+	return dataType
+}
+
+// 返回流式输出的类型（如 [webapi.EventStream] ）的元素类型。
+// 这些类型都形如 func(yield func(data DATA, err error) bool) ，若不是这种形式，返回 nil 。
+func streamingDataType(typ reflect.Type) reflect.Type {
+	if typ.Kind() != reflect.Func || typ.NumIn() != 1 {
+		return nil
+	}
+
+	yield := typ.In(0)
+	if yield.Kind() != reflect.Func || yield.NumIn() != 2 {
+		return nil
+	}
+
+	// webapi.SseEvent 中只有 Data 字段放在信封中。
+	dataType := yield.In(0)
+	if dataType.Kind() == reflect.Struct && dataType.Implements(typeSseEventData) {
+		if f, ok := dataType.FieldByName("Data"); ok {
+			return f.Type
+		}
+	}
+	return dataType
+}
+
+// 判断参数是否由框架注入（如 [*webapi.ApiState] ），这类参数不是请求参数，即便其有名称。
+func isInjectedArgType(typ reflect.Type) bool {
+	return typ == typeApiState || typ == typeContext || typ == typeLastEventId || typ == typeLongPoll
+}
+
+// 判断是否是以 multipart/form-data 上传的文件，包括接收同名的多个文件的 slice 。
+func isFileType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	return typ == typeFileHeader || typ == typeFilePart
+}
+
+func (g *openApiSchemaGenerator) schema(typ reflect.Type, mode openApiSchemaMode) *OpenApiSchema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if elem, ok := optionalElemType(typ); ok {
+		return g.schema(elem, mode)
+	}
+
+	switch typ {
+	case typeTime:
+		if mode == openApiSchemaModeRequest {
+			return &OpenApiSchema{Type: "string", Description: "yyyy-MM-dd HH:mm:ss or RFC3339"}
+		}
+		return &OpenApiSchema{Type: "string", Format: "date-time"}
+
+	case typeSlimApiTime:
+		return &OpenApiSchema{Type: "string", Description: "yyyy-MM-dd HH:mm:ss"}
+
+	case typeFilePart.Elem(), typeFileHeader.Elem():
+		return &OpenApiSchema{Type: "string", Format: "binary"}
+	}
+
+	// 自定义了 JSON 序列化的类型，无法得知其格式。
+	if mode == openApiSchemaModeResponse && typ.Kind() != reflect.Interface &&
+		(typ.Implements(typeJsonMarshaler) || reflect.PointerTo(typ).Implements(typeJsonMarshaler)) {
+		return &OpenApiSchema{}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &OpenApiSchema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &OpenApiSchema{Type: "integer", Format: "int64"}
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenApiSchema{Type: "integer", Format: "int32"}
+
+	case reflect.Float32:
+		return &OpenApiSchema{Type: "number", Format: "float"}
+
+	case reflect.Float64:
+		return &OpenApiSchema{Type: "number", Format: "double"}
+
+	case reflect.String:
+		return &OpenApiSchema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			if mode == openApiSchemaModeResponse {
+				return &OpenApiSchema{Type: "string", ContentEncoding: "base64"}
+			}
+			return &OpenApiSchema{Type: "string"}
+		}
+		return &OpenApiSchema{Type: "array", Items: g.schema(typ.Elem(), mode)}
+
+	case reflect.Map:
+		return &OpenApiSchema{Type: "object", AdditionalProperties: g.schema(typ.Elem(), mode)}
+
+	case reflect.Struct:
+		return g.structSchema(typ, mode)
+	}
+
+	// interface 等，可以是任意值。
+	return &OpenApiSchema{}
+}
+
+// 匿名 struct 直接内联；具名 struct 放在 components.schemas 中，返回其引用。
+func (g *openApiSchemaGenerator) structSchema(typ reflect.Type, mode openApiSchemaMode) *OpenApiSchema {
+	if typ.Name() == "" {
+		return g.objectSchema(typ, mode)
+	}
+
+	// 请求参数的名称不受 json tag 影响，没有改名的 tag 时，请求和回执可共用一个定义。
+	if mode == openApiSchemaModeRequest && !hasRenamingJsonTag(typ) {
+		mode = openApiSchemaModeResponse
+	}
+
+	if name, ok := g.names[mode][typ]; ok {
+		return &OpenApiSchema{Ref: "#/components/schemas/" + name}
+	}
+
+	base := regexpInvalidSchemaNameChar.ReplaceAllString(typ.Name(), "_")
+	if mode == openApiSchemaModeRequest {
+		base += "Input"
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		owner, ok := g.owners[name]
+		if !ok || owner == typ {
+			break
+		}
+		name = base + strconv.Itoa(i)
+	}
+
+	// 先占位再生成字段，以支持自引用的类型。
+	g.names[mode][typ] = name
+	g.owners[name] = typ
+	g.schemas[name] = g.objectSchema(typ, mode)
+
+	return &OpenApiSchema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *openApiSchemaGenerator) objectSchema(typ reflect.Type, mode openApiSchemaMode) *OpenApiSchema {
+	res := &OpenApiSchema{Type: "object", Properties: make(map[string]*OpenApiSchema)}
+	eachStructField(typ, mode, func(name string, f reflect.StructField) {
+		res.Properties[name] = g.schema(f.Type, mode)
+	})
+	return res
+}
+
+// eachStructField 遍历 struct 的公开字段，内嵌的 struct 会被展开。
+// 回执模式下，字段名称为 JSON 序列化的名称，且忽略 json:"-" 的字段；请求模式下，同 eachParamField 。
+func eachStructField(typ reflect.Type, mode openApiSchemaMode, fn func(name string, f reflect.StructField)) {
+	if mode == openApiSchemaModeRequest {
+		eachParamField(typ, fn)
+		return
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+
+		jsonName, hasJsonName, skip := parseJsonTag(f)
+		if skip {
+			continue
+		}
+
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct && !hasJsonName {
+				eachStructField(ft, mode, fn)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Name
+		if hasJsonName {
+			name = jsonName
+		}
+		fn(name, f)
+	}
+}
+
+// 解析字段的 json tag 。 skip 表示 tag 为 "-" 。
+func parseJsonTag(f reflect.StructField) (name string, hasName bool, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, _, _ = strings.Cut(tag, ",")
+	return name, name != "", false
+}
+
+// 判断 struct 是否有改变字段名称（或忽略字段）的 json tag 。不检查嵌套的类型。
+func hasRenamingJsonTag(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, hasName, skip := parseJsonTag(f)
+		if skip || (hasName && name != f.Name) {
+			return true
+		}
+
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && hasRenamingJsonTag(ft) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+package slimapi
+
+import (
+	"encoding/json"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/stretchr/testify/require"
+)
+
+type openApiTestNode struct {
+	Value    int
+	Children []*openApiTestNode
+}
+
+type openApiTestRenamed struct {
+	A    string `json:"a"`
+	B    int    `json:"-"`
+	Time time.Time
+}
+
+type openApiTestEmbedded struct {
+	E string
+}
+
+type openApiTestRequest struct {
+	openApiTestEmbedded
+	Name    string
+	Tags    []string
+	Map     map[string]float64
+	Node    openApiTestNode
+	Renamed *openApiTestRenamed
+	File    *FilePart
+	Header  *multipart.FileHeader
+	Files   []*FilePart
+	Raw     []byte
+	Opt     Optional[int]
+	Token   string `from:"header=X-Token"`
+	Id      int    `from:"route"`
+}
+
+type openApiTestProvider struct{}
+
+func (openApiTestProvider) Empty() {}
+
+func (openApiTestProvider) Error(state *webapi.ApiState) error { return nil }
+
+func (openApiTestProvider) Do(req openApiTestRequest, other struct{ Other bool }) (openApiTestRenamed, error) {
+	return openApiTestRenamed{}, nil
+}
+
+func (openApiTestProvider) Events() webapi.EventStream[openApiTestNode] { return nil }
+
+func (openApiTestProvider) Lines() webapi.NdJson[[]int] { return nil }
+
+// 将 v 序列化后再反序列化为 map ，以便用路径检查内容。
+func openApiToMap(t *testing.T, v any) map[string]any {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	m := make(map[string]any)
+	require.NoError(t, json.Unmarshal(b, &m))
+	return m
+}
+
+// 按路径获取 map 中的值。
+func openApiGet(m any, path ...string) any {
+	for _, p := range path {
+		mm, ok := m.(map[string]any)
+		if !ok {
+			return nil
+		}
+		m = mm[p]
+	}
+	return m
+}
+
+func TestNewOpenApiDocument(t *testing.T) {
+	h := NewSlimApiHandler("")
+	h.RegisterMethods(openApiTestProvider{})
+
+	doc := NewOpenApiDocument(h, OpenApiOp{PathPrefix: "/api/", Servers: []string{"http://temp.org"}})
+	require.Equal(t, OpenApiVersion, doc.OpenApi)
+	require.Equal(t, "SlimAPI", doc.Info.Title)
+	require.Equal(t, "1.0.0", doc.Info.Version)
+	require.Equal(t, []OpenApiServer{{Url: "http://temp.org"}}, doc.Servers)
+	require.Len(t, doc.Paths, 5)
+
+	m := openApiToMap(t, doc)
+	paths := m["paths"]
+
+	t.Run("empty", func(t *testing.T) {
+		op := openApiGet(paths, "/api/Empty", "post")
+		require.Equal(t, "Empty", openApiGet(op, "operationId"))
+		require.Equal(t, []any{"openApiTestProvider"}, openApiGet(op, "tags"))
+		require.Nil(t, openApiGet(op, "requestBody"))
+		require.Equal(t, "null", openApiGet(op, "responses", "200", "content", "application/json", "schema", "properties", "Data", "type"))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		op := openApiGet(paths, "/api/Error", "post")
+		require.Nil(t, openApiGet(op, "requestBody"))
+		require.Equal(t, "null", openApiGet(op, "responses", "200", "content", "application/json", "schema", "properties", "Data", "type"))
+	})
+
+	t.Run("request", func(t *testing.T) {
+		content := openApiGet(paths, "/api/Do", "post", "requestBody", "content")
+
+		for _, ct := range []string{"application/json", "application/x-www-form-urlencoded"} {
+			props := openApiGet(content, ct, "schema", "properties").(map[string]any)
+			require.Equal(t, map[string]any{"type": "string"}, props["E"])
+			require.Equal(t, map[string]any{"type": "string"}, props["Name"])
+			require.Equal(t, map[string]any{"type": "boolean"}, props["Other"])
+			require.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, props["Tags"])
+			require.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "number", "format": "double"}}, props["Map"])
+			require.Equal(t, map[string]any{"$ref": "#/components/schemas/openApiTestNode"}, props["Node"])
+			require.Equal(t, map[string]any{"$ref": "#/components/schemas/openApiTestRenamedInput"}, props["Renamed"])
+			require.Equal(t, map[string]any{"type": "string"}, props["Raw"])
+			require.Equal(t, map[string]any{"type": "integer", "format": "int64"}, props["Opt"])
+			require.NotContains(t, props, "Token")
+			require.NotContains(t, props, "Id")
+			require.NotContains(t, props, "File")
+			require.NotContains(t, props, "Header")
+			require.NotContains(t, props, "Files")
+		}
+
+		// header 参数放在 parameters 中，路由参数无法描述。
+		require.Equal(t, []any{
+			map[string]any{"name": "X-Token", "in": "header", "schema": map[string]any{"type": "string"}},
+		}, openApiGet(paths, "/api/Do", "post", "parameters"))
+
+		props := openApiGet(content, "multipart/form-data", "schema", "properties").(map[string]any)
+		require.Equal(t, map[string]any{"type": "string", "format": "binary"}, props["File"])
+		require.Equal(t, map[string]any{"type": "string", "format": "binary"}, props["Header"])
+		require.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string", "format": "binary"}}, props["Files"])
+		require.Equal(t, map[string]any{"type": "string"}, props["Name"])
+	})
+
+	t.Run("response", func(t *testing.T) {
+		schema := openApiGet(paths, "/api/Do", "post", "responses", "200", "content", "application/json", "schema")
+		require.Equal(t, map[string]any{"type": "integer", "description": "0 for success, otherwise an error code."}, openApiGet(schema, "properties", "Code"))
+		require.Equal(t, map[string]any{"type": "string"}, openApiGet(schema, "properties", "Message"))
+		require.Equal(t, map[string]any{"$ref": "#/components/schemas/openApiTestRenamed"}, openApiGet(schema, "properties", "Data"))
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		schema := openApiGet(paths, "/api/Events", "post", "responses", "200", "content", "text/event-stream", "schema")
+		require.Equal(t, map[string]any{"$ref": "#/components/schemas/openApiTestNode"}, openApiGet(schema, "properties", "Data"))
+
+		schema = openApiGet(paths, "/api/Lines", "post", "responses", "200", "content", "application/x-ndjson", "schema")
+		require.Equal(t, "array", openApiGet(schema, "properties", "Data", "type"))
+	})
+
+	t.Run("components", func(t *testing.T) {
+		schemas := openApiGet(m, "components", "schemas").(map[string]any)
+		require.Len(t, schemas, 3)
+
+		require.Equal(t, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"Value": map[string]any{"type": "integer", "format": "int64"},
+				"Children": map[string]any{
+					"type":  "array",
+					"items": map[string]any{"$ref": "#/components/schemas/openApiTestNode"},
+				},
+			},
+		}, schemas["openApiTestNode"])
+
+		require.Equal(t, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"a":    map[string]any{"type": "string"},
+				"Time": map[string]any{"type": "string", "format": "date-time"},
+			},
+		}, schemas["openApiTestRenamed"])
+
+		require.Equal(t, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"A":    map[string]any{"type": "string"},
+				"B":    map[string]any{"type": "integer", "format": "int64"},
+				"Time": map[string]any{"type": "string", "description": "yyyy-MM-dd HH:mm:ss or RFC3339"},
+			},
+		}, schemas["openApiTestRenamedInput"])
+	})
+}
+
+func TestNewOpenApiDocument_named(t *testing.T) {
+	g := newOpenApiSchemaGenerator()
+	body, parameters := g.requestBody(webapi.ApiMethod{
+		Value: reflect.ValueOf(func(*webapi.ApiState, int, struct{ A string }, *FilePart) {}),
+		Meta:  webapi.NewApiMeta(map[string]any{webapi.ApiMetaParamNames: []string{"state", "id", "", "file"}}),
+	})
+	require.Nil(t, parameters)
+
+	m := openApiToMap(t, body)
+	require.Equal(t, map[string]any{
+		"id": map[string]any{"type": "integer", "format": "int64"},
+		"A":  map[string]any{"type": "string"},
+	}, openApiGet(m, "content", "application/json", "schema", "properties"))
+	require.Equal(t, map[string]any{"type": "string", "format": "binary"},
+		openApiGet(m, "content", "multipart/form-data", "schema", "properties", "file"))
+
+	// 只有无名称的非 struct 参数时，没有 body 。
+	body, _ = g.requestBody(webapi.ApiMethod{Value: reflect.ValueOf(func(*webapi.ApiState) {})})
+	require.Nil(t, body)
+}
+
+func TestNewOpenApiDocument_stream(t *testing.T) {
+	g := newOpenApiSchemaGenerator()
+	body, parameters := g.requestBody(webapi.ApiMethod{
+		Value: reflect.ValueOf(func(io.Reader, struct {
+			B     int
+			A     string
+			Token string `from:"header=X-Token"`
+		}) {
+		}),
+	})
+	require.Equal(t, []*OpenApiParameter{
+		{Name: "X-Token", In: "header", Schema: &OpenApiSchema{Type: "string"}},
+		{Name: "A", In: "query", Schema: &OpenApiSchema{Type: "string"}},
+		{Name: "B", In: "query", Schema: &OpenApiSchema{Type: "integer", Format: "int64"}},
+	}, parameters)
+	require.Equal(t, map[string]*OpenApiMediaType{
+		"application/octet-stream": {Schema: &OpenApiSchema{Type: "string", Format: "binary"}},
+	}, body.Content)
+
+	body, parameters = g.requestBody(webapi.ApiMethod{Value: reflect.ValueOf(func(MultipartParts) {})})
+	require.Nil(t, parameters)
+	require.Equal(t, map[string]*OpenApiMediaType{
+		"multipart/form-data": {Schema: &OpenApiSchema{Type: "object"}},
+	}, body.Content)
+
+	body, _ = g.requestBody(webapi.ApiMethod{Value: reflect.ValueOf(func(iter.Seq2[int, error]) {})})
+	require.Equal(t, map[string]*OpenApiMediaType{
+		"application/x-ndjson": {Schema: &OpenApiSchema{Type: "integer", Format: "int64"}},
+	}, body.Content)
+}
+
+func TestNewOpenApiDocument_longPoll(t *testing.T) {
+	g := newOpenApiSchemaGenerator()
+	body, parameters := g.requestBody(webapi.ApiMethod{
+		Value: reflect.ValueOf(func(webapi.LongPoll, struct{ Topic string }) {}),
+		Meta:  webapi.NewApiMeta(map[string]any{webapi.ApiMetaParamNames: []string{"lp"}}),
+	})
+	require.Equal(t, []*OpenApiParameter{
+		{Name: "~cursor", In: "query", Schema: &OpenApiSchema{Type: "string"}},
+		{Name: "~wait", In: "query", Schema: &OpenApiSchema{Type: "integer"}},
+	}, parameters)
+
+	m := openApiToMap(t, body)
+	require.Equal(t, map[string]any{
+		"Topic": map[string]any{"type": "string"},
+	}, openApiGet(m, "content", "application/json", "schema", "properties"))
+}
+
+func TestNewOpenApiDocument_fileResponse(t *testing.T) {
+	g := newOpenApiSchemaGenerator()
+	res := g.response(reflect.TypeOf(func() (*webapi.FileResponse, error) { return nil, nil }))
+
+	m := openApiToMap(t, res)
+	require.Equal(t, map[string]any{"type": "string", "format": "binary"},
+		openApiGet(m, "content", "application/octet-stream", "schema"))
+	require.Equal(t, map[string]any{"type": "null"},
+		openApiGet(m, "content", "application/json", "schema", "properties", "Data"))
+}
+
+// 其方法依赖内嵌的实例，在零值上调用时 panic 。
+type openApiTestStream struct{ webapi.StreamingResponse }
+
+func Test_streamingContentType(t *testing.T) {
+	require.Equal(t, webapi.ContentTypeNdJson, streamingContentType(reflect.TypeOf(webapi.NdJson[int](nil))))
+	require.Equal(t, webapi.ContentTypeNdJson, streamingContentType(reflect.TypeOf((*webapi.NdJson[int])(nil))))
+	require.Equal(t, webapi.ContentTypeBinary, streamingContentType(typeStreamingResponse))
+	require.Equal(t, webapi.ContentTypeBinary, streamingContentType(reflect.TypeOf(openApiTestStream{})))
+	require.Equal(t, webapi.ContentTypeBinary, streamingContentType(reflect.TypeOf(&openApiTestStream{})))
+
+	// 不论类型如何，生成文档时都不 panic 。
+	g := newOpenApiSchemaGenerator()
+	res := g.response(reflect.TypeOf(func() webapi.StreamingResponse { return nil }))
+	require.Contains(t, res.Content, webapi.ContentTypeBinary)
+
+	d := describeMethod(webapi.ApiMethod{Value: reflect.ValueOf(func() webapi.StreamingResponse { return nil })})
+	require.Equal(t, webapi.ContentTypeBinary, d.Streaming)
+}
+
+func TestOpenApiHandlerFunc(t *testing.T) {
+	h := NewSlimApiHandler("")
+	e := webapi.NewEngine()
+	e.Handle("/api/{~method}", h, nil)
+	e.HandleGet("/openapi.json", OpenApiHandlerFunc(h, OpenApiOp{Title: "t", Version: "v", PathPrefix: "/api"}))
+
+	// 文档在第一次请求时生成，故可在之后注册方法。
+	h.RegisterMethods(openApiTestProvider{})
+
+	s := httptest.NewServer(e)
+	defer s.Close()
+
+	resp, err := s.Client().Get(s.URL + "/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, webapi.ContentTypeJson, resp.Header.Get(webapi.HttpHeaderContentType))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var doc OpenApiDocument
+	require.NoError(t, json.Unmarshal(body, &doc))
+	require.Equal(t, "t", doc.Info.Title)
+	require.Equal(t, "v", doc.Info.Version)
+	require.Contains(t, doc.Paths, "/api/Do")
+}
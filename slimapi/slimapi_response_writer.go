@@ -3,7 +3,12 @@ package slimapi
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"time"
 
+	"github.com/cmstar/go-errx"
 	"github.com/cmstar/go-webapi"
 )
 
@@ -23,6 +28,16 @@ func (x *slimApiResponseWriter) WriteResponse(state *webapi.ApiState) {
 		return
 	}
 
+	// 文件直接输出，不使用信封。
+	if file, ok := state.Data.(*webapi.FileResponse); ok && file != nil {
+		state.AddCleanup(func() { file.Close() })
+
+		if state.Error == nil && x.writeFileResponse(state, file) {
+			return
+		}
+		state.Data = nil
+	}
+
 	// 分为流式和非流式两种情况。如果是流式，则强制使用其自带的 Content-Type 和格式。
 	streamingResponse, ok := state.Data.(webapi.StreamingResponse)
 	if ok {
@@ -72,36 +87,346 @@ func (x *slimApiResponseWriter) writeGenericResponse(state *webapi.ApiState) {
 func (x *slimApiResponseWriter) writeStreamingResponse(state *webapi.ApiState, streaming webapi.StreamingResponse) {
 	state.ResponseContentType = streaming.ContentType()
 
+	if arrayWriter, ok := streaming.(webapi.JsonArrayWriter); ok {
+		x.writeJsonArray(state, streaming, arrayWriter)
+		return
+	}
+
+	heartbeatWriter, canHeartbeat := streaming.(webapi.HeartbeatWriter)
+	heartbeat := webapi.StreamHeartbeat(state)
+
 	state.ResponseBody = func(yield func([]byte) bool) {
 		// 因为 ResponseBody 的迭代是串行的，这里可以复用同一个 buf 以提高性能。
 		buf := new(bytes.Buffer)
 
-		for data, err := range streaming.Iter() {
+		onItem := func(data any, err error) streamStep {
 			if err != nil {
 				state.Error = err
 				// 并没有严格要求 error 必须是 StreamingResponse 的最后一段。故此处不需要 break 。
 			}
 
+			fields, data := webapi.UnwrapSseEvent(data)
 			response := x.buildJsonResponse(state, data, err)
 			if response == nil {
-				continue
+				return streamNext
 			}
 
-			streaming.WriteJsonBlock(buf, response)
-			seg := buf.Bytes()
-			if !yield(seg) {
-				break
+			buf.Reset()
+			if w, ok := streaming.(webapi.SseEventWriter); ok {
+				w.WriteEvent(buf, fields, response)
+			} else {
+				streaming.WriteJsonBlock(buf, response)
 			}
+			return nextOrAbort(yield(buf.Bytes()))
+		}
 
+		interval := time.Duration(0)
+		onIdle := func() bool {
 			buf.Reset()
+			heartbeatWriter.WriteHeartbeat(buf)
+			return yield(buf.Bytes())
+		}
+		if canHeartbeat {
+			interval = heartbeat
 		}
 
+		// 输出中断（通常是连接已断开）或请求被取消后，不再输出任何内容，包括最终块。
+		if !iterateStream(state, streaming.Iter(), interval, onItem, onIdle) {
+			return
+		}
+
+		buf.Reset()
 		streaming.WriteFinalBlock(buf)
 		final := buf.Bytes()
 		yield(final)
 	}
 }
 
+// 输出 Data 为数组的一个 JSON 文档，见 [webapi.JsonArray] 。
+func (x *slimApiResponseWriter) writeJsonArray(state *webapi.ApiState, streaming webapi.StreamingResponse, arrayWriter webapi.JsonArrayWriter) {
+	heartbeatWriter, canHeartbeat := streaming.(webapi.HeartbeatWriter)
+	heartbeat := webapi.StreamHeartbeat(state)
+
+	state.ResponseBody = func(yield func([]byte) bool) {
+		buf := new(bytes.Buffer)
+		arrayWriter.WriteArrayStart(buf)
+		if !yield(buf.Bytes()) {
+			return
+		}
+
+		index := 0
+		var streamErr error
+		onItem := func(data any, err error) streamStep {
+			// 信封上只能给出一个错误，其后的数据没有输出的位置，故停止迭代并立即输出信封。
+			if err != nil {
+				state.Error = err
+				streamErr = err
+				return streamStop
+			}
+
+			_, data = webapi.UnwrapSseEvent(data)
+			item, err := json.Marshal(data)
+			if err != nil {
+				webapi.PanicApiError(state, err, "json encoding error")
+			}
+
+			buf.Reset()
+			arrayWriter.WriteArrayItem(buf, index, item)
+			index++
+			return nextOrAbort(yield(buf.Bytes()))
+		}
+
+		interval := time.Duration(0)
+		onIdle := func() bool {
+			buf.Reset()
+			heartbeatWriter.WriteHeartbeat(buf)
+			return yield(buf.Bytes())
+		}
+		if canHeartbeat {
+			interval = heartbeat
+		}
+
+		if !iterateStream(state, streaming.Iter(), interval, onItem, onIdle) {
+			return
+		}
+
+		envelope := []byte("{}")
+		if response := x.buildJsonResponse(state, nil, streamErr); response != nil {
+			var err error
+			envelope, err = removeJsonMember(response, "Data")
+			if err != nil {
+				webapi.PanicApiError(state, err, "the response must be a JSON object, got %s", response)
+			}
+		}
+
+		buf.Reset()
+		arrayWriter.WriteArrayEnd(buf, envelope)
+		yield(buf.Bytes())
+	}
+}
+
+// 移除 JSON 对象 obj 中名称为 name 的成员，其余成员保持原来的顺序。 obj 不是 JSON 对象时返回错误。
+func removeJsonMember(obj []byte, name string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(obj))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("not a JSON object")
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		key := tok.(string)
+		if key == name {
+			continue
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// 流式输出结束的原因，记录在日志的 StreamEnd 字段上，见 [iterateStream] 。
+const (
+	streamEndCompleted = "completed" // 迭代器执行完毕。
+	streamEndStopped   = "stopped"   // onItem 要求停止，见 [streamStop] 。
+	streamEndCanceled  = "canceled"  // 请求的 context 被取消，通常是客户端断开了连接。
+	streamEndAborted   = "aborted"   // 输出失败，通常是连接已断开。
+	streamEndPanic     = "panic"     // 迭代器 panic 。
+)
+
+// streamStep 是 [iterateStream] 中 onItem 的返回值，决定迭代如何继续。
+type streamStep int
+
+const (
+	streamNext  streamStep = iota // 继续迭代。
+	streamStop                    // 停止迭代，但输出没有失败，调用方仍需输出最终块。
+	streamAbort                   // 输出失败，停止迭代，此后不再输出任何内容。
+)
+
+// 输出成功时继续迭代，否则中断。
+func nextOrAbort(ok bool) streamStep {
+	if ok {
+		return streamNext
+	}
+	return streamAbort
+}
+
+// iterateStream 在另一个 goroutine 中迭代 seq ，在当前 goroutine 中将每段数据交给 onItem ；
+// heartbeat 大于 0 时，若等待下一段数据的时间超过 heartbeat ，则调用 onIdle 。
+//
+// onItem 返回 streamStop 或 streamAbort ， onIdle 返回 false ，或者请求的 context 被取消（通常是客户端断开了连接）时，
+// 迭代立即停止，此后 seq 中的 yield 返回 false ；阻塞中的 seq 可以通过同一个 context （见 [webapi.ContextArgumentDecoder] ）感知。
+// 前两者由调用方发起，此时通过 [webapi.ApiState.CancelRequest] 取消该 context 。
+// 返回前（包括 onItem 或 onIdle panic 时）等待 seq 执行完毕，以保证其中的清理逻辑（如 defer ）在请求结束前执行。
+// seq 中的 panic 被转移到当前 goroutine 。
+//
+// 输出的段数、耗时和结束原因，以 StreamItems 、 StreamDuration 、 StreamEnd 记录在 [webapi.ApiState.LogMessage] 上。
+// 返回调用方是否应继续输出最终块，即 seq 执行完毕，或由 onItem 返回 streamStop 停止。
+func iterateStream(state *webapi.ApiState, seq iter.Seq2[any, error], heartbeat time.Duration, onItem func(any, error) streamStep, onIdle func() bool) bool {
+	type item struct {
+		data any
+		err  error
+	}
+
+	items := make(chan item)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	var panicked any
+
+	go func() {
+		defer close(done)
+		defer func() {
+			panicked = recover()
+		}()
+
+		for data, err := range seq {
+			select {
+			case items <- item{data, err}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	count := 0
+	end := streamEndCompleted
+	defer func() {
+		state.LogMessage = append(state.LogMessage,
+			"StreamItems", count,
+			"StreamDuration", time.Since(start),
+			"StreamEnd", end,
+		)
+	}()
+
+	// 结束迭代并等待 seq 执行完毕。 onItem 或 onIdle panic （如 JSON 序列化失败）时也需执行，否则 seq 会一直阻塞在发送上。
+	// 循环没有正常结束时（包括输出失败），阻塞中的 seq （如等待新消息的订阅）可能不会再调用 yield ，
+	// 需通过 context 通知其结束，否则等待不会返回。
+	loopDone := false
+	finished := false
+	finish := func() {
+		if finished {
+			return
+		}
+		finished = true
+
+		if !loopDone {
+			end = streamEndPanic
+		}
+		if end != streamEndCompleted {
+			state.CancelRequest()
+		}
+
+		close(stop)
+		<-done
+	}
+	defer finish()
+
+	ctx := state.RawRequest.Context()
+	var timer *time.Timer
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		timer = time.NewTimer(heartbeat)
+		defer timer.Stop()
+		tick = timer.C
+	}
+
+loop:
+	for {
+		select {
+		case it := <-items:
+			// 数据与取消同时到达时，优先处理取消。
+			if ctx.Err() != nil {
+				end = streamEndCanceled
+				break loop
+			}
+
+			count++
+			switch onItem(it.data, it.err) {
+			case streamStop:
+				end = streamEndStopped
+				break loop
+			case streamAbort:
+				end = streamEndAborted
+				break loop
+			}
+
+		case <-tick:
+			if !onIdle() {
+				end = streamEndAborted
+				break loop
+			}
+
+		case <-ctx.Done():
+			end = streamEndCanceled
+			break loop
+
+		case <-done:
+			break loop
+		}
+
+		if timer != nil {
+			timer.Reset(heartbeat)
+		}
+	}
+
+	loopDone = true
+	finish()
+
+	if panicked != nil {
+		end = streamEndPanic
+		panic(panicked)
+	}
+	return end == streamEndCompleted || end == streamEndStopped
+}
+
+// 输出文件。在开始输出 body 前检查文件是否可读，不可读时将错误记录在 state.Error 上并返回 false ，由调用方以信封的形式输出错误。
+func (x *slimApiResponseWriter) writeFileResponse(state *webapi.ApiState, file *webapi.FileResponse) bool {
+	contentType, err := file.DetectContentType()
+	if err == nil {
+		err = checkSeekable(file.Content())
+	}
+
+	if err != nil {
+		state.Error = errx.Wrap("slimApiResponseWriter: file response", err)
+		return false
+	}
+
+	// http.ServeContent 需要自行处理 HTTP 状态码（如 206 、 304 、 416 ）及头部，故直接写入 RawResponse ，
+	// 迭代器本身不给出数据。 Content-Type 头由 CreateHandlerFunc 在迭代前设置。
+	state.ResponseContentType = contentType
+	state.ResponseBody = func(yield func([]byte) bool) {
+		file.ServeContent(state.RawResponse, state.RawRequest)
+	}
+	return true
+}
+
+// 检查 r 可以定位到末尾（ http.ServeContent 以此获取文件大小），之后重新定位到开头。
+func checkSeekable(r io.Seeker) error {
+	if _, err := r.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	_, err := r.Seek(0, io.SeekStart)
+	return err
+}
+
 func (x *slimApiResponseWriter) buildJsonResponse(state *webapi.ApiState, callResult any, callError error) []byte {
 	response := state.Handler.BuildResponse(state, callResult, callError)
 	if response == nil {
@@ -1,8 +1,12 @@
 package slimapi
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/cmstar/go-errx"
 	"github.com/cmstar/go-webapi"
 	"github.com/cmstar/go-webapi/webapitest"
 	"github.com/stretchr/testify/assert"
@@ -101,6 +105,36 @@ func Test_slimApiResponseWriter_WriteResponse(t *testing.T) {
 		})
 	})
 
+	t.Run("SseEvent", func(t *testing.T) {
+		testOne(args{
+			callData: webapi.EventStream[webapi.SseEvent[int]](func(yield func(data webapi.SseEvent[int], err error) bool) {
+				_ = yield(webapi.SseEvent[int]{Id: "1", Event: "progress", Retry: 1500 * time.Millisecond, Data: 1}, nil) &&
+					yield(webapi.SseEvent[int]{Id: "2\r\n", Data: 2}, nil) &&
+					yield(webapi.SseEvent[int]{Data: 3}, nil)
+			}),
+			wantBody: []string{
+				"id: 1\nevent: progress\nretry: 1500\n" + `data: {"Code":0,"Message":"","Data":1}` + "\n\n",
+				"id: 2\n" + `data: {"Code":0,"Message":"","Data":2}` + "\n\n",
+				`data: {"Code":0,"Message":"","Data":3}` + "\n\n",
+				`event: END` + "\n" + `data: {"Code":1000,"Message":"","Data":null}` + "\n\n",
+			},
+			wantPanicPattern: "",
+		})
+	})
+
+	t.Run("SseEvent-NdJson", func(t *testing.T) {
+		testOne(args{
+			callData: webapi.NdJson[webapi.SseEvent[string]](func(yield func(data webapi.SseEvent[string], err error) bool) {
+				yield(webapi.SseEvent[string]{Id: "1", Data: "a"}, nil)
+			}),
+			wantBody: []string{
+				`{"Code":0,"Message":"","Data":"a"}` + "\n",
+				"",
+			},
+			wantPanicPattern: "",
+		})
+	})
+
 	t.Run("NdJson", func(t *testing.T) {
 		testOne(args{
 			callData: webapi.NdJson[string](func(yield func(data string, err error) bool) {
@@ -120,3 +154,287 @@ func Test_slimApiResponseWriter_WriteResponse(t *testing.T) {
 		})
 	})
 }
+
+func Test_slimApiResponseWriter_jsonArray(t *testing.T) {
+	write := func(data any) []string {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, "/", webapitest.NewStateSetup{})
+		state.Data = data
+		state.Handler = &webapi.ApiHandlerWrapper{
+			ApiResponseBuilder: webapi.NewBasicApiResponseBuilder(),
+		}
+		NewSlimApiResponseWriter().WriteResponse(state)
+		require.Equal(t, webapi.ContentTypeJsonArrayStream, state.ResponseContentType)
+
+		var body []string
+		for block := range state.ResponseBody {
+			body = append(body, string(block))
+		}
+		return body
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		body := write(webapi.JsonArray[webapi.SseEvent[int]](func(yield func(webapi.SseEvent[int], error) bool) {
+			for i := 1; i <= 3; i++ {
+				if !yield(webapi.SseEvent[int]{Id: "x", Data: i}, nil) {
+					return
+				}
+			}
+		}))
+		require.Equal(t, []string{`{"Data":[`, `1`, `,2`, `,3`, `],"Code":0,"Message":""}`}, body)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		body := write(webapi.JsonArray[int](func(yield func(int, error) bool) {}))
+		require.Equal(t, `{"Data":[],"Code":0,"Message":""}`, strings.Join(body, ""))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		body := write(webapi.JsonArray[int](func(yield func(int, error) bool) {
+			if yield(1, nil) && yield(0, errx.NewBizError(2, "biz", nil)) {
+				yield(3, nil)
+			}
+		}))
+		require.Equal(t, `{"Data":[1],"Code":2,"Message":"biz"}`, strings.Join(body, ""))
+	})
+
+	t.Run("stop after error", func(t *testing.T) {
+		// 错误之后迭代立即停止，后续的数据不再被生产。
+		accepted := 0
+		body := write(webapi.JsonArray[int](func(yield func(int, error) bool) {
+			if !yield(0, errx.NewBizError(2, "biz", nil)) {
+				return
+			}
+			for i := 1; i <= 100; i++ {
+				if !yield(i, nil) {
+					return
+				}
+				accepted++
+			}
+		}))
+		require.Equal(t, `{"Data":[],"Code":2,"Message":"biz"}`, strings.Join(body, ""))
+		require.Zero(t, accepted)
+	})
+}
+
+func Test_slimApiResponseWriter_heartbeat(t *testing.T) {
+	newState := func(data any, heartbeat time.Duration) *webapi.ApiState {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, "/", webapitest.NewStateSetup{})
+		state.Data = data
+		state.Handler = &webapi.ApiHandlerWrapper{
+			ApiResponseBuilder: webapi.NewBasicApiResponseBuilder(),
+			StreamHeartbeat:    heartbeat,
+		}
+		NewSlimApiResponseWriter().WriteResponse(state)
+		return state
+	}
+
+	t.Run("idle", func(t *testing.T) {
+		state := newState(webapi.EventStream[int](func(yield func(int, error) bool) {
+			if yield(1, nil) {
+				time.Sleep(50 * time.Millisecond)
+				yield(2, nil)
+			}
+		}), 5*time.Millisecond)
+
+		var body []string
+		for block := range state.ResponseBody {
+			body = append(body, string(block))
+		}
+
+		require.Greater(t, len(body), 4)
+		require.Equal(t, `data: {"Code":0,"Message":"","Data":1}`+"\n\n", body[0])
+		require.Equal(t, ": heartbeat\n\n", body[1])
+		require.Equal(t, `data: {"Code":0,"Message":"","Data":2}`+"\n\n", body[len(body)-2])
+		require.Equal(t, `event: END`+"\n"+`data: {"Code":1000,"Message":"","Data":null}`+"\n\n", body[len(body)-1])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		state := newState(webapi.NdJson[int](func(yield func(int, error) bool) {
+			time.Sleep(20 * time.Millisecond)
+			yield(1, nil)
+		}), 0)
+
+		var body []string
+		for block := range state.ResponseBody {
+			body = append(body, string(block))
+		}
+		require.Equal(t, []string{`{"Code":0,"Message":"","Data":1}` + "\n", ""}, body)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		stopped := make(chan bool, 1)
+		state := newState(webapi.NdJson[int](func(yield func(int, error) bool) {
+			for i := 0; ; i++ {
+				if !yield(i, nil) {
+					stopped <- true
+					return
+				}
+			}
+		}), time.Second)
+
+		for range state.ResponseBody {
+			break
+		}
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the iterator is not stopped")
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		state := newState(webapi.NdJson[int](func(yield func(int, error) bool) {
+			panic("oops")
+		}), time.Second)
+
+		require.PanicsWithValue(t, "oops", func() {
+			for range state.ResponseBody {
+			}
+		})
+	})
+}
+
+func Test_slimApiResponseWriter_streamEnd(t *testing.T) {
+	newState := func(ctx context.Context, data any) *webapi.ApiState {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, "/", webapitest.NewStateSetup{})
+		state.RawRequest = state.RawRequest.WithContext(ctx)
+		state.Data = data
+		state.Handler = &webapi.ApiHandlerWrapper{
+			ApiResponseBuilder: webapi.NewBasicApiResponseBuilder(),
+		}
+		NewSlimApiResponseWriter().WriteResponse(state)
+		return state
+	}
+
+	// 从 LogMessage 中读取流式输出的统计信息。
+	streamLog := func(state *webapi.ApiState) (items int, end string) {
+		for i := 0; i+1 < len(state.LogMessage); i += 2 {
+			switch state.LogMessage[i] {
+			case "StreamItems":
+				items = state.LogMessage[i+1].(int)
+			case "StreamEnd":
+				end = state.LogMessage[i+1].(string)
+			case "StreamDuration":
+				require.IsType(t, time.Duration(0), state.LogMessage[i+1])
+			}
+		}
+		return
+	}
+
+	t.Run("completed", func(t *testing.T) {
+		state := newState(context.Background(), webapi.NdJson[int](func(yield func(int, error) bool) {
+			_ = yield(1, nil) && yield(2, nil)
+		}))
+
+		var body []string
+		for block := range state.ResponseBody {
+			body = append(body, string(block))
+		}
+		require.Len(t, body, 3)
+
+		items, end := streamLog(state)
+		require.Equal(t, 2, items)
+		require.Equal(t, "completed", end)
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		state := newState(context.Background(), webapi.NdJson[int](func(yield func(int, error) bool) {
+			_ = yield(1, nil) && yield(2, nil)
+		}))
+
+		for range state.ResponseBody {
+			break
+		}
+
+		items, end := streamLog(state)
+		require.Equal(t, 1, items)
+		require.Equal(t, "aborted", end)
+	})
+
+	t.Run("aborted-blocked", func(t *testing.T) {
+		// 输出失败后，请求的 context 被取消，阻塞在其上的迭代器得以结束。
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, "/", webapitest.NewStateSetup{})
+		ctx := state.RawRequest.Context()
+		state.Data = webapi.EventStream[int](func(yield func(int, error) bool) {
+			if yield(1, nil) {
+				<-ctx.Done()
+			}
+		})
+		state.Handler = &webapi.ApiHandlerWrapper{ApiResponseBuilder: webapi.NewBasicApiResponseBuilder()}
+		NewSlimApiResponseWriter().WriteResponse(state)
+
+		for range state.ResponseBody {
+			break
+		}
+
+		_, end := streamLog(state)
+		require.Equal(t, "aborted", end)
+		require.Error(t, ctx.Err())
+	})
+
+	t.Run("item-panic", func(t *testing.T) {
+		// 数据不能被 JSON 序列化时 onItem panic ，迭代器仍被结束，其中的 defer 在 panic 传出前执行。
+		cleaned := false
+		state := newState(context.Background(), webapi.NdJson[any](func(yield func(any, error) bool) {
+			defer func() { cleaned = true }()
+
+			if yield(func() {}, nil) {
+				yield(2, nil)
+			}
+		}))
+
+		require.Panics(t, func() {
+			for range state.ResponseBody {
+			}
+		})
+		require.True(t, cleaned)
+
+		_, end := streamLog(state)
+		require.Equal(t, "panic", end)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cleaned := false
+		state := newState(ctx, webapi.EventStream[int](func(yield func(int, error) bool) {
+			defer func() { cleaned = true }()
+
+			if !yield(1, nil) {
+				return
+			}
+			<-ctx.Done()
+			yield(2, nil)
+		}))
+
+		var body []string
+		for block := range state.ResponseBody {
+			body = append(body, string(block))
+			cancel()
+		}
+
+		// 不输出最终块；迭代器执行完毕后才返回。
+		require.Equal(t, []string{`data: {"Code":0,"Message":"","Data":1}` + "\n\n"}, body)
+		require.True(t, cleaned)
+
+		items, end := streamLog(state)
+		require.Equal(t, 1, items)
+		require.Equal(t, "canceled", end)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		state := newState(context.Background(), webapi.NdJson[int](func(yield func(int, error) bool) {
+			yield(1, nil)
+			panic("oops")
+		}))
+
+		require.Panics(t, func() {
+			for range state.ResponseBody {
+			}
+		})
+
+		_, end := streamLog(state)
+		require.Equal(t, "panic", end)
+	})
+}
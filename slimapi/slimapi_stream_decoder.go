@@ -0,0 +1,316 @@
+package slimapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"mime/multipart"
+	"reflect"
+	"strings"
+
+	"github.com/cmstar/go-webapi"
+)
+
+// MultipartParts 是 multipart/form-data 请求的各个分部（ part ）的迭代器，可作为方法的参数，见 [StreamArgumentDecoder] 。
+// 迭代中出现错误时，给出 nil 和错误，随后迭代结束。
+// 每个 part 的数据需在获取下一个 part 之前读取，获取下一个 part 时，未读取的数据被丢弃。
+type MultipartParts = iter.Seq2[*multipart.Part, error]
+
+// StreamArgumentDecoder 是一个 [webapi.ArgumentDecoder] ，用于以流的方式读取请求的 body ，
+// 使大文件等数据可以直接写入存储，而不必先读入内存或临时文件。支持以下类型的参数：
+//   - [io.Reader] 请求的原始 body 。
+//   - [*multipart.Reader] multipart/form-data 请求的 body ，请求不是此格式时返回错误。
+//   - [MultipartParts] multipart/form-data 请求的各个 part ，请求不是此格式时返回错误。
+//   - iter.Seq2[T, error] 请求 body 中的每一项，见下文。
+//
+// iter.Seq2[T, error] （ T 不是 *multipart.Part ）用于接收 Content-Type 为 application/x-ndjson 或 text/event-stream 的 body ，
+// 前者每行、后者每个事件的 data 是一项 JSON ，在迭代时逐项读取，并使用 [Conv] 转换为 T ，使大量的数据不必全部读入内存。
+// Content-Type 不是这两者时返回错误。读取或转换出错时，给出 T 的零值和 [webapi.BadRequestError] ，随后迭代结束；
+// body 超过大小限制时，错误的 Code 为 [webapi.ErrorCodeRequestEntityTooLarge] 。 body 只能被读取一次，故只能迭代一次。
+//
+// 方法的参数表中有上述类型的参数时， [StructArgumentDecoder] 和 [NamedArgumentDecoder] 不再读取 body ，
+// 其他参数仅从 URL 上的 query 和路由参数中读取，即如同 GET 请求。一个方法至多有一个此类参数。
+//
+// 日志中不记录 body 的内容；对于 [MultipartParts] ，记录已读取的 part 的名称、文件名和 Content-Type ；
+// 对于 iter.Seq2[T, error] ，记录已读取的项数。
+//
+// 这是一个单例。
+var StreamArgumentDecoder = slimApiMethodStreamArgDecoder{}
+
+var (
+	typeIoReader        = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	typeMultipartReader = reflect.TypeOf((*multipart.Reader)(nil))
+	typeMultipartParts  = reflect.TypeOf(MultipartParts(nil))
+)
+
+type slimApiMethodStreamArgDecoder struct{}
+
+// DecodeArg implements [webapi.ApiDecoder.DecodeArg].
+func (d slimApiMethodStreamArgDecoder) DecodeArg(state *webapi.ApiState, index int, argType reflect.Type) (ok bool, v any, err error) {
+	if !isStreamArgType(argType) {
+		return false, nil, nil
+	}
+
+	if _, ok := state.GetCustomData(customData_BodyStream); ok {
+		webapi.PanicApiError(state, nil, "method '%s' arg%d %v: only one streaming argument is allowed", state.Name, index, argType)
+	}
+	state.SetCustomData(customData_BodyStream, true)
+
+	if itemType, ok := streamItemType(argType); ok {
+		return d.decodeItems(state, argType, itemType)
+	}
+
+	req := state.RawRequest
+	if argType == typeIoReader {
+		setRequestBodyDescription(state, "(stream)")
+		return true, req.Body, nil
+	}
+
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return false, nil, webapi.CreateBadRequestError(state, err, "bad request")
+	}
+
+	if argType == typeMultipartReader {
+		setRequestBodyDescription(state, "(multipart stream)")
+		return true, mr, nil
+	}
+
+	desc := &streamPartsDescription{}
+	setRequestBodyDescription(state, desc)
+
+	var parts MultipartParts = func(yield func(*multipart.Part, error) bool) {
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			desc.Parts = append(desc.Parts, streamPartDescription{
+				Name:        p.FormName(),
+				FileName:    p.FileName(),
+				ContentType: p.Header.Get(webapi.HttpHeaderContentType),
+			})
+
+			if !yield(p, nil) {
+				return
+			}
+		}
+	}
+	return true, parts, nil
+}
+
+// 读取 NDJSON 或 SSE 格式的 body ，返回 argType （形如 iter.Seq2[itemType, error] ）的迭代器。
+func (d slimApiMethodStreamArgDecoder) decodeItems(state *webapi.ApiState, argType, itemType reflect.Type) (ok bool, v any, err error) {
+	contentType := state.RawRequest.Header.Get(webapi.HttpHeaderContentType)
+	contentType, _, _ = strings.Cut(contentType, ";")
+	contentType = strings.TrimSpace(contentType)
+
+	br := bufio.NewReader(state.RawRequest.Body)
+	var next func() ([]byte, error)
+	switch contentType {
+	case webapi.ContentTypeNdJson:
+		next = func() ([]byte, error) { return nextNdJsonItem(br) }
+	case webapi.ContentTypeEventStream:
+		next = func() ([]byte, error) { return nextSseItem(br) }
+	default:
+		err = fmt.Errorf("Content-Type must be %s or %s", webapi.ContentTypeNdJson, webapi.ContentTypeEventStream)
+		return false, nil, webapi.CreateBadRequestError(state, err, "bad request")
+	}
+
+	desc := &streamItemsDescription{ContentType: contentType}
+	setRequestBodyDescription(state, desc)
+
+	fn := reflect.MakeFunc(argType, func(args []reflect.Value) []reflect.Value {
+		yield := args[0]
+		for {
+			raw, err := next()
+			if err == io.EOF {
+				return nil
+			}
+
+			var item reflect.Value
+			if err == nil {
+				item, err = convertStreamItem(raw, itemType)
+			}
+
+			if err != nil {
+				if e, ok := webapi.AsBodyTooLargeError(state, err); ok {
+					err = e
+				} else {
+					err = webapi.CreateBadRequestError(state, err, "bad item %d", desc.Count+1)
+				}
+				yield.Call([]reflect.Value{reflect.Zero(itemType), reflect.ValueOf(&err).Elem()})
+				return nil
+			}
+
+			desc.Count++
+			if !yield.Call([]reflect.Value{item, reflect.Zero(typeError)})[0].Bool() {
+				return nil
+			}
+		}
+	})
+	return true, fn.Interface(), nil
+}
+
+// 将一项 JSON 转换为 typ 的值。
+func convertStreamItem(raw []byte, typ reflect.Type) (reflect.Value, error) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return reflect.Value{}, err
+	}
+
+	res, err := Conv.ConvertType(v, typ)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if res == nil {
+		return reflect.Zero(typ), nil
+	}
+	return reflect.ValueOf(res), nil
+}
+
+// 读取 NDJSON 的下一行，跳过空行。没有更多数据时返回 [io.EOF] 。
+func nextNdJsonItem(br *bufio.Reader) ([]byte, error) {
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+	}
+}
+
+// 读取 SSE 的下一个有 data 的事件，返回其 data ，多行的 data 使用换行拼接；其他字段被忽略。没有更多数据时返回 [io.EOF] 。
+func nextSseItem(br *bufio.Reader) ([]byte, error) {
+	var data []byte
+	hasData := false
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line = strings.TrimRight(strings.TrimRight(line, "\n"), "\r")
+		if name, value, _ := strings.Cut(line, ":"); name == "data" {
+			if hasData {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimSpace(value)...)
+			hasData = true
+		}
+
+		// 空行表示事件结束；流没有以空行结束时，仍处理最后一个事件。
+		if (line == "" || err == io.EOF) && hasData {
+			return data, nil
+		}
+
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+	}
+}
+
+func isStreamArgType(typ reflect.Type) bool {
+	if typ == typeIoReader || typ == typeMultipartReader || typ == typeMultipartParts {
+		return true
+	}
+
+	_, ok := streamItemType(typ)
+	return ok
+}
+
+// 若 typ 形如 iter.Seq2[T, error] （不含 [MultipartParts] ），返回 T 和 true 。
+func streamItemType(typ reflect.Type) (reflect.Type, bool) {
+	if typ == typeMultipartParts || typ.Kind() != reflect.Func || typ.NumIn() != 1 || typ.NumOut() != 0 {
+		return nil, false
+	}
+
+	yield := typ.In(0)
+	if yield.Kind() != reflect.Func || yield.NumIn() != 2 || yield.NumOut() != 1 ||
+		yield.In(1) != typeError || yield.Out(0).Kind() != reflect.Bool {
+		return nil, false
+	}
+	return yield.In(0), true
+}
+
+// 判断方法的参数表中是否有 [StreamArgumentDecoder] 支持的参数。
+func hasStreamArg(m webapi.ApiMethod) bool {
+	if !m.Value.IsValid() {
+		return false
+	}
+
+	return requestStreamContentType(m.Value.Type()) != ""
+}
+
+// 返回方法以流的方式读取的 body 的 Content-Type ：参数为 [io.Reader] 时为 application/octet-stream ，
+// 为 [*multipart.Reader] 或 [MultipartParts] 时为 multipart/form-data ，为 iter.Seq2[T, error] 时为 application/x-ndjson 。
+// 没有这类参数时返回空字符串。
+func requestStreamContentType(methodType reflect.Type) string {
+	for i := 0; i < methodType.NumIn(); i++ {
+		in := methodType.In(i)
+		switch in {
+		case typeIoReader:
+			return webapi.ContentTypeBinary
+		case typeMultipartReader, typeMultipartParts:
+			return webapi.ContentTypeMultipartForm
+		}
+
+		if _, ok := streamItemType(in); ok {
+			return webapi.ContentTypeNdJson
+		}
+	}
+	return ""
+}
+
+// 用于在日志中记录 [MultipartParts] 已读取的 part 。
+type streamPartsDescription struct {
+	Parts []streamPartDescription
+}
+
+type streamPartDescription struct {
+	Name        string
+	FileName    string `json:",omitempty"`
+	ContentType string `json:",omitempty"`
+}
+
+// String 实现 fmt.Stringer 。
+func (x *streamPartsDescription) String() string {
+	if len(x.Parts) == 0 {
+		return "(multipart stream)"
+	}
+
+	b, err := json.Marshal(x.Parts)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// 用于在日志中记录 iter.Seq2[T, error] 已读取的项数。
+type streamItemsDescription struct {
+	ContentType string
+	Count       int
+}
+
+// String 实现 fmt.Stringer 。
+func (x *streamItemsDescription) String() string {
+	return fmt.Sprintf("(%s stream, %d items)", x.ContentType, x.Count)
+}
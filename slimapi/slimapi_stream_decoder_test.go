@@ -0,0 +1,236 @@
+package slimapi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/cmstar/go-webapi/webapitest"
+	"github.com/stretchr/testify/require"
+)
+
+// 使用 SlimAPI 的 decoder 解析 fn 的参数。
+func streamDecoderTestDecode(t *testing.T, fn any, url string, setup webapitest.NewStateSetup) *webapi.ApiState {
+	state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, url, setup)
+	setRequestFormat(state, meta_RequestFormat_Post)
+	state.Method = webapi.ApiMethod{Value: reflect.ValueOf(fn)}
+	NewSlimApiDecoder().Decode(state)
+	return state
+}
+
+func streamDecoderTestMultipart(t *testing.T) (body string, contentType string) {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+	require.NoError(t, w.WriteField("Name", "b"))
+
+	f, err := w.CreateFormFile("file", "a.txt")
+	require.NoError(t, err)
+	f.Write([]byte("content"))
+
+	require.NoError(t, w.Close())
+	return buf.String(), w.FormDataContentType()
+}
+
+func TestStreamArgumentDecoder(t *testing.T) {
+	t.Run("reader", func(t *testing.T) {
+		state := streamDecoderTestDecode(t,
+			func(io.Reader, struct{ Name string }) {},
+			urlBase+"?name=a",
+			webapitest.NewStateSetup{
+				HttpMethod:  http.MethodPost,
+				ContentType: webapi.ContentTypeJson,
+				BodyString:  `{"Name":"x"}`,
+			})
+		require.Nil(t, state.Error)
+		require.Len(t, state.Args, 2)
+
+		// body 交给了 io.Reader ，其他参数仅来自 query 。
+		b, err := io.ReadAll(state.Args[0].Interface().(io.Reader))
+		require.NoError(t, err)
+		require.Equal(t, `{"Name":"x"}`, string(b))
+		require.Equal(t, struct{ Name string }{"a"}, state.Args[1].Interface())
+		require.Equal(t, "(stream)", getRequestBodyDescription(state))
+	})
+
+	t.Run("multipart-reader", func(t *testing.T) {
+		body, contentType := streamDecoderTestMultipart(t)
+		state := streamDecoderTestDecode(t,
+			func(*multipart.Reader) {},
+			urlBase,
+			webapitest.NewStateSetup{HttpMethod: http.MethodPost, ContentType: contentType, BodyString: body})
+		require.Nil(t, state.Error)
+
+		mr := state.Args[0].Interface().(*multipart.Reader)
+		p, err := mr.NextPart()
+		require.NoError(t, err)
+		require.Equal(t, "Name", p.FormName())
+		require.Equal(t, "(multipart stream)", getRequestBodyDescription(state))
+	})
+
+	t.Run("parts", func(t *testing.T) {
+		body, contentType := streamDecoderTestMultipart(t)
+		state := streamDecoderTestDecode(t,
+			func(MultipartParts, struct{ Name string }) {},
+			urlBase+"?name=a",
+			webapitest.NewStateSetup{HttpMethod: http.MethodPost, ContentType: contentType, BodyString: body})
+		require.Nil(t, state.Error)
+		require.Equal(t, struct{ Name string }{"a"}, state.Args[1].Interface())
+		require.Equal(t, "(multipart stream)", getRequestBodyDescription(state))
+
+		var contents []string
+		for p, err := range state.Args[0].Interface().(MultipartParts) {
+			require.NoError(t, err)
+			b, _ := io.ReadAll(p)
+			contents = append(contents, p.FormName()+"="+string(b))
+		}
+		require.Equal(t, []string{"Name=b", "file=content"}, contents)
+		require.Equal(t,
+			`[{"Name":"Name"},{"Name":"file","FileName":"a.txt","ContentType":"application/octet-stream"}]`,
+			getRequestBodyDescription(state))
+	})
+
+	t.Run("parts-break", func(t *testing.T) {
+		body, contentType := streamDecoderTestMultipart(t)
+		state := streamDecoderTestDecode(t,
+			func(MultipartParts) {},
+			urlBase,
+			webapitest.NewStateSetup{HttpMethod: http.MethodPost, ContentType: contentType, BodyString: body})
+
+		n := 0
+		for range state.Args[0].Interface().(MultipartParts) {
+			n++
+			break
+		}
+		require.Equal(t, 1, n)
+		require.Equal(t, `[{"Name":"Name"}]`, getRequestBodyDescription(state))
+	})
+
+	t.Run("parts-error", func(t *testing.T) {
+		_, contentType := streamDecoderTestMultipart(t)
+		state := streamDecoderTestDecode(t,
+			func(MultipartParts) {},
+			urlBase,
+			webapitest.NewStateSetup{HttpMethod: http.MethodPost, ContentType: contentType, BodyString: "broken"})
+
+		var errs []error
+		for p, err := range state.Args[0].Interface().(MultipartParts) {
+			require.Nil(t, p)
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		require.Error(t, errs[0])
+	})
+
+	t.Run("not-multipart", func(t *testing.T) {
+		state := streamDecoderTestDecode(t,
+			func(MultipartParts) {},
+			urlBase,
+			webapitest.NewStateSetup{HttpMethod: http.MethodPost, ContentType: webapi.ContentTypeJson, BodyString: "{}"})
+		require.NotNil(t, state.Error)
+
+		var e webapi.BadRequestError
+		require.True(t, errors.As(state.Error, &e))
+		require.Nil(t, state.Args)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		state := streamDecoderTestDecode(t,
+			func(iter.Seq2[struct{ A int }, error], struct{ Name string }) {},
+			urlBase+"?name=a",
+			webapitest.NewStateSetup{
+				HttpMethod:  http.MethodPost,
+				ContentType: webapi.ContentTypeNdJson,
+				BodyString:  "{\"A\":1}\n\n{\"a\":\"2\"}\n{\"A\":3}",
+			})
+		require.Nil(t, state.Error)
+		require.Equal(t, struct{ Name string }{"a"}, state.Args[1].Interface())
+		require.Equal(t, "(application/x-ndjson stream, 0 items)", getRequestBodyDescription(state))
+
+		var items []int
+		for v, err := range state.Args[0].Interface().(iter.Seq2[struct{ A int }, error]) {
+			require.NoError(t, err)
+			items = append(items, v.A)
+		}
+		require.Equal(t, []int{1, 2, 3}, items)
+		require.Equal(t, "(application/x-ndjson stream, 3 items)", getRequestBodyDescription(state))
+	})
+
+	t.Run("sse", func(t *testing.T) {
+		state := streamDecoderTestDecode(t,
+			func(iter.Seq2[[]int, error]) {},
+			urlBase,
+			webapitest.NewStateSetup{
+				HttpMethod:  http.MethodPost,
+				ContentType: webapi.ContentTypeEventStream + "; charset=utf-8",
+				BodyString:  ": comment\n\nid: 1\ndata: [1,\ndata: 2]\n\nevent: x\ndata: [3]",
+			})
+		require.Nil(t, state.Error)
+
+		var items [][]int
+		for v, err := range state.Args[0].Interface().(iter.Seq2[[]int, error]) {
+			require.NoError(t, err)
+			items = append(items, v)
+		}
+		require.Equal(t, [][]int{{1, 2}, {3}}, items)
+	})
+
+	t.Run("bad-item", func(t *testing.T) {
+		state := streamDecoderTestDecode(t,
+			func(iter.Seq2[int, error]) {},
+			urlBase,
+			webapitest.NewStateSetup{HttpMethod: http.MethodPost, ContentType: webapi.ContentTypeNdJson, BodyString: "1\nx\n3"})
+		require.Nil(t, state.Error)
+
+		var items []int
+		var errs []error
+		for v, err := range state.Args[0].Interface().(iter.Seq2[int, error]) {
+			items = append(items, v)
+			errs = append(errs, err)
+		}
+		require.Equal(t, []int{1, 0}, items)
+		require.NoError(t, errs[0])
+
+		var e webapi.BadRequestError
+		require.True(t, errors.As(errs[1], &e))
+		require.Equal(t, "bad item 2", e.Message)
+	})
+
+	t.Run("items-break", func(t *testing.T) {
+		state := streamDecoderTestDecode(t,
+			func(iter.Seq2[int, error]) {},
+			urlBase,
+			webapitest.NewStateSetup{HttpMethod: http.MethodPost, ContentType: webapi.ContentTypeNdJson, BodyString: "1\n2\n3"})
+
+		for range state.Args[0].Interface().(iter.Seq2[int, error]) {
+			break
+		}
+		require.Equal(t, "(application/x-ndjson stream, 1 items)", getRequestBodyDescription(state))
+	})
+
+	t.Run("not-ndjson", func(t *testing.T) {
+		state := streamDecoderTestDecode(t,
+			func(iter.Seq2[int, error]) {},
+			urlBase,
+			webapitest.NewStateSetup{HttpMethod: http.MethodPost, ContentType: webapi.ContentTypeJson, BodyString: "[1]"})
+
+		var e webapi.BadRequestError
+		require.True(t, errors.As(state.Error, &e))
+		require.Nil(t, state.Args)
+	})
+
+	t.Run("more-than-one", func(t *testing.T) {
+		require.PanicsWithError(t, "method '' arg1 *multipart.Reader: only one streaming argument is allowed", func() {
+			streamDecoderTestDecode(t,
+				func(io.Reader, *multipart.Reader) {},
+				urlBase,
+				webapitest.NewStateSetup{HttpMethod: http.MethodPost, BodyString: strings.Repeat("x", 3)})
+		})
+	})
+}
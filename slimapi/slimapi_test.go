@@ -1,11 +1,17 @@
 package slimapi
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"net/textproto"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -229,6 +235,31 @@ func TestSlimApi_SumAndShowMap_multipart(t *testing.T) {
 	})
 }
 
+func TestSlimApi_UploadFiles_multipart(t *testing.T) {
+	buf := new(strings.Builder)
+	w := multipart.NewWriter(buf)
+
+	for i, name := range []string{"files", "files", "headers", "data", "data", "last", "last"} {
+		p, _ := w.CreateFormFile(name, fmt.Sprintf("%s%d", name, i))
+		p.Write([]byte(strconv.Itoa(i)))
+	}
+	w.Close()
+
+	DoIntegrationTest(t, integrationTestArgs{
+		requestRelativeUrl: "?UploadFiles",
+		requestContentType: w.FormDataContentType(),
+		requestBody:        buf.String(),
+		requestRouteParam:  map[string]string{},
+		wantStatusCode:     200,
+		wantContentType:    webapi.ContentTypeJson,
+		wantBody:           `{"Code":0,"Message":"","Data":"Files:files0,files1, Headers:1, Data:[\"3\" \"4\"], Last:last6"}`,
+		wantLogPattern: map[string]string{
+			"Body": regexp.QuoteMeta(`"files":[{"$FileName":"files0","ContentType":"application/octet-stream","Size":1},` +
+				`{"$FileName":"files1","ContentType":"application/octet-stream","Size":1}]`),
+		},
+	})
+}
+
 func TestSlimApi_SumAndShowMap_decodeError(t *testing.T) {
 	DoIntegrationTest(t, integrationTestArgs{
 		requestRelativeUrl: "?SumAndShowMap.json",
@@ -245,6 +276,131 @@ func TestSlimApi_SumAndShowMap_decodeError(t *testing.T) {
 	})
 }
 
+func TestSlimApi_Validate(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		DoIntegrationTest(t, integrationTestArgs{
+			requestRelativeUrl: "?Validate",
+			requestContentType: "application/json",
+			requestBody:        `{ "Name":"abc", "Items":[{"Code":"x"}] }`,
+			requestRouteParam:  map[string]string{},
+			wantStatusCode:     200,
+			wantContentType:    webapi.ContentTypeJson,
+			wantBody:           `{"Code":0,"Message":"","Data":"abc"}`,
+		})
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		DoIntegrationTest(t, integrationTestArgs{
+			requestRelativeUrl: "?Validate",
+			requestContentType: "application/json",
+			requestBody:        `{ "Items":[{"Code":"x"},{}] }`,
+			requestRouteParam:  map[string]string{},
+			wantStatusCode:     200,
+			wantContentType:    webapi.ContentTypeJson,
+			wantBody: `{"Code":400,"Message":"Name is required; Items[1].Code is required","Data":[` +
+				`{"Field":"Name","Rule":"required","Param":"","Message":"Name is required"},` +
+				`{"Field":"Items[1].Code","Rule":"required","Param":"","Message":"Items[1].Code is required"}]}`,
+			wantLogPattern: map[string]string{
+				"ErrorType": "BadRequestError",
+			},
+		})
+	})
+}
+
+func TestSlimApi_ValidateOptional(t *testing.T) {
+	do := func(body, wantBody string) {
+		t.Helper()
+		DoIntegrationTest(t, integrationTestArgs{
+			requestRelativeUrl: "?ValidateOptional",
+			requestContentType: "application/json",
+			requestBody:        body,
+			requestRouteParam:  map[string]string{},
+			wantStatusCode:     200,
+			wantContentType:    webapi.ContentTypeJson,
+			wantBody:           wantBody,
+			wantLogPattern:     map[string]string{},
+		})
+	}
+
+	nameRequired := `{"Code":400,"Message":"Name is required","Data":[{"Field":"Name","Rule":"required","Param":"","Message":"Name is required"}]}`
+
+	// 未给出或为 null 时， required 不通过，其他规则被跳过。
+	do(`{}`, nameRequired)
+	do(`{"Name":null,"Age":null}`, nameRequired)
+
+	// 给出了零值时， required 通过，其他规则照常执行。
+	do(`{"Name":""}`, `{"Code":0,"Message":"","Data":""}`)
+	do(`{"Name":"a","Age":0}`, `{"Code":400,"Message":"Age must be at least 1","Data":[{"Field":"Age","Rule":"min","Param":"1","Message":"Age must be at least 1"}]}`)
+	do(`{"Name":"a","Age":1}`, `{"Code":0,"Message":"","Data":"a"}`)
+}
+
+func TestSlimApi_MaxBodySize(t *testing.T) {
+	h := NewSlimApiHandler("")
+	h.MaxBodySize = 20
+	h.RegisterMethods(integrationTestMethodProvider{})
+
+	engine := webapi.NewEngine()
+	engine.Handle("/", h, nil).SetMaxBodySize("SumAndShowMap", 100)
+
+	do := func(method, contentType, body string) string {
+		r := httptest.NewRequest(http.MethodPost, "/?"+method, strings.NewReader(body))
+		r.Header.Set(webapi.HttpHeaderContentType, contentType)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, r)
+		return rec.Body.String()
+	}
+
+	t.Run("handler", func(t *testing.T) {
+		require.Equal(t, `{"Code":0,"Message":"","Data":3}`, do("Plus", webapi.ContentTypeForm, "a=1&b=2"))
+		require.Equal(t, `{"Code":413,"Message":"request body too large","Data":null}`,
+			do("Plus", webapi.ContentTypeJson, `{"A":1,"B":2,"C":"0123456789"}`))
+	})
+
+	t.Run("method", func(t *testing.T) {
+		require.Equal(t, `{"Code":0,"Message":"","Data":{"Sum":3,"M":null}}`,
+			do("SumAndShowMap", webapi.ContentTypeJson, `{"S":[1,2],"C":"0123456789"}`))
+		require.Equal(t, `{"Code":413,"Message":"request body too large","Data":null}`,
+			do("SumAndShowMap", webapi.ContentTypeForm, "s=1&c="+strings.Repeat("x", 100)))
+	})
+
+	// 设置的上限可以超过 maxMemorySizeParseRequestBody 。
+	t.Run("above-in-memory", func(t *testing.T) {
+		h := NewSlimApiHandler("")
+		h.MaxBodySize = 2 * maxMemorySizeParseRequestBody
+		h.RegisterMethods(integrationTestMethodProvider{})
+
+		engine := webapi.NewEngine()
+		engine.Handle("/", h, nil)
+
+		body := `{"A":1,"B":2,"C":"` + strings.Repeat("x", maxMemorySizeParseRequestBody) + `"}`
+		r := httptest.NewRequest(http.MethodPost, "/?Plus", strings.NewReader(body))
+		r.Header.Set(webapi.HttpHeaderContentType, webapi.ContentTypeJson)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, r)
+		require.Equal(t, `{"Code":0,"Message":"","Data":3}`, rec.Body.String())
+	})
+
+	// 没有设置上限时，不限制 body 的大小，表单和 JSON 被整个读取，而不是截断。
+	t.Run("unlimited", func(t *testing.T) {
+		for _, contentType := range []string{webapi.ContentTypeForm, webapi.ContentTypeJson} {
+			body := "a=1&b=2&c=" + strings.Repeat("x", maxMemorySizeParseRequestBody)
+			if contentType == webapi.ContentTypeJson {
+				body = `{"A":1,"C":"` + strings.Repeat("x", maxMemorySizeParseRequestBody) + `","B":2}`
+			}
+
+			DoIntegrationTest(t, integrationTestArgs{
+				requestRelativeUrl: "?Plus",
+				requestContentType: contentType,
+				requestBody:        body,
+				requestRouteParam:  map[string]string{},
+				wantStatusCode:     200,
+				wantContentType:    webapi.ContentTypeJson,
+				wantBody:           `{"Code":0,"Message":"","Data":3}`,
+			})
+		}
+	})
+}
+
 func TestSlimApi_Time_json(t *testing.T) {
 	DoIntegrationTest(t, integrationTestArgs{
 		requestRelativeUrl: "?Time",
@@ -416,6 +572,23 @@ func TestSlimApi_NdJsonWithError(t *testing.T) {
 	})
 }
 
+func TestSlimApi_JsonArrayWithError(t *testing.T) {
+	DoIntegrationTest(t, integrationTestArgs{
+		requestRelativeUrl: "?JsonArrayWithError",
+		requestContentType: "",
+		requestBody:        "",
+		requestRouteParam:  map[string]string{},
+		wantStatusCode:     200,
+		wantContentType:    webapi.ContentTypeJsonArrayStream,
+		wantBody:           `{"Data":["a","b"],"Code":500,"Message":"internal error"}`,
+		wantLogPattern: map[string]string{
+			"level":     "ERROR",
+			"ErrorType": "errorString",
+			"Error":     `msg`,
+		},
+	})
+}
+
 func (integrationTestMethodProvider) Empty() {}
 
 type PlusRequest struct {
@@ -483,6 +656,26 @@ func (integrationTestMethodProvider) ShowError(args ShowErrorRequest) (s string,
 	}
 }
 
+type ValidateRequest struct {
+	Name  string `validate:"required,max=10"`
+	Items []struct {
+		Code string `validate:"required"`
+	} `validate:"max=2"`
+}
+
+func (integrationTestMethodProvider) Validate(req ValidateRequest) string {
+	return req.Name
+}
+
+type ValidateOptionalRequest struct {
+	Name Optional[string] `validate:"required"`
+	Age  Optional[int]    `validate:"min=1"`
+}
+
+func (integrationTestMethodProvider) ValidateOptional(req ValidateOptionalRequest) string {
+	return req.Name.Value()
+}
+
 type CannotDecodeRequest struct {
 	C chan int
 }
@@ -515,6 +708,20 @@ func (integrationTestMethodProvider) UploadFile(req struct {
 	return fmt.Sprintf("A:%s, B:%d, C:%s, Sum:%d", req.A.Filename, len(req.B), req.C, sum)
 }
 
+func (integrationTestMethodProvider) UploadFiles(req struct {
+	Files   []*FilePart
+	Headers []*multipart.FileHeader
+	Data    [][]byte
+	Last    *FilePart
+}) string {
+	var names []string
+	for _, f := range req.Files {
+		names = append(names, f.Filename)
+	}
+	return fmt.Sprintf("Files:%s, Headers:%d, Data:%q, Last:%s",
+		strings.Join(names, ","), len(req.Headers), req.Data, req.Last.Filename)
+}
+
 // SSE 按间隔时间输出，并在最后输出一段错误。
 func (integrationTestMethodProvider) ServerSendEventWithError() webapi.EventStream[string] {
 	return func(yield func(data string, err error) bool) {
@@ -544,3 +751,228 @@ func (integrationTestMethodProvider) NdJsonWithError() webapi.NdJson[string] {
 		yield("error data", errors.New("msg"))
 	}
 }
+
+// JsonArray 按间隔时间输出，并在最后输出一段错误。
+func (integrationTestMethodProvider) JsonArrayWithError() webapi.JsonArray[string] {
+	return func(yield func(data string, err error) bool) {
+		for _, v := range []string{"a", "b"} {
+			if !yield(v, nil) {
+				return
+			}
+
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		yield("error data", errors.New("msg"))
+		yield("ignored", nil)
+	}
+}
+
+type fileResponseTestProvider struct {
+	closed *bool
+}
+
+func (p fileResponseTestProvider) Download(req struct{ Name string }) (*webapi.FileResponse, error) {
+	switch req.Name {
+	case "":
+		return nil, errx.NewBizError(1, "missing name", nil)
+	case "unseekable":
+		return webapi.NewFileResponse("a.txt", unseekableReader{}), nil
+	}
+
+	f := webapi.NewFileResponse(req.Name, closeRecorder{strings.NewReader("0123456789"), p.closed})
+	f.ModTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	return f, nil
+}
+
+type closeRecorder struct {
+	*strings.Reader
+	closed *bool
+}
+
+func (x closeRecorder) Close() error {
+	*x.closed = true
+	return nil
+}
+
+type unseekableReader struct{}
+
+func (unseekableReader) Read(p []byte) (int, error) { return 0, io.EOF }
+
+func (unseekableReader) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("cannot seek")
+}
+
+func TestSlimApi_FileResponse(t *testing.T) {
+	var closed bool
+	h := NewSlimApiHandler("")
+	h.RegisterMethods(fileResponseTestProvider{&closed})
+
+	engine := webapi.NewEngine()
+	engine.Handle("/", h, nil)
+
+	do := func(query string, header map[string]string) *httptest.ResponseRecorder {
+		closed = false
+		r := httptest.NewRequest(http.MethodGet, "/?Download&"+query, nil)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, r)
+		return rec
+	}
+
+	t.Run("full", func(t *testing.T) {
+		rec := do("name=a.txt", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "0123456789", rec.Body.String())
+		require.Equal(t, "text/plain; charset=utf-8", rec.Header().Get(webapi.HttpHeaderContentType))
+		require.Equal(t, `attachment; filename=a.txt`, rec.Header().Get(webapi.HttpHeaderContentDisposition))
+		require.Equal(t, "10", rec.Header().Get("Content-Length"))
+		require.Equal(t, "Thu, 02 Jan 2020 03:04:05 GMT", rec.Header().Get("Last-Modified"))
+		require.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+		require.True(t, closed)
+	})
+
+	t.Run("non-ascii-name", func(t *testing.T) {
+		rec := do("name=%E6%96%87%E4%BB%B6.bin", nil)
+		require.Equal(t, "application/octet-stream", rec.Header().Get(webapi.HttpHeaderContentType))
+		require.Equal(t, `attachment; filename*=utf-8''%E6%96%87%E4%BB%B6.bin`, rec.Header().Get(webapi.HttpHeaderContentDisposition))
+	})
+
+	t.Run("range", func(t *testing.T) {
+		rec := do("name=a.txt", map[string]string{"Range": "bytes=2-5"})
+		require.Equal(t, http.StatusPartialContent, rec.Code)
+		require.Equal(t, "2345", rec.Body.String())
+		require.Equal(t, "bytes 2-5/10", rec.Header().Get("Content-Range"))
+		require.True(t, closed)
+
+		rec = do("name=a.txt", map[string]string{"Range": "bytes=20-"})
+		require.Equal(t, http.StatusRequestedRangeNotSatisfiable, rec.Code)
+	})
+
+	t.Run("if-range", func(t *testing.T) {
+		rec := do("name=a.txt", map[string]string{"Range": "bytes=2-5", "If-Range": "Thu, 02 Jan 2020 03:04:05 GMT"})
+		require.Equal(t, http.StatusPartialContent, rec.Code)
+
+		// 文件已修改，返回整个文件。
+		rec = do("name=a.txt", map[string]string{"Range": "bytes=2-5", "If-Range": "Wed, 01 Jan 2020 00:00:00 GMT"})
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "0123456789", rec.Body.String())
+	})
+
+	t.Run("not-modified", func(t *testing.T) {
+		rec := do("name=a.txt", map[string]string{"If-Modified-Since": "Thu, 02 Jan 2020 03:04:05 GMT"})
+		require.Equal(t, http.StatusNotModified, rec.Code)
+		require.Empty(t, rec.Body.String())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		rec := do("name=", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, webapi.ContentTypeJson, rec.Header().Get(webapi.HttpHeaderContentType))
+		require.Equal(t, `{"Code":1,"Message":"missing name","Data":null}`, rec.Body.String())
+		require.Empty(t, rec.Header().Get(webapi.HttpHeaderContentDisposition))
+	})
+
+	t.Run("unseekable", func(t *testing.T) {
+		rec := do("name=unseekable", nil)
+		require.Equal(t, `{"Code":500,"Message":"internal error","Data":null}`, rec.Body.String())
+	})
+}
+
+type disconnectTestProvider struct {
+	cleaned chan struct{}
+}
+
+func (p disconnectTestProvider) Wait(ctx context.Context) webapi.EventStream[int] {
+	return func(yield func(int, error) bool) {
+		defer close(p.cleaned)
+
+		if yield(1, nil) {
+			<-ctx.Done()
+		}
+	}
+}
+
+func TestSlimApi_clientDisconnect(t *testing.T) {
+	p := disconnectTestProvider{make(chan struct{})}
+	h := NewSlimApiHandler("")
+	h.RegisterMethods(p)
+
+	engine := webapi.NewEngine()
+	engine.Handle("/", h, nil)
+	s := httptest.NewServer(engine)
+	defer s.Close()
+
+	res, err := http.Get(s.URL + "/?Wait")
+	require.NoError(t, err)
+
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, `data: {"Code":0,"Message":"","Data":1}`+"\n", line)
+
+	// 断开连接后，方法通过 context 感知，迭代器的 defer 被执行。
+	res.Body.Close()
+	select {
+	case <-p.cleaned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the iterator is not finished")
+	}
+}
+
+type hubTestProvider struct {
+	hub *webapi.Hub[string]
+}
+
+func (p hubTestProvider) Watch(ctx context.Context, req struct{ Topic string }) webapi.EventStream[string] {
+	return p.hub.Subscribe(ctx, req.Topic)
+}
+
+func TestSlimApi_hub(t *testing.T) {
+	p := hubTestProvider{new(webapi.Hub[string])}
+	h := NewSlimApiHandler("")
+	h.RegisterMethods(p)
+
+	engine := webapi.NewEngine()
+	engine.Handle("/", h, nil)
+	s := httptest.NewServer(engine)
+	defer s.Close()
+
+	// 在第一段数据之前，回执的头尚未输出， http.Get 不会返回，故在 goroutine 中发起请求。
+	// 逐个发起，待前一个请求订阅后再发起下一个。
+	responses := make(chan *http.Response, 2)
+	for i := 1; i <= 2; i++ {
+		go func() {
+			res, err := http.Get(s.URL + "/?Watch&Topic=t")
+			if assert.NoError(t, err) {
+				responses <- res
+			}
+		}()
+		require.Eventually(t, func() bool { return p.hub.Subscribers("t") == i }, 5*time.Second, time.Millisecond)
+	}
+
+	require.Equal(t, 2, p.hub.Publish("t", "a"))
+
+	var bodies []io.Closer
+	for i := 0; i < 2; i++ {
+		var res *http.Response
+		select {
+		case res = <-responses:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		line, err := bufio.NewReader(res.Body).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, `data: {"Code":0,"Message":"","Data":"a"}`+"\n", line)
+		bodies = append(bodies, res.Body)
+	}
+
+	// 断开连接后，订阅被取消。
+	bodies[0].Close()
+	require.Eventually(t, func() bool { return p.hub.Subscribers("t") == 1 }, 5*time.Second, time.Millisecond)
+
+	bodies[1].Close()
+	require.Eventually(t, func() bool { return p.hub.Subscribers("t") == 0 }, 5*time.Second, time.Millisecond)
+}
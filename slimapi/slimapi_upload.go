@@ -0,0 +1,370 @@
+package slimapi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+
+	"github.com/cmstar/go-webapi"
+)
+
+const (
+	// ApiMetaUploadStorage 是指定上传文件的存储方式的元数据的 key ，值为 [UploadStorage] 。
+	//
+	// 未指定时，使用 [http.Request.ParseMultipartForm] 解析 multipart/form-data 请求，超过 10MB 的部分被写入系统的临时目录。
+	// 无论是否指定，上传的文件都会在请求结束时被删除，需要保留的文件可通过 [FilePart.SaveTo] 移走。
+	//
+	// 可通过 [webapi.ApiSetup.SetMetaForAllMethods] 为全部方法设置。
+	ApiMetaUploadStorage = "UploadStorage"
+
+	// ApiMetaMaxFileSize 是限制 multipart/form-data 请求中单个文件大小的元数据的 key ，值为 int64 ，单位是字节，
+	// 小于等于 0 表示不限制。超过时返回 Code 为 [webapi.ErrorCodeRequestEntityTooLarge] 的错误。
+	//
+	// 指定了 [ApiMetaUploadStorage] 时，在读取文件的过程中即中止；否则在整个请求被解析后校验。
+	ApiMetaMaxFileSize = "MaxFileSize"
+)
+
+// UploadStorage 用于存放 multipart/form-data 请求中上传的文件，见 [ApiMetaUploadStorage] 。
+// 内置 [NewLocalUploadStorage] 和 [NewMemoryUploadStorage] 两种实现，也可自行实现，如将文件直接写入对象存储。
+type UploadStorage interface {
+	// Save 读取 r 中的全部数据并存储。 header 描述文件的名称、 HTTP 头等，其 Size 尚未被赋值。
+	// r 在读取超过大小限制时返回 [*http.MaxBytesError] ，此时应清理已写入的数据，并将此错误原样返回。
+	Save(header *multipart.FileHeader, r io.Reader) (UploadedFile, error)
+}
+
+// UploadedFile 是由 [UploadStorage] 存储的文件。
+type UploadedFile interface {
+	// Size 返回文件的字节数。
+	Size() int64
+
+	// Open 打开文件用于读取，可以被多次调用。
+	Open() (multipart.File, error)
+
+	// MoveTo 将文件移动到本地的给定路径。之后，当前文件不再可用， Remove 不做任何事。
+	MoveTo(path string) error
+
+	// Remove 删除文件，在请求结束时被调用。
+	Remove() error
+}
+
+// NewLocalUploadStorage 返回一个将文件写入本地目录 dir 的 [UploadStorage] 。
+// dir 为空时，使用系统的临时目录。 [UploadedFile.MoveTo] 优先使用重命名，失败时（如跨越分区）才复制文件。
+func NewLocalUploadStorage(dir string) UploadStorage {
+	return localUploadStorage{dir}
+}
+
+type localUploadStorage struct {
+	dir string
+}
+
+func (s localUploadStorage) Save(header *multipart.FileHeader, r io.Reader) (UploadedFile, error) {
+	f, err := os.CreateTemp(s.dir, "upload-")
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &localUploadedFile{path: f.Name(), size: size}, nil
+}
+
+type localUploadedFile struct {
+	path string // 被移走或删除后为空。
+	size int64
+}
+
+func (f *localUploadedFile) Size() int64 {
+	return f.size
+}
+
+func (f *localUploadedFile) Open() (multipart.File, error) {
+	if f.path == "" {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(f.path)
+}
+
+func (f *localUploadedFile) MoveTo(path string) error {
+	if f.path == "" {
+		return os.ErrNotExist
+	}
+
+	if err := moveFile(f.path, path); err != nil {
+		return err
+	}
+	f.path = ""
+	return nil
+}
+
+func (f *localUploadedFile) Remove() error {
+	if f.path == "" {
+		return nil
+	}
+
+	err := os.Remove(f.path)
+	f.path = ""
+	return err
+}
+
+// NewMemoryUploadStorage 返回一个将文件保存在内存中的 [UploadStorage] ，适用于文件较小的场景，通常搭配 [ApiMetaMaxFileSize] 使用。
+func NewMemoryUploadStorage() UploadStorage {
+	return memoryUploadStorage{}
+}
+
+type memoryUploadStorage struct{}
+
+func (memoryUploadStorage) Save(header *multipart.FileHeader, r io.Reader) (UploadedFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryUploadedFile{data}, nil
+}
+
+type memoryUploadedFile struct {
+	data []byte
+}
+
+// bytes.Reader 没有 Close 方法，补充一个，以实现 multipart.File 。
+type memoryFileReader struct {
+	*bytes.Reader
+}
+
+func (memoryFileReader) Close() error {
+	return nil
+}
+
+func (f *memoryUploadedFile) Size() int64 {
+	return int64(len(f.data))
+}
+
+func (f *memoryUploadedFile) Open() (multipart.File, error) {
+	if f.data == nil {
+		return nil, os.ErrNotExist
+	}
+	return memoryFileReader{bytes.NewReader(f.data)}, nil
+}
+
+func (f *memoryUploadedFile) MoveTo(path string) error {
+	if f.data == nil {
+		return os.ErrNotExist
+	}
+
+	if err := os.WriteFile(path, f.data, 0o644); err != nil {
+		return err
+	}
+	f.data = nil
+	return nil
+}
+
+func (f *memoryUploadedFile) Remove() error {
+	f.data = nil
+	return nil
+}
+
+// 将文件 src 移动到 dst ，优先使用重命名，失败时复制后删除 src 。
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dst, in)
+}
+
+func writeFile(path string, r io.Reader) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// 读取超过 limit 字节时，返回 *http.MaxBytesError 。 limit 小于等于 0 表示不限制。
+type limitedFileReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func (x *limitedFileReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	x.n += int64(n)
+	if x.limit > 0 && x.n > x.limit {
+		return n, &http.MaxBytesError{Limit: x.limit}
+	}
+	return n, err
+}
+
+// 获取方法的 ApiMetaMaxFileSize 。
+func maxFileSize(state *webapi.ApiState) int64 {
+	size, _ := webapi.GetApiMetaValue[int64](state.Method.Meta, ApiMetaMaxFileSize)
+	return size
+}
+
+// 解析 multipart/form-data 请求，得到其中的表单值和文件。
+// 方法指定了 ApiMetaUploadStorage 时，逐个读取 part ，文件交给 UploadStorage 存储；否则使用 http.Request.ParseMultipartForm 。
+// 文件均在请求结束时被删除。若方法指定了 ApiMetaUploadPolicy ，解析后对文件进行校验。
+func parseMultipartForm(state *webapi.ApiState) (values map[string][]string, files map[string][]*FilePart, err error) {
+	storage, ok := webapi.GetApiMetaValue[UploadStorage](state.Method.Meta, ApiMetaUploadStorage)
+	if ok && storage != nil {
+		return readMultipartToStorage(state, storage)
+	}
+
+	req := state.RawRequest
+	err = req.ParseMultipartForm(maxMemorySizeParseRequestBody)
+	if req.MultipartForm != nil {
+		form := req.MultipartForm
+		state.AddCleanup(func() { form.RemoveAll() })
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limit := maxFileSize(state)
+	files = make(map[string][]*FilePart, len(req.MultipartForm.File))
+	for name, fileHeaders := range req.MultipartForm.File {
+		for _, fileHeader := range fileHeaders {
+			if limit > 0 && fileHeader.Size > limit {
+				return nil, nil, &http.MaxBytesError{Limit: limit}
+			}
+
+			filePart, err := NewFilePart(fileHeader)
+			if err != nil {
+				return nil, nil, err
+			}
+			files[name] = append(files[name], filePart)
+		}
+	}
+
+	if err := checkUploadPolicy(state, files); err != nil {
+		return nil, nil, err
+	}
+	return req.PostForm, files, nil
+}
+
+func readMultipartToStorage(state *webapi.ApiState, storage UploadStorage) (values map[string][]string, files map[string][]*FilePart, err error) {
+	req := state.RawRequest
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values = make(map[string][]string)
+	files = make(map[string][]*FilePart)
+
+	// 同 http.Request.ParseMultipartForm ，在 MultipartForm 上记录文件信息，以便 logsetup.Files 等使用。
+	// 其中的 FileHeader 不能通过 Open 读取数据。
+	form := &multipart.Form{Value: values, File: make(map[string][]*multipart.FileHeader)}
+	req.MultipartForm = form
+
+	limit := maxFileSize(state)
+	valueBytes := int64(0)
+
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+
+		// 非文件的 part ，读到内存中，总大小受限。
+		if p.FileName() == "" {
+			data, err := io.ReadAll(io.LimitReader(p, maxMemorySizeParseRequestBody-valueBytes+1))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			valueBytes += int64(len(data))
+			if valueBytes > maxMemorySizeParseRequestBody {
+				return nil, nil, &http.MaxBytesError{Limit: maxMemorySizeParseRequestBody}
+			}
+			values[name] = append(values[name], string(data))
+			continue
+		}
+
+		fileHeader := &multipart.FileHeader{
+			Filename: p.FileName(),
+			Header:   p.Header,
+		}
+
+		file, err := storage.Save(fileHeader, &limitedFileReader{r: p, limit: limit})
+		if err != nil {
+			return nil, nil, err
+		}
+		state.AddCleanup(func() { file.Remove() })
+
+		fileHeader.Size = file.Size()
+		form.File[name] = append(form.File[name], fileHeader)
+
+		filePart, err := newStoredFilePart(fileHeader, file)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[name] = append(files[name], filePart)
+	}
+
+	if err := checkUploadPolicy(state, files); err != nil {
+		return nil, nil, err
+	}
+	return values, files, nil
+}
+
+// SaveTo 将文件保存到本地的给定路径。文件位于临时文件中时，直接将其移动（重命名）到目标路径，否则复制数据。
+// 上传的文件在请求结束时被删除，需要保留的文件可通过此方法移走。保存后，不应再读取当前文件。
+func (x *FilePart) SaveTo(path string) error {
+	if x.file != nil {
+		return x.file.MoveTo(path)
+	}
+
+	f, err := x.FileHeader.Open()
+	if err != nil {
+		return err
+	}
+
+	// ParseMultipartForm 写入了临时文件的，打开得到的是 *os.File 。
+	if osFile, ok := f.(*os.File); ok {
+		osFile.Close()
+		return moveFile(osFile.Name(), path)
+	}
+
+	defer f.Close()
+	return writeFile(path, f)
+}
+
+var errFileHeaderFromStorage = errors.New("*multipart.FileHeader cannot be used with an UploadStorage, use *slimapi.FilePart instead")
@@ -0,0 +1,221 @@
+package slimapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cmstar/go-webapi"
+)
+
+// ApiMetaUploadPolicy 是指定上传文件的校验规则的元数据的 key ，值为 [*UploadPolicy] 。
+// 可通过 [webapi.ApiSetup.SetMetaForAllMethods] 为全部方法设置。
+const ApiMetaUploadPolicy = "UploadPolicy"
+
+// UploadPolicy 定义 multipart/form-data 请求中上传的文件需满足的规则，通过元数据 [ApiMetaUploadPolicy] 指定。
+// 校验在请求被解析后、方法被调用前进行，未通过时返回 [*UploadPolicyError] ，其消息作为回执的 Message 返回给请求者。
+//
+// 带有文件名的 part 均作为文件校验，其声明的 Content-Type （包括 application/json ）不被采信。
+// 以流的方式读取 body 的方法（如使用 [MultipartParts] 参数），不会进行校验。
+type UploadPolicy struct {
+	// AllowedTypes 是允许的 MIME 类型，如 image/png ，可使用 image/* 的形式匹配一类。为空表示不限制。
+	// 文件的类型由其内容判断（见 [FilePart.SniffContentType] ），与请求中给定的 Content-Type 无关。
+	AllowedTypes []string
+
+	// AllowedExtensions 是允许的文件扩展名，如 .png ，大小写不敏感，可省略开头的点。为空表示不限制。
+	AllowedExtensions []string
+
+	// MaxFileSize 限制单个文件的字节数，小于等于 0 表示不限制。
+	// 文件在被完整接收后才校验，若需在读取过程中即中止，可同时使用 [ApiMetaMaxFileSize] 。
+	MaxFileSize int64
+
+	// MaxTotalSize 限制请求中全部文件的总字节数，小于等于 0 表示不限制。
+	MaxTotalSize int64
+
+	// Scan 在上述规则都通过后，对每个文件调用，可用于接入外部的扫描（如杀毒）。返回非 nil 的错误时，拒绝请求，
+	// 错误的消息会返回给请求者。为 nil 表示不扫描。
+	Scan func(state *webapi.ApiState, field string, file *FilePart) error
+}
+
+// UploadPolicyError 描述上传的文件未通过 [UploadPolicy] 的校验。
+type UploadPolicyError struct {
+	Field    string // 文件所在的 part 的名称。校验总大小时为空。
+	Filename string // 文件名。校验总大小时为空。
+	Code     int    // 返回给请求者的错误码，大小超过限制时为 [webapi.ErrorCodeRequestEntityTooLarge] ，其余为 [webapi.ErrorCodeBadRequest] 。
+	Message  string // 不通过的原因。
+	Cause    error  // 导致错误的原因，如 Scan 返回的错误。可为 nil 。
+}
+
+var _ error = (*UploadPolicyError)(nil)
+
+// Error implements error.Error().
+func (e *UploadPolicyError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("file '%s' of '%s': %s", e.Filename, e.Field, e.Message)
+}
+
+// Unwrap 返回 Cause 。
+func (e *UploadPolicyError) Unwrap() error {
+	return e.Cause
+}
+
+// 按方法的 ApiMetaUploadPolicy 校验 multipart/form-data 请求中的文件。方法未指定时，不做任何事。
+func checkUploadPolicy(state *webapi.ApiState, files map[string][]*FilePart) error {
+	policy, ok := webapi.GetApiMetaValue[*UploadPolicy](state.Method.Meta, ApiMetaUploadPolicy)
+	if !ok || policy == nil {
+		return nil
+	}
+
+	// 按名称的顺序校验，使同一个请求总是得到相同的错误。
+	total := int64(0)
+	for _, field := range slices.Sorted(maps.Keys(files)) {
+		for _, f := range files[field] {
+			if err := policy.checkFile(state, field, f); err != nil {
+				return err
+			}
+			total += f.Size
+		}
+	}
+
+	if policy.MaxTotalSize > 0 && total > policy.MaxTotalSize {
+		return &UploadPolicyError{
+			Code:    webapi.ErrorCodeRequestEntityTooLarge,
+			Message: fmt.Sprintf("total size of files exceeds %d bytes", policy.MaxTotalSize),
+		}
+	}
+	return nil
+}
+
+func (p *UploadPolicy) checkFile(state *webapi.ApiState, field string, f *FilePart) error {
+	fail := func(code int, cause error, format string, args ...any) error {
+		return &UploadPolicyError{
+			Field:    field,
+			Filename: f.Filename,
+			Code:     code,
+			Message:  fmt.Sprintf(format, args...),
+			Cause:    cause,
+		}
+	}
+
+	if p.MaxFileSize > 0 && f.Size > p.MaxFileSize {
+		return fail(webapi.ErrorCodeRequestEntityTooLarge, nil, "file size exceeds %d bytes", p.MaxFileSize)
+	}
+
+	if len(p.AllowedExtensions) > 0 {
+		ext := filepath.Ext(f.Filename)
+		if !matchExtension(p.AllowedExtensions, ext) {
+			return fail(webapi.ErrorCodeBadRequest, nil, "extension '%s' is not allowed", ext)
+		}
+	}
+
+	if len(p.AllowedTypes) > 0 {
+		typ, err := f.SniffContentType()
+		if err != nil {
+			return err
+		}
+
+		if !matchMediaType(p.AllowedTypes, typ) {
+			return fail(webapi.ErrorCodeBadRequest, nil, "content type '%s' is not allowed", typ)
+		}
+	}
+
+	if p.Scan != nil {
+		if err := p.Scan(state, field, f); err != nil {
+			return fail(webapi.ErrorCodeBadRequest, err, "rejected by scanner: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+func matchExtension(allowed []string, ext string) bool {
+	ext = strings.TrimPrefix(ext, ".")
+	if ext == "" {
+		return false
+	}
+
+	for _, v := range allowed {
+		if strings.EqualFold(strings.TrimPrefix(v, "."), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// typ 是不带参数的 MIME 类型。 allowed 中的元素可以是 image/* 的形式。
+func matchMediaType(allowed []string, typ string) bool {
+	for _, v := range allowed {
+		v = strings.ToLower(v)
+		if v == typ {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(v, "/*"); ok && strings.HasPrefix(typ, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// SniffContentType 根据文件开头的数据判断其 MIME 类型，返回的值不带参数（如 charset ），均为小写。
+// 依次按下列方式判断：
+//   - 几种 [http.DetectContentType] 不能识别的格式的魔数，如 image/tiff 、 image/avif 、 image/heic 、 application/x-7z-compressed ；
+//   - [http.DetectContentType] 。
+//
+// 不能识别的二进制数据，得到 application/octet-stream ；文本数据（如 CSV ）通常得到 text/plain 。
+func (x *FilePart) SniffContentType() (string, error) {
+	f, err := x.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	head = head[:n]
+
+	if typ := sniffMagicNumber(head); typ != "" {
+		return typ, nil
+	}
+
+	typ, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "", err
+	}
+	return typ, nil
+}
+
+// http.DetectContentType 之外补充的魔数。 offset 是魔数在文件中的位置。
+var magicNumbers = []struct {
+	offset int
+	magic  []byte
+	typ    string
+}{
+	{0, []byte("II*\x00"), "image/tiff"},
+	{0, []byte("MM\x00*"), "image/tiff"},
+	{0, []byte("7z\xBC\xAF\x27\x1C"), "application/x-7z-compressed"},
+	{4, []byte("ftypavif"), "image/avif"},
+	{4, []byte("ftypheic"), "image/heic"},
+	{4, []byte("ftypheix"), "image/heic"},
+	{4, []byte("ftypmif1"), "image/heif"},
+}
+
+func sniffMagicNumber(head []byte) string {
+	for _, v := range magicNumbers {
+		if len(head) >= v.offset+len(v.magic) && bytes.Equal(head[v.offset:v.offset+len(v.magic)], v.magic) {
+			return v.typ
+		}
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package slimapi
+
+import (
+	"errors"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/cmstar/go-webapi/webapitest"
+	"github.com/stretchr/testify/require"
+)
+
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+func TestUploadPolicy(t *testing.T) {
+	run := func(t *testing.T, policy *UploadPolicy, files map[string]string, want string) {
+		var got []*FilePart
+		engine := uploadTestEngine(uploadTestProvider{got: &got}, func(s webapi.ApiSetup) {
+			s.SetMethodMeta("Upload", ApiMetaUploadPolicy, policy)
+		})
+
+		body := uploadTestRequest(t, engine, "Upload", files)
+		require.Equal(t, want, body)
+	}
+
+	t.Run("types", func(t *testing.T) {
+		policy := &UploadPolicy{AllowedTypes: []string{"image/*", "application/pdf"}}
+
+		run(t, policy, map[string]string{"a.png": pngHeader},
+			`{"Code":0,"Message":"","Data":"n,a.png:`+"\ufffdPNG\\r\\n\\u001a\\n"+`"}`)
+
+		run(t, policy, map[string]string{"a.pdf": "%PDF-1.4"},
+			`{"Code":0,"Message":"","Data":"n,a.pdf:%PDF-1.4"}`)
+
+		// 文件名和声明的类型都不能骗过检查。
+		run(t, policy, map[string]string{"a.png": "<html></html>"},
+			`{"Code":400,"Message":"file 'a.png' of 'file': content type 'text/html' is not allowed","Data":null}`)
+	})
+
+	t.Run("extensions", func(t *testing.T) {
+		policy := &UploadPolicy{AllowedExtensions: []string{".txt", "CSV"}}
+
+		run(t, policy, map[string]string{"a.TXT": "a"}, `{"Code":0,"Message":"","Data":"n,a.TXT:a"}`)
+		run(t, policy, map[string]string{"a.csv": "a"}, `{"Code":0,"Message":"","Data":"n,a.csv:a"}`)
+		run(t, policy, map[string]string{"a.exe": "a"},
+			`{"Code":400,"Message":"file 'a.exe' of 'file': extension '.exe' is not allowed","Data":null}`)
+		run(t, policy, map[string]string{"txt": "a"},
+			`{"Code":400,"Message":"file 'txt' of 'file': extension '' is not allowed","Data":null}`)
+	})
+
+	t.Run("sizes", func(t *testing.T) {
+		policy := &UploadPolicy{MaxFileSize: 3, MaxTotalSize: 5}
+
+		run(t, policy, map[string]string{"a": "abc"}, `{"Code":0,"Message":"","Data":"n,a:abc"}`)
+		run(t, policy, map[string]string{"a": "abcd"},
+			`{"Code":413,"Message":"file 'a' of 'file': file size exceeds 3 bytes","Data":null}`)
+		run(t, policy, map[string]string{"a": "abc", "b": "abc"},
+			`{"Code":413,"Message":"total size of files exceeds 5 bytes","Data":null}`)
+	})
+
+	t.Run("scan", func(t *testing.T) {
+		var fields []string
+		policy := &UploadPolicy{
+			Scan: func(state *webapi.ApiState, field string, file *FilePart) error {
+				fields = append(fields, field)
+				data, err := file.ReadAll()
+				if err != nil {
+					return err
+				}
+				if string(data) == "virus" {
+					return errors.New("infected")
+				}
+				return nil
+			},
+		}
+
+		run(t, policy, map[string]string{"a": "ok"}, `{"Code":0,"Message":"","Data":"n,a:ok"}`)
+		run(t, policy, map[string]string{"a": "virus"},
+			`{"Code":400,"Message":"file 'a' of 'file': rejected by scanner: infected","Data":null}`)
+		require.Equal(t, []string{"file", "file"}, fields)
+	})
+
+	t.Run("storage", func(t *testing.T) {
+		var got []*FilePart
+		engine := uploadTestEngine(uploadTestProvider{got: &got}, func(s webapi.ApiSetup) {
+			s.SetMetaForAllMethods(ApiMetaUploadStorage, NewMemoryUploadStorage()).
+				SetMetaForAllMethods(ApiMetaUploadPolicy, &UploadPolicy{AllowedTypes: []string{"text/plain"}})
+		})
+
+		body := uploadTestRequest(t, engine, "Upload", map[string]string{"a": "text"})
+		require.Equal(t, `{"Code":0,"Message":"","Data":"n,a:text"}`, body)
+
+		body = uploadTestRequest(t, engine, "Upload", map[string]string{"a": "%PDF-1.4"})
+		require.Equal(t, `{"Code":400,"Message":"file 'a' of 'file': content type 'application/pdf' is not allowed","Data":null}`, body)
+	})
+}
+
+func Test_checkUploadPolicy(t *testing.T) {
+	newPart := func(field, filename, content string, header map[string]string) *FilePart {
+		f, err := NewFilePart(webapitest.CreateMultipartFileHeader(field, filename, []byte(content), header))
+		require.NoError(t, err)
+		return f
+	}
+	check := func(policy *UploadPolicy, files map[string][]*FilePart) error {
+		state, _ := webapitest.NewStateForTest(webapitest.NoOpHandler, "/", webapitest.NewStateSetup{})
+		state.Method.Meta = webapi.NewApiMeta(map[string]any{ApiMetaUploadPolicy: policy})
+		return checkUploadPolicy(state, files)
+	}
+	jsonHeader := map[string]string{webapi.HttpHeaderContentType: webapi.ContentTypeJson}
+
+	t.Run("json-file", func(t *testing.T) {
+		// 声明为 JSON 的文件同样被校验，类型由内容判断。
+		files := map[string][]*FilePart{"a": {newPart("a", "a.json", `{"A":12345}`, jsonHeader)}}
+
+		err := check(&UploadPolicy{MaxFileSize: 3}, files)
+		require.EqualError(t, err, "file 'a.json' of 'a': file size exceeds 3 bytes")
+
+		err = check(&UploadPolicy{AllowedTypes: []string{"image/*"}}, files)
+		require.EqualError(t, err, "file 'a.json' of 'a': content type 'text/plain' is not allowed")
+	})
+
+	t.Run("order", func(t *testing.T) {
+		files := map[string][]*FilePart{}
+		for _, field := range []string{"d", "b", "c", "a", "e"} {
+			files[field] = []*FilePart{newPart(field, field+".exe", "x", nil)}
+		}
+
+		for range 10 {
+			err := check(&UploadPolicy{AllowedExtensions: []string{".png"}}, files)
+			require.EqualError(t, err, "file 'a.exe' of 'a': extension '.exe' is not allowed")
+		}
+	})
+}
+
+func TestFilePart_SniffContentType(t *testing.T) {
+	cases := []struct {
+		content string
+		want    string
+	}{
+		{"", "text/plain"},
+		{"hello", "text/plain"},
+		{pngHeader, "image/png"},
+		{"\xff\xd8\xff\xe0", "image/jpeg"},
+		{"II*\x00\x08\x00", "image/tiff"},
+		{"MM\x00*\x00\x08", "image/tiff"},
+		{"7z\xBC\xAF\x27\x1C\x00\x04", "application/x-7z-compressed"},
+		{"\x00\x00\x00\x1cftypavif", "image/avif"},
+		{"\x00\x00\x00\x18ftypheic", "image/heic"},
+		{"\x00\x01\x02\x03", "application/octet-stream"},
+	}
+
+	for _, c := range cases {
+		storage := NewMemoryUploadStorage()
+		file, err := storage.Save(&multipart.FileHeader{}, strings.NewReader(c.content))
+		require.NoError(t, err)
+
+		f, err := newStoredFilePart(&multipart.FileHeader{Filename: "f"}, file)
+		require.NoError(t, err)
+
+		typ, err := f.SniffContentType()
+		require.NoError(t, err)
+		require.Equal(t, c.want, typ, "content: %q", c.content)
+	}
+}
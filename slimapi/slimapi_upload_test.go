@@ -0,0 +1,200 @@
+package slimapi
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadTestProvider struct {
+	// 记录方法收到的文件，以便在请求结束后检查。
+	got *[]*FilePart
+
+	// 不为空时，将文件移到此路径。
+	saveTo string
+}
+
+func (p uploadTestProvider) Upload(req struct {
+	Name string
+	File []*FilePart
+}) string {
+	*p.got = req.File
+
+	var b strings.Builder
+	b.WriteString(req.Name)
+	for _, f := range req.File {
+		data, err := f.ReadAll()
+		if err != nil {
+			panic(err)
+		}
+		b.WriteString("," + f.Filename + ":" + string(data))
+	}
+
+	if p.saveTo != "" {
+		if err := req.File[0].SaveTo(p.saveTo); err != nil {
+			panic(err)
+		}
+	}
+	return b.String()
+}
+
+func (p uploadTestProvider) Header(req struct{ File *multipart.FileHeader }) string {
+	return req.File.Filename
+}
+
+// 发送包含 name=n 及给定文件的请求，返回回执的 body 。
+func uploadTestRequest(t *testing.T, engine http.Handler, method string, files map[string]string) string {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+	require.NoError(t, w.WriteField("Name", "n"))
+	for name, content := range files {
+		f, err := w.CreateFormFile("file", name)
+		require.NoError(t, err)
+		f.Write([]byte(content))
+	}
+	require.NoError(t, w.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/?"+method, buf)
+	r.Header.Set(webapi.HttpHeaderContentType, w.FormDataContentType())
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, r)
+	return rec.Body.String()
+}
+
+func uploadTestEngine(p uploadTestProvider, setup func(webapi.ApiSetup)) http.Handler {
+	h := NewSlimApiHandler("")
+	h.RegisterMethods(p)
+
+	engine := webapi.NewEngine()
+	setup(engine.Handle("/", h, nil))
+	return engine
+}
+
+func TestUploadStorage(t *testing.T) {
+	t.Run("local", func(t *testing.T) {
+		dir := t.TempDir()
+		var got []*FilePart
+		engine := uploadTestEngine(uploadTestProvider{got: &got}, func(s webapi.ApiSetup) {
+			s.SetMetaForAllMethods(ApiMetaUploadStorage, NewLocalUploadStorage(dir))
+		})
+
+		body := uploadTestRequest(t, engine, "Upload", map[string]string{"a.txt": "aa"})
+		require.Equal(t, `{"Code":0,"Message":"","Data":"n,a.txt:aa"}`, body)
+		require.Len(t, got, 1)
+		require.Equal(t, int64(2), got[0].Size)
+
+		// 请求结束后，文件被删除。
+		entries, _ := os.ReadDir(dir)
+		require.Empty(t, entries)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		var got []*FilePart
+		engine := uploadTestEngine(uploadTestProvider{got: &got}, func(s webapi.ApiSetup) {
+			s.SetMethodMeta("Upload", ApiMetaUploadStorage, NewMemoryUploadStorage())
+		})
+
+		body := uploadTestRequest(t, engine, "Upload", map[string]string{"a.txt": "aa"})
+		require.Equal(t, `{"Code":0,"Message":"","Data":"n,a.txt:aa"}`, body)
+
+		_, err := got[0].Open()
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("save-to", func(t *testing.T) {
+		for name, storage := range map[string]UploadStorage{
+			"default": nil,
+			"local":   NewLocalUploadStorage(t.TempDir()),
+			"memory":  NewMemoryUploadStorage(),
+		} {
+			t.Run(name, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "saved")
+				var got []*FilePart
+				engine := uploadTestEngine(uploadTestProvider{got: &got, saveTo: path}, func(s webapi.ApiSetup) {
+					s.SetMetaForAllMethods(ApiMetaUploadStorage, storage)
+				})
+
+				uploadTestRequest(t, engine, "Upload", map[string]string{"a.txt": "aa"})
+
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				require.Equal(t, "aa", string(data))
+			})
+		}
+	})
+
+	// 超过 10MB 的文件被 ParseMultipartForm 写入临时文件，请求结束后被删除。
+	t.Run("default-temp-file", func(t *testing.T) {
+		var got []*FilePart
+		engine := uploadTestEngine(uploadTestProvider{got: &got}, func(webapi.ApiSetup) {})
+
+		content := strings.Repeat("x", maxMemorySizeParseRequestBody+1)
+		uploadTestRequest(t, engine, "Upload", map[string]string{"a.txt": content})
+
+		f, err := got[0].Open()
+		if err == nil {
+			f.Close()
+		}
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("max-file-size", func(t *testing.T) {
+		for name, storage := range map[string]UploadStorage{
+			"default": nil,
+			"memory":  NewMemoryUploadStorage(),
+		} {
+			t.Run(name, func(t *testing.T) {
+				var got []*FilePart
+				engine := uploadTestEngine(uploadTestProvider{got: &got}, func(s webapi.ApiSetup) {
+					s.SetMetaForAllMethods(ApiMetaUploadStorage, storage).
+						SetMetaForAllMethods(ApiMetaMaxFileSize, int64(3))
+				})
+
+				body := uploadTestRequest(t, engine, "Upload", map[string]string{"a.txt": "abc"})
+				require.Equal(t, `{"Code":0,"Message":"","Data":"n,a.txt:abc"}`, body)
+
+				body = uploadTestRequest(t, engine, "Upload", map[string]string{"a.txt": "abcd"})
+				require.Equal(t, `{"Code":413,"Message":"request body too large","Data":null}`, body)
+			})
+		}
+	})
+
+	t.Run("file-header", func(t *testing.T) {
+		engine := uploadTestEngine(uploadTestProvider{}, func(s webapi.ApiSetup) {
+			s.SetMethodMeta("Header", ApiMetaUploadStorage, NewMemoryUploadStorage())
+		})
+
+		body := uploadTestRequest(t, engine, "Header", map[string]string{"a.txt": "aa"})
+		require.Equal(t, `{"Code":400,"Message":"bad request","Data":null}`, body)
+	})
+}
+
+func TestLocalUploadStorage_error(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalUploadStorage(dir)
+
+	_, err := s.Save(&multipart.FileHeader{}, &limitedFileReader{r: strings.NewReader("abcd"), limit: 3})
+	var maxBytesErr *http.MaxBytesError
+	require.ErrorAs(t, err, &maxBytesErr)
+
+	// 写了一半的文件被删除。
+	entries, _ := os.ReadDir(dir)
+	require.Empty(t, entries)
+
+	f, err := s.Save(&multipart.FileHeader{}, strings.NewReader("abc"))
+	require.NoError(t, err)
+	require.NoError(t, f.Remove())
+	require.NoError(t, f.Remove())
+
+	_, err = f.Open()
+	require.ErrorIs(t, err, os.ErrNotExist)
+	require.ErrorIs(t, f.MoveTo(filepath.Join(dir, "x")), os.ErrNotExist)
+}
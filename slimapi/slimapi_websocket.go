@@ -0,0 +1,380 @@
+package slimapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cmstar/go-logx"
+	"github.com/cmstar/go-webapi"
+	"github.com/cmstar/go-webapi/websocket"
+)
+
+// WebSocketRequest 是客户端通过 WebSocket 发送的请求帧，格式为 JSON ，字段名称大小写不敏感：
+//
+//	{"Id":1,"Method":"Plus","Params":{"A":1,"B":2}}
+type WebSocketRequest struct {
+	// Id 用于将回执与请求对应起来，可以是任意 JSON 值，原样放在回执帧上。
+	Id json.RawMessage
+
+	// Method 是方法的名称。
+	Method string
+
+	// Params 是方法的参数，同 Content-Type: application/json 的请求的 body 。可省略。
+	Params json.RawMessage
+}
+
+// WebSocketHandler 通过 WebSocket 提供 [webapi.ApiHandler] 上注册的方法。
+// 客户端建立一个连接后，可以发送多个请求帧（见 [WebSocketRequest] ），各请求并发执行（个数受 MaxConcurrentRequests 限制），回执帧通过 Id 对应。
+//
+// 每个请求帧都按 HTTP 请求的流程（ ApiDecoder 、 ApiMethodCaller 、 ApiLogger 等）处理，
+// 相当于一个 Content-Type 为 application/json 的 POST 请求，其 HTTP 头、 query 参数、路由参数等沿用 upgrade 请求的。
+// 回执帧是在 [webapi.ApiResponse] 信封上增加 Id 字段：
+//
+//	{"Id":1,"Code":0,"Message":"","Data":3}
+//
+// 方法返回 [webapi.EventStream] 、 [webapi.NdJson] 或 [webapi.JsonArray] 时，流中的每段数据各输出一个回执帧，
+// 最后输出一个 Code 为 [webapi.EventStreamEndCode] 的帧，表示流结束：
+//
+//	{"Id":1,"Code":1000,"Message":"","Data":null}
+//
+// 连接关闭时，正在执行的方法的 context 被取消（见 [http.Request.Context] ）。
+// 不支持 [*webapi.FileResponse] 及以流的方式读取 body 的方法。
+type WebSocketHandler struct {
+	// CheckOrigin 校验 upgrade 请求，返回 false 时拒绝连接（ 403 ）。
+	// 为 nil 时，要求请求没有 Origin 头，或 Origin 的 host 与请求的 Host 一致，以防止跨站的 WebSocket 劫持。
+	CheckOrigin func(r *http.Request) bool
+
+	// MaxMessageSize 是请求帧的最大字节数，小于等于 0 时使用 [websocket.DefaultMaxMessageSize] 。
+	MaxMessageSize int64
+
+	// MaxConcurrentRequests 是一个连接上同时执行的请求的最大个数，小于等于 0 时使用 [DefaultWebSocketMaxConcurrentRequests] 。
+	// 达到上限时，后续的请求帧不被执行，直接输出 Code 为 429 的回执帧，客户端可稍后重试：
+	//
+	//	{"Id":2,"Code":429,"Message":"too many requests","Data":null}
+	MaxConcurrentRequests int
+
+	// WriteTimeout 是写入每个帧的超时时间，小于等于 0 时使用 [DefaultWebSocketWriteTimeout] 。
+	// 对方长时间不读取而使写入超时时，连接被关闭，见 [websocket.Conn.WriteTimeout] 。
+	WriteTimeout time.Duration
+
+	// PingInterval 是发送 ping 帧的间隔，小于等于 0 时使用 [DefaultWebSocketPingInterval] 。
+	// 超过两个间隔没有收到对方的任何帧（包括 pong ）时，认为连接已失效，将其关闭。
+	PingInterval time.Duration
+
+	handlerFunc http.HandlerFunc
+}
+
+// DefaultWebSocketMaxConcurrentRequests 是 [WebSocketHandler.MaxConcurrentRequests] 的默认值。
+const DefaultWebSocketMaxConcurrentRequests = 16
+
+// DefaultWebSocketWriteTimeout 是 [WebSocketHandler.WriteTimeout] 的默认值。
+const DefaultWebSocketWriteTimeout = 10 * time.Second
+
+// DefaultWebSocketPingInterval 是 [WebSocketHandler.PingInterval] 的默认值。
+const DefaultWebSocketPingInterval = 30 * time.Second
+
+var _ http.Handler = (*WebSocketHandler)(nil)
+
+// NewWebSocketHandler 创建一个 [WebSocketHandler] ，其使用 handler 上注册的方法，并替换 handler 的 ApiResponseWriter 以输出回执帧。
+// logFinder 同 [webapi.CreateHandlerFunc] ，可为 nil 。
+//
+// 请求帧按 SlimAPI 协议转换为 HTTP 请求，故 handler 必须是 [NewSlimApiHandler] 创建的（可替换 ApiNameResolver 以外的成员），否则 panic 。
+// 其他协议（如 SlimAuth 需要对每个请求签名）不能通过请求帧表达。
+//
+// 可将其注册到与 HTTP 方式不同的路径上，例如：
+//
+//	h := slimapi.NewSlimApiHandler("api")
+//	e.Handle("/api", h, logFinder).RegisterMethods(Methods{})
+//	e.HandleGet("/ws", slimapi.NewWebSocketHandler(h, logFinder).ServeHTTP)
+func NewWebSocketHandler(handler webapi.ApiHandler, logFinder logx.LogFinder) *WebSocketHandler {
+	if !isSlimApiHandler(handler) {
+		panic("NewWebSocketHandler: the handler must be a SlimAPI handler")
+	}
+
+	wrapper := webapi.Wrap(handler)
+	wrapper.ApiResponseWriter = webSocketResponseWriter{}
+
+	return &WebSocketHandler{
+		handlerFunc: webapi.CreateHandlerFunc(wrapper, logFinder),
+	}
+}
+
+// 判断 handler 是否是 SlimAPI 协议的，即使用 SlimAPI 的 ApiNameResolver 。
+func isSlimApiHandler(handler webapi.ApiHandler) bool {
+	w, ok := handler.(*webapi.ApiHandlerWrapper)
+	if !ok {
+		return false
+	}
+
+	_, ok = w.ApiNameResolver.(*slimApiNameResolver)
+	return ok
+}
+
+// ServeHTTP implements http.Handler.ServeHTTP().
+func (x *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	checkOrigin := x.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+
+	if !checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	conn.MaxMessageSize = x.MaxMessageSize
+	conn.WriteTimeout = x.WriteTimeout
+	if conn.WriteTimeout <= 0 {
+		conn.WriteTimeout = DefaultWebSocketWriteTimeout
+	}
+
+	pingInterval := x.PingInterval
+	if pingInterval <= 0 {
+		pingInterval = DefaultWebSocketPingInterval
+	}
+	conn.ReadTimeout = 2 * pingInterval
+
+	maxConcurrent := x.MaxConcurrentRequests
+	if maxConcurrent <= 0 {
+		maxConcurrent = DefaultWebSocketMaxConcurrentRequests
+	}
+	sem := make(chan struct{}, maxConcurrent)
+
+	// 读取失败（包括连接关闭、读取超时）时，取消正在执行的请求，并等待它们结束。
+	ctx, cancel := context.WithCancel(r.Context())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+		conn.Close()
+	}()
+
+	// 定时发送 ping ，对方回复的 pong 刷新读取期限。写入失败时连接被关闭，读取随之失败。
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if conn.Ping(nil) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req WebSocketRequest
+		if typ != websocket.TextMessage || json.Unmarshal(data, &req) != nil || req.Method == "" {
+			conn.WriteMessage(websocket.TextMessage, buildWebSocketFrame(req.Id, []byte(`{"Code":400,"Message":"bad frame","Data":null}`)))
+			continue
+		}
+
+		// 不能阻塞在这里，否则无法继续读取帧（包括 pong 和 close ），也就不能发现连接已断开。
+		select {
+		case sem <- struct{}{}:
+		default:
+			conn.WriteMessage(websocket.TextMessage, buildWebSocketFrame(req.Id, []byte(`{"Code":429,"Message":"too many requests","Data":null}`)))
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			x.serveFrame(ctx, conn, r, req)
+		}()
+	}
+}
+
+// 将请求帧转换为一个 HTTP 请求，按 HTTP 请求的流程处理。
+func (x *WebSocketHandler) serveFrame(ctx context.Context, conn *websocket.Conn, upgrade *http.Request, frame WebSocketRequest) {
+	w := &webSocketFrameWriter{
+		conn:   conn,
+		id:     frame.Id,
+		header: make(http.Header),
+	}
+
+	// CreateHandlerFunc 已处理了绝大部分 panic ，这里兜底，不能让 goroutine 的 panic 使进程退出。
+	defer func() {
+		if recover() != nil {
+			w.Write(buildWebSocketFrame(frame.Id, []byte(`{"Code":500,"Message":"internal error","Data":null}`)))
+		}
+	}()
+
+	params := []byte(frame.Params)
+	if len(params) == 0 || string(params) == "null" {
+		params = []byte("{}")
+	}
+
+	// 保留 upgrade 请求的 query （如 token 、租户 ID ），只覆盖元参数。
+	query := upgrade.URL.Query()
+	query.Set(meta_Param_Method, frame.Method)
+	query.Set(meta_Param_Format, meta_RequestFormat_Json)
+
+	req := upgrade.Clone(ctx)
+	req.Method = http.MethodPost
+	req.URL.RawQuery = query.Encode()
+	req.RequestURI = req.URL.RequestURI()
+	req.Body = io.NopCloser(bytes.NewReader(params))
+	req.ContentLength = int64(len(params))
+	req.Header.Set(webapi.HttpHeaderContentType, webapi.ContentTypeJson)
+	for _, name := range []string{"Connection", "Upgrade", "Sec-WebSocket-Key", "Sec-WebSocket-Version", "Sec-WebSocket-Extensions"} {
+		req.Header.Del(name)
+	}
+
+	x.handlerFunc(w, req)
+}
+
+// 默认的 CheckOrigin 。
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// 作为每个请求帧的 http.ResponseWriter ，每次 Write 发送一个回执帧。
+type webSocketFrameWriter struct {
+	conn   *websocket.Conn
+	id     json.RawMessage
+	header http.Header
+}
+
+func (w *webSocketFrameWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *webSocketFrameWriter) WriteHeader(statusCode int) {}
+
+func (w *webSocketFrameWriter) Write(p []byte) (int, error) {
+	if err := w.conn.WriteMessage(websocket.TextMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// webSocketResponseWriter 实现 webapi.ApiResponseWriter ，输出 WebSocketHandler 的回执帧。
+type webSocketResponseWriter struct{}
+
+// WriteResponse 实现 webapi.ApiResponseWriter.WriteResponse 。
+func (x webSocketResponseWriter) WriteResponse(state *webapi.ApiState) {
+	if state.ResponseBody != nil {
+		return
+	}
+
+	var id json.RawMessage
+	if w, ok := state.RawResponse.(*webSocketFrameWriter); ok {
+		id = w.id
+	}
+
+	state.ResponseContentType = webapi.ContentTypeJson
+
+	if file, ok := state.Data.(*webapi.FileResponse); ok {
+		if file != nil {
+			state.AddCleanup(func() { file.Close() })
+		}
+
+		if state.Error == nil {
+			state.Error = errors.New("FileResponse is not supported by WebSocketHandler")
+		}
+		state.Data = nil
+	}
+
+	streaming, ok := state.Data.(webapi.StreamingResponse)
+	if !ok || state.Error != nil {
+		frame := x.buildFrame(state, id, state.Data, state.Error)
+		state.ResponseBody = func(yield func([]byte) bool) {
+			yield(frame)
+		}
+		return
+	}
+
+	state.ResponseBody = func(yield func([]byte) bool) {
+		onItem := func(data any, err error) streamStep {
+			if err != nil {
+				state.Error = err
+			}
+
+			// SSE 事件的字段在 WebSocket 中没有意义，仅输出数据。
+			_, data = webapi.UnwrapSseEvent(data)
+			frame := x.buildFrame(state, id, data, err)
+			if frame == nil {
+				return streamNext
+			}
+			return nextOrAbort(yield(frame))
+		}
+
+		// 不需要流的心跳，连接的存活由 WebSocketHandler 的 ping 检测；连接断开时 context 被取消，流随之结束。
+		if !iterateStream(state, streaming.Iter(), 0, onItem, nil) {
+			// 连接已断开。
+			return
+		}
+
+		yield(buildWebSocketFrame(id, []byte(`{"Code":1000,"Message":"","Data":null}`)))
+	}
+}
+
+func (x webSocketResponseWriter) buildFrame(state *webapi.ApiState, id json.RawMessage, callResult any, callError error) []byte {
+	response := state.Handler.BuildResponse(state, callResult, callError)
+	if response == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		webapi.PanicApiError(state, err, "json encoding error")
+	}
+
+	if len(b) == 0 || b[0] != '{' {
+		webapi.PanicApiError(state, nil, "the response must be a JSON object, got %s", b)
+	}
+	return buildWebSocketFrame(id, b)
+}
+
+// 在 JSON 对象 response 的开头加上 Id 字段。
+func buildWebSocketFrame(id json.RawMessage, response []byte) []byte {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(`{"Id":`)
+	buf.Write(id)
+	if len(response) > 2 {
+		buf.WriteByte(',')
+	}
+	buf.Write(response[1:])
+	return buf.Bytes()
+}
@@ -0,0 +1,261 @@
+package slimapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmstar/go-webapi"
+	"github.com/cmstar/go-webapi/webapitest"
+	"github.com/cmstar/go-webapi/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webSocketTestProvider struct {
+	release  chan struct{}
+	canceled chan struct{}
+}
+
+func (webSocketTestProvider) Plus(req struct{ A, B int }) int {
+	return req.A + req.B
+}
+
+func (webSocketTestProvider) Token(req struct {
+	Token string `from:"header=X-Token"`
+}) string {
+	return req.Token
+}
+
+func (webSocketTestProvider) Tenant(req struct{ Tenant string }) string {
+	return req.Tenant
+}
+
+func (webSocketTestProvider) Count(req struct{ N int }) webapi.EventStream[int] {
+	return func(yield func(int, error) bool) {
+		for i := 1; i <= req.N; i++ {
+			if !yield(i, nil) {
+				return
+			}
+		}
+		yield(0, errors.New("stream error"))
+	}
+}
+
+func (p webSocketTestProvider) Block() string {
+	<-p.release
+	return "released"
+}
+
+func (p webSocketTestProvider) Wait(state *webapi.ApiState) {
+	<-state.RawRequest.Context().Done()
+	close(p.canceled)
+}
+
+func (webSocketTestProvider) File() *webapi.FileResponse {
+	return webapi.NewBytesFileResponse("a.txt", []byte("a"))
+}
+
+// setup 若不为 nil ，用于设置 WebSocketHandler 。
+func newWebSocketTestServer(t *testing.T, p webSocketTestProvider, setup func(*WebSocketHandler)) string {
+	h := NewSlimApiHandler("")
+	engine := webapi.NewEngine()
+	engine.Handle("/api", h, nil).RegisterMethods(p)
+
+	ws := NewWebSocketHandler(h, nil)
+	if setup != nil {
+		setup(ws)
+	}
+	engine.HandleGet("/ws", ws.ServeHTTP)
+
+	s := httptest.NewServer(engine)
+	t.Cleanup(s.Close)
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/ws"
+}
+
+func dialWebSocketTest(t *testing.T, url string, header http.Header) *websocket.Conn {
+	conn, _, err := websocket.Dial(context.Background(), url, header)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func sendWebSocketTest(t *testing.T, conn *websocket.Conn, frame string) {
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(frame)))
+}
+
+func readWebSocketTest(t *testing.T, conn *websocket.Conn) string {
+	conn.NetConn().SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	p := webSocketTestProvider{release: make(chan struct{}), canceled: make(chan struct{})}
+	url := newWebSocketTestServer(t, p, nil)
+
+	t.Run("call", func(t *testing.T) {
+		conn := dialWebSocketTest(t, url, http.Header{"X-Token": {"tk"}})
+
+		sendWebSocketTest(t, conn, `{"id":1,"method":"Plus","params":{"A":1,"B":2}}`)
+		require.Equal(t, `{"Id":1,"Code":0,"Message":"","Data":3}`, readWebSocketTest(t, conn))
+
+		// HTTP 头沿用 upgrade 请求的。
+		sendWebSocketTest(t, conn, `{"Id":"t","Method":"Token"}`)
+		require.Equal(t, `{"Id":"t","Code":0,"Message":"","Data":"tk"}`, readWebSocketTest(t, conn))
+	})
+
+	t.Run("upgrade-query", func(t *testing.T) {
+		// query 参数沿用 upgrade 请求的，其中的元参数被请求帧覆盖。
+		conn := dialWebSocketTest(t, url+"?tenant=t1&~method=Plus&~format=plain", nil)
+
+		sendWebSocketTest(t, conn, `{"Id":1,"Method":"Tenant"}`)
+		require.Equal(t, `{"Id":1,"Code":0,"Message":"","Data":"t1"}`, readWebSocketTest(t, conn))
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		conn := dialWebSocketTest(t, url, nil)
+
+		sendWebSocketTest(t, conn, `{"Id":"b","Method":"Block"}`)
+		sendWebSocketTest(t, conn, `{"Id":2,"Method":"Plus","Params":{"A":2,"B":2}}`)
+		require.Equal(t, `{"Id":2,"Code":0,"Message":"","Data":4}`, readWebSocketTest(t, conn))
+
+		close(p.release)
+		require.Equal(t, `{"Id":"b","Code":0,"Message":"","Data":"released"}`, readWebSocketTest(t, conn))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		conn := dialWebSocketTest(t, url, nil)
+
+		sendWebSocketTest(t, conn, `{"Id":3,"Method":"Count","Params":{"N":2}}`)
+		require.Equal(t, `{"Id":3,"Code":0,"Message":"","Data":1}`, readWebSocketTest(t, conn))
+		require.Equal(t, `{"Id":3,"Code":0,"Message":"","Data":2}`, readWebSocketTest(t, conn))
+		require.Equal(t, `{"Id":3,"Code":500,"Message":"internal error","Data":0}`, readWebSocketTest(t, conn))
+		require.Equal(t, `{"Id":3,"Code":1000,"Message":"","Data":null}`, readWebSocketTest(t, conn))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		conn := dialWebSocketTest(t, url, nil)
+
+		sendWebSocketTest(t, conn, `{"Id":4,"Method":"NotFound"}`)
+		require.Equal(t, `{"Id":4,"Code":400,"Message":"bad request","Data":null}`, readWebSocketTest(t, conn))
+
+		sendWebSocketTest(t, conn, `{"Id":5,"Method":"Plus","Params":{"A":"x"}}`)
+		require.Equal(t, `{"Id":5,"Code":400,"Message":"bad request","Data":null}`, readWebSocketTest(t, conn))
+
+		sendWebSocketTest(t, conn, `{"Id":6,"Method":"File"}`)
+		require.Equal(t, `{"Id":6,"Code":500,"Message":"internal error","Data":null}`, readWebSocketTest(t, conn))
+
+		sendWebSocketTest(t, conn, `{"Id":7}`)
+		require.Equal(t, `{"Id":7,"Code":400,"Message":"bad frame","Data":null}`, readWebSocketTest(t, conn))
+
+		sendWebSocketTest(t, conn, `not json`)
+		require.Equal(t, `{"Id":null,"Code":400,"Message":"bad frame","Data":null}`, readWebSocketTest(t, conn))
+
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte(`{}`)))
+		require.Equal(t, `{"Id":null,"Code":400,"Message":"bad frame","Data":null}`, readWebSocketTest(t, conn))
+	})
+
+	t.Run("cancel-on-close", func(t *testing.T) {
+		conn := dialWebSocketTest(t, url, nil)
+
+		sendWebSocketTest(t, conn, `{"Id":8,"Method":"Wait"}`)
+		time.Sleep(10 * time.Millisecond)
+		conn.Close()
+
+		select {
+		case <-p.canceled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the context is not canceled")
+		}
+	})
+
+	t.Run("origin", func(t *testing.T) {
+		_, resp, err := websocket.Dial(context.Background(), url, http.Header{"Origin": {"http://evil.example"}})
+		require.Error(t, err)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		host := strings.TrimPrefix(strings.TrimSuffix(url, "/ws"), "ws://")
+		conn := dialWebSocketTest(t, url, http.Header{"Origin": {"http://" + host}})
+		sendWebSocketTest(t, conn, `{"Id":9,"Method":"Plus"}`)
+		require.Equal(t, `{"Id":9,"Code":0,"Message":"","Data":0}`, readWebSocketTest(t, conn))
+	})
+}
+
+func TestNewWebSocketHandler(t *testing.T) {
+	require.NotPanics(t, func() { NewWebSocketHandler(NewSlimApiHandler(""), nil) })
+
+	// 不是 SlimAPI 协议的 handler ，如替换了 ApiNameResolver 的。
+	other := NewSlimApiHandler("")
+	other.ApiNameResolver = webapitest.NoOpHandler
+	require.PanicsWithValue(t, "NewWebSocketHandler: the handler must be a SlimAPI handler", func() { NewWebSocketHandler(other, nil) })
+	require.Panics(t, func() { NewWebSocketHandler(webapitest.NoOpHandler, nil) })
+}
+
+func TestWebSocketHandler_MaxConcurrentRequests(t *testing.T) {
+	p := webSocketTestProvider{release: make(chan struct{}), canceled: make(chan struct{})}
+	url := newWebSocketTestServer(t, p, func(h *WebSocketHandler) { h.MaxConcurrentRequests = 1 })
+	conn := dialWebSocketTest(t, url, nil)
+
+	// 达到上限时，后续的请求直接被拒绝，读取不受影响。
+	sendWebSocketTest(t, conn, `{"Id":"b","Method":"Block"}`)
+	time.Sleep(50 * time.Millisecond)
+	sendWebSocketTest(t, conn, `{"Id":2,"Method":"Plus","Params":{"A":2,"B":2}}`)
+	require.Equal(t, `{"Id":2,"Code":429,"Message":"too many requests","Data":null}`, readWebSocketTest(t, conn))
+
+	close(p.release)
+	require.Equal(t, `{"Id":"b","Code":0,"Message":"","Data":"released"}`, readWebSocketTest(t, conn))
+
+	// 执行完毕后可以继续请求。
+	sendWebSocketTest(t, conn, `{"Id":3,"Method":"Plus","Params":{"A":2,"B":2}}`)
+	require.Equal(t, `{"Id":3,"Code":0,"Message":"","Data":4}`, readWebSocketTest(t, conn))
+
+	// 达到上限时，连接关闭仍能取消正在执行的请求。
+	sendWebSocketTest(t, conn, `{"Id":4,"Method":"Wait"}`)
+	time.Sleep(50 * time.Millisecond)
+	sendWebSocketTest(t, conn, `{"Id":5,"Method":"Wait"}`)
+	require.Equal(t, `{"Id":5,"Code":429,"Message":"too many requests","Data":null}`, readWebSocketTest(t, conn))
+	conn.Close()
+
+	select {
+	case <-p.canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context is not canceled")
+	}
+}
+
+func TestWebSocketHandler_PingInterval(t *testing.T) {
+	p := webSocketTestProvider{release: make(chan struct{}), canceled: make(chan struct{})}
+	url := newWebSocketTestServer(t, p, func(h *WebSocketHandler) { h.PingInterval = 20 * time.Millisecond })
+
+	t.Run("alive", func(t *testing.T) {
+		// ReadMessage 自动回复 pong ，连接在多个间隔之后仍可用。
+		conn := dialWebSocketTest(t, url, nil)
+		sendWebSocketTest(t, conn, `{"Id":1,"Method":"Block"}`)
+		time.AfterFunc(200*time.Millisecond, func() { close(p.release) })
+		require.Equal(t, `{"Id":1,"Code":0,"Message":"","Data":"released"}`, readWebSocketTest(t, conn))
+	})
+
+	t.Run("no-pong", func(t *testing.T) {
+		// 不读取，也就不回复 pong ，模拟已失效的连接：服务端关闭连接并取消请求。
+		conn := dialWebSocketTest(t, url, nil)
+		sendWebSocketTest(t, conn, `{"Id":2,"Method":"Wait"}`)
+
+		select {
+		case <-p.canceled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the context is not canceled")
+		}
+	})
+}
+
+func TestBuildWebSocketFrame(t *testing.T) {
+	assert.Equal(t, `{"Id":null,"A":1}`, string(buildWebSocketFrame(nil, []byte(`{"A":1}`))))
+	assert.Equal(t, `{"Id":[1,2]}`, string(buildWebSocketFrame(json.RawMessage(`[1,2]`), []byte(`{}`))))
+}
@@ -11,7 +11,10 @@ import (
 func NewSlimAuthApiDecoder() webapi.ApiDecoder {
 	return webapi.NewArgumentDecoderPipeline(
 		authorizationArgumentDecoder{},
+		slimapi.LongPollArgumentDecoder,
+		slimapi.StreamArgumentDecoder,
 		slimapi.StructArgumentDecoder,
+		slimapi.NamedArgumentDecoder,
 	)
 }
 
@@ -16,15 +16,24 @@ type SlimAuthInvoker[TParam, TData any] struct {
 
 // SlimAuthInvokerOp 用于初始化 [SlimAuthInvoker] 。
 type SlimAuthInvokerOp struct {
-	Uri        string // 目标 URL 。
+	Uri        string // 目标 URL 。若指定了 Balancer ，则为追加在节点基础 URL 后面的相对路径。
 	Key        string // SlimAuth 协议的 key 。
 	Secret     string // SlimAuth 协议的 secret 。
 	AuthScheme string // Authorization 头的 <scheme> 部分，为空时自动使用默认值。
+
+	// 若不为 nil ，则请求通过此 [slimapi.LoadBalancer] 在多个节点间分发。签名针对每次实际发送的请求单独计算。
+	Balancer *slimapi.LoadBalancer
 }
 
 // SlimAuthInvoker 创建一个 [SlimAuthInvoker] 实例。
 func NewSlimAuthInvoker[TParam, TData any](op SlimAuthInvokerOp) *SlimAuthInvoker[TParam, TData] {
-	inner := slimapi.NewSlimApiInvoker[TParam, TData](op.Uri)
+	var inner *slimapi.SlimApiInvoker[TParam, TData]
+	if op.Balancer != nil {
+		inner = slimapi.NewBalancedSlimApiInvoker[TParam, TData](op.Balancer, op.Uri)
+	} else {
+		inner = slimapi.NewSlimApiInvoker[TParam, TData](op.Uri)
+	}
+
 	inner.RequestSetup = func(r *http.Request) error {
 		timestamp := time.Now().Unix()
 		signResult := AppendSign(r, op.Key, op.Secret, op.AuthScheme, timestamp)
@@ -35,8 +35,8 @@ func NewSlimAuthApiNameResolver(authScheme string, secretFinder SecretFinderFunc
 
 // FillMethod implements [webapi.ApiNameResolver.FillMethod].
 func (x slimAuthApiNameResolver) FillMethod(state *webapi.ApiState) {
+	// 签名需读取 body ，而 body 的大小限制可以是方法级别的，故在校验签名的过程中解析方法，见 verifySignature 。
 	x.verifySignature(state)
-	x.raw.FillMethod(state)
 }
 
 func (x slimAuthApiNameResolver) verifySignature(state *webapi.ApiState) {
@@ -59,6 +59,14 @@ func (x slimAuthApiNameResolver) verifySignature(state *webapi.ApiState) {
 		panic(webapi.CreateBadRequestError(state, nil, "unknown key"))
 	}
 
+	// 读取 body 前先限制其大小。限制可以是方法级别的，需先解析出方法，此时不检查方法是否存在。
+	// 在此之前已校验了 key ，未知 key 的请求不能借助方法级别的限制探测方法是否存在。
+	x.raw.FillMethod(state)
+	if m, ok := state.Handler.GetMethod(state.Name); ok {
+		state.Method = m
+	}
+	webapi.LimitRequestBody(state)
+
 	// 后续走 SlimAPI 的 decode 过程，需要重读 body 。
 	signResult := Sign(r, true, secret, auth.Timestamp)
 
@@ -76,6 +84,9 @@ func (x slimAuthApiNameResolver) verifySignature(state *webapi.ApiState) {
 		panic(webapi.CreateBadRequestError(state, signResult.Cause, "unsupported Content-Type"))
 
 	case SignResultType_InvalidRequestBody:
+		if e, ok := webapi.AsBodyTooLargeError(state, signResult.Cause); ok {
+			panic(e)
+		}
 		panic(webapi.CreateBadRequestError(state, signResult.Cause, "invalid request body"))
 	}
 
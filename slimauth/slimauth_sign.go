@@ -5,6 +5,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -274,6 +275,11 @@ func buildDataToSign(r *http.Request, rewindBody bool, timestamp int64) ([]byte,
 		}
 
 		if err != nil {
+			// body 超过了 webapi.LimitRequestBody 限制的大小，属于请求的问题。
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, SignResultType_InvalidRequestBody, err
+			}
 			panic(err)
 		}
 
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -86,12 +87,15 @@ func finderForTest(accessKey string) string {
 	}
 }
 
-// op.SecretFinder 不需要给定，会自动赋值。
-func newTestServer(op SlimAuthApiHandlerOption) *httptest.Server {
+// op.SecretFinder 不需要给定，会自动赋值。 setup 可用于定制 handler 。
+func newTestServer(op SlimAuthApiHandlerOption, setup ...func(h *webapi.ApiHandlerWrapper)) *httptest.Server {
 	op.SecretFinder = finderForTest
 
 	handler := NewSlimAuthApiHandler(op)
 	handler.RegisterMethods(methodProvider{})
+	for _, f := range setup {
+		f(handler)
+	}
 
 	logger := logx.NopLogger
 	handlerFunc := webapi.CreateHandlerFunc(handler, logx.NewSingleLoggerLogFinder(logger))
@@ -159,6 +163,57 @@ func TestSlimAuthApiHandler_errors(t *testing.T) {
 		testRequest(t, r, `{"Code":400,"Message":"invalid request body","Data":null}`)
 	})
 
+	t.Run("BodyTooLarge", func(t *testing.T) {
+		s := newTestServer(SlimAuthApiHandlerOption{TimeChecker: NoTimeChecker}, func(h *webapi.ApiHandlerWrapper) {
+			h.MaxBodySize = 3
+		})
+
+		r, _ := http.NewRequest(http.MethodPost, s.URL+"?Plus", strings.NewReader("x=1&y=2"))
+		r.Header.Set(HttpHeaderAuthorization, "SLIM-AUTH Key=key, Sign=sign, Timestamp=1")
+		r.Header.Set(webapi.HttpHeaderContentType, webapi.ContentTypeForm)
+
+		testRequest(t, r, `{"Code":413,"Message":"request body too large","Data":null}`)
+	})
+
+	t.Run("MethodBodyTooLarge", func(t *testing.T) {
+		s := newTestServer(SlimAuthApiHandlerOption{TimeChecker: NoTimeChecker}, func(h *webapi.ApiHandlerWrapper) {
+			m, _ := h.GetMethod("Plus")
+			m.Meta = m.Meta.With(webapi.ApiMetaMaxBodySize, int64(3))
+			h.RegisterMethod(m)
+		})
+
+		// key 的校验先于方法的解析，未知 key 的请求不能通过方法级别的限制探测方法是否存在。
+		r, _ := http.NewRequest(http.MethodPost, s.URL+"?Plus", strings.NewReader("x=1&y=2"))
+		r.Header.Set(HttpHeaderAuthorization, "SLIM-AUTH Key=unknown, Sign=sign, Timestamp=1")
+		r.Header.Set(webapi.HttpHeaderContentType, webapi.ContentTypeForm)
+		testRequest(t, r, `{"Code":400,"Message":"unknown key","Data":null}`)
+
+		r, _ = http.NewRequest(http.MethodPost, s.URL+"?Plus", strings.NewReader("x=1&y=2"))
+		r.Header.Set(webapi.HttpHeaderContentType, webapi.ContentTypeForm)
+		require.Equal(t, SignResultType_OK, AppendSign(r, _key, _secret, "", _timestamp).Type)
+		testRequest(t, r, `{"Code":413,"Message":"request body too large","Data":null}`)
+	})
+
+	t.Run("MethodBodyLimitAboveHandler", func(t *testing.T) {
+		// 方法级别的限制可以比 handler 级别的宽松，计算签名时即使用方法级别的限制。
+		s := newTestServer(SlimAuthApiHandlerOption{TimeChecker: NoTimeChecker}, func(h *webapi.ApiHandlerWrapper) {
+			h.MaxBodySize = 5
+			m, _ := h.GetMethod("Plus")
+			m.Meta = m.Meta.With(webapi.ApiMetaMaxBodySize, int64(100))
+			h.RegisterMethod(m)
+		})
+
+		r, _ := http.NewRequest(http.MethodPost, s.URL+"?Plus", strings.NewReader("x=1&y=2&pad="+strings.Repeat("x", 50)))
+		r.Header.Set(webapi.HttpHeaderContentType, webapi.ContentTypeForm)
+		require.Equal(t, SignResultType_OK, AppendSign(r, _key, _secret, "", _timestamp).Type)
+		testRequest(t, r, `{"Code":0,"Message":"","Data":3}`)
+
+		r, _ = http.NewRequest(http.MethodPost, s.URL+"?GetKey", strings.NewReader("pad="+strings.Repeat("x", 50)))
+		r.Header.Set(webapi.HttpHeaderContentType, webapi.ContentTypeForm)
+		require.Equal(t, SignResultType_OK, AppendSign(r, _key, _secret, "", _timestamp).Type)
+		testRequest(t, r, `{"Code":413,"Message":"request body too large","Data":null}`)
+	})
+
 	t.Run("BadSign", func(t *testing.T) {
 		auth := BuildAuthorizationHeader(Authorization{
 			Key:       _key,
@@ -311,3 +366,38 @@ func TestSlimAuthApiHandler_customScheme(t *testing.T) {
 		testRequest(t, r, `{"Code":0,"Message":"","Data":33}`)
 	})
 }
+
+func TestSlimAuthApiHandler_longPoll(t *testing.T) {
+	h := NewSlimAuthApiHandler(SlimAuthApiHandlerOption{
+		SecretFinder: finderForTest,
+	})
+	h.RegisterMethod(webapi.ApiMethod{
+		Name: "Poll",
+		Value: reflect.ValueOf(func(lp webapi.LongPoll) string {
+			return lp.Cursor + "/" + lp.Wait.String()
+		}),
+	})
+
+	e := webapi.NewEngine()
+	e.Handle("/{~method}", h, nil).SetLongPollMaxWait("Poll", 5*time.Second)
+	s := httptest.NewServer(e)
+	defer s.Close()
+
+	poll := func(query string, body map[string]any) string {
+		invoker := NewSlimAuthInvoker[map[string]any, string](SlimAuthInvokerOp{
+			Uri:    s.URL + "/Poll" + query,
+			Key:    _key,
+			Secret: _secret,
+		})
+		res, err := invoker.Do(body)
+		require.NoError(t, err)
+		return res
+	}
+
+	require.Equal(t, "c/3s", poll("?~cursor=c&~wait=3", nil))
+	require.Equal(t, "c/5s", poll("?~cursor=c&~wait=60", nil))
+	require.Equal(t, "/5s", poll("", nil))
+
+	// 字段不能从 body 中读取。
+	require.Equal(t, "/5s", poll("", map[string]any{"Cursor": "c", "Wait": 1}))
+}
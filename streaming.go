@@ -3,6 +3,9 @@ package webapi
 import (
 	"io"
 	"iter"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // StreamingResponse 描述以流式方式输出 HTTP body 。
@@ -12,6 +15,7 @@ type StreamingResponse interface {
 	// 支持的 Content-Type 比如：
 	//   - text/event-stream
 	//   - application/ndjson
+	//   - application/json; stream=array
 	ContentType() string
 
 	// Iter 返回当前实例的非泛型版本。此方法用于将泛型类型转换为 any 。
@@ -28,6 +32,29 @@ type StreamingResponse interface {
 	WriteFinalBlock(w io.Writer)
 }
 
+// HeartbeatWriter 由 [EventStream] 、 [NdJson] 和 [JsonArray] 实现，用于在流式输出的间歇写入心跳，
+// 避免代理服务器等因连接长时间没有数据而将其断开。见 [StreamHeartbeat] 。
+type HeartbeatWriter interface {
+	// WriteHeartbeat 将一个心跳写入给定的 w ，心跳应被客户端忽略。
+	WriteHeartbeat(w io.Writer)
+}
+
+// StreamHeartbeat 返回当前请求的流式输出的心跳间隔，小于等于 0 表示不发送心跳。
+// 优先使用 [ApiState.Method] 上的元数据 [ApiMetaStreamHeartbeat] ；
+// 其次，若 [ApiState.Handler] 是 [*ApiHandlerWrapper] ，使用 [ApiHandlerWrapper.StreamHeartbeat] 。
+//
+// 方法返回的流实现了 [HeartbeatWriter] 时，若在此间隔内没有输出数据，则输出一个心跳。
+func StreamHeartbeat(state *ApiState) time.Duration {
+	if interval, ok := GetApiMetaValue[time.Duration](state.Method.Meta, ApiMetaStreamHeartbeat); ok {
+		return interval
+	}
+
+	if w, ok := state.Handler.(*ApiHandlerWrapper); ok {
+		return w.StreamHeartbeat
+	}
+	return 0
+}
+
 // EventStreamEndCode 表示 SSE 流结束的事件代码。
 //
 // 思路同 slimapi 返回 HTTP 200 状态码，此值与 WebSocket 正常关闭的状态码一致。
@@ -51,11 +78,13 @@ const EventStreamEndCode = 1000
 //
 // 其中，除了最后一段外，每段输出通常是 [ApiResponse] （或其衍生结构）的 JSON 序列化结果。
 // 流结束时，固定发送一个 END 事件，其 event 与 data 均固定， Code 为 1000 （定义在 [EventStreamEndCode] ）。
+// 数据为 [SseEvent] 时，可为每段输出附加 id 、 event 和 retry 行。
 //
 // 通常出现错误时，输出流就终止了，故 error 是数据的最后一段；但并不严格要求此行为。
 type EventStream[DATA any] func(yield func(data DATA, err error) bool)
 
 var _ StreamingResponse = (*EventStream[any])(nil)
+var _ HeartbeatWriter = (*EventStream[any])(nil)
 
 // ContentType implements [StreamingResponse.ContentType].
 func (x EventStream[DATA]) ContentType() string {
@@ -64,11 +93,33 @@ func (x EventStream[DATA]) ContentType() string {
 
 // WriteJsonBlock implements [StreamingResponse.WriteJsonBlock].
 func (x EventStream[DATA]) WriteJsonBlock(w io.Writer, jsonBlock []byte) {
+	x.WriteEvent(w, SseFields{}, jsonBlock)
+}
+
+// WriteEvent implements [SseEventWriter.WriteEvent].
+func (x EventStream[DATA]) WriteEvent(w io.Writer, fields SseFields, jsonBlock []byte) {
+	if fields.Id != "" {
+		io.WriteString(w, "id: "+sseLineReplacer.Replace(fields.Id)+"\n")
+	}
+
+	if fields.Event != "" {
+		io.WriteString(w, "event: "+sseLineReplacer.Replace(fields.Event)+"\n")
+	}
+
+	if fields.Retry > 0 {
+		io.WriteString(w, "retry: "+strconv.FormatInt(fields.Retry.Milliseconds(), 10)+"\n")
+	}
+
 	w.Write([]byte("data: "))
 	w.Write(jsonBlock)
 	w.Write([]byte{'\n', '\n'})
 }
 
+// WriteHeartbeat implements [HeartbeatWriter.WriteHeartbeat]. 输出一个 SSE 的注释行。
+func (x EventStream[DATA]) WriteHeartbeat(w io.Writer) {
+	w.Write([]byte(": heartbeat\n\n"))
+}
+
 // WriteFinalBlock implements [StreamingResponse.WriteFinalBlock].
 func (x EventStream[DATA]) WriteFinalBlock(w io.Writer) {
 	w.Write([]byte("event: END\ndata: {\"Code\":1000,\"Message\":\"\",\"Data\":null}\n\n"))
@@ -85,6 +136,76 @@ func (x EventStream[DATA]) Iter() iter.Seq2[any, error] {
 	}
 }
 
+// SseFields 是 SSE 事件中 data 以外的字段，见 [SseEvent] 。
+type SseFields struct {
+	// Id 是事件的 id ，输出为 id 行。
+	Id string
+
+	// Event 是事件的名称，输出为 event 行。
+	Event string
+
+	// Retry 是建议客户端断线重连前等待的时间，输出为 retry 行（毫秒）。
+	Retry time.Duration
+}
+
+// SseEvent 用作 [EventStream] 的数据时，可为每段输出指定 SSE 事件的 id 、名称和重连等待时间。
+// 例如方法返回 EventStream[SseEvent[Item]] ，给出 SseEvent[Item]{Id: "5", Retry: 3 * time.Second, Data: item} ，输出：
+//
+//	id: 5
+//	retry: 3000
+//	data: {"Code":0,"Message":"","Data":<item>}
+//
+// 其中 Data 字段按通常的方式放在信封中。为零值的字段，对应的行不输出； Id 和 Event 中的换行符被移除。
+//
+// 客户端断线重连时，在 Last-Event-ID 头中给出最后收到的事件的 id ，方法可通过 [LastEventId] 类型的参数获取，从断点继续输出。
+// 用于 [NdJson] 等其他格式时，仅输出 Data 。
+//
+// DATA 为 any 时（即 EventStream[SseEvent[any]] ），各段的 Data 可以是不同的类型，以 Event 区分，如 progress 、 result 。
+// END 由流的结束块使用，不应作为 Event 。
+type SseEvent[DATA any] struct {
+	Id    string        // 见 [SseFields.Id] 。
+	Event string        // 见 [SseFields.Event] 。
+	Retry time.Duration // 见 [SseFields.Retry] 。
+	Data  DATA          // 放在信封中的数据。
+}
+
+// SseEventData 由 [SseEvent] 实现，用于在非泛型的上下文中读取事件的字段和数据。
+type SseEventData interface {
+	// SseEventData 返回事件 data 以外的字段，及放在信封中的数据。
+	SseEventData() (fields SseFields, data any)
+}
+
+var _ SseEventData = SseEvent[any]{}
+
+// SseEventData implements [SseEventData.SseEventData].
+func (x SseEvent[DATA]) SseEventData() (fields SseFields, data any) {
+	return SseFields{Id: x.Id, Event: x.Event, Retry: x.Retry}, x.Data
+}
+
+// UnwrapSseEvent 若 data 是 [SseEventData] ，则返回其字段和放在信封中的数据；否则返回零值的字段和 data 本身。
+func UnwrapSseEvent(data any) (SseFields, any) {
+	if ev, ok := data.(SseEventData); ok {
+		return ev.SseEventData()
+	}
+	return SseFields{}, data
+}
+
+// SseEventWriter 由 [EventStream] 实现，用于写入带有 [SseFields] 的事件。
+type SseEventWriter interface {
+	// WriteEvent 将 jsonBlock 作为 data ，连同 fields 写为一个 SSE 事件。
+	WriteEvent(w io.Writer, fields SseFields, jsonBlock []byte)
+}
+
+var _ SseEventWriter = (*EventStream[any])(nil)
+
+// SSE 的字段值不能包含换行。
+var sseLineReplacer = strings.NewReplacer("\r", "", "\n", "")
+
+// LastEventId 是客户端断线重连时，在 Last-Event-ID 头中给出的最后收到的 SSE 事件的 id ，没有时为空字符串。
+//
+// 方法的参数表中可以有此类型的参数，由 [LastEventIdArgumentDecoder] 赋值，用于配合 [SseEvent] 从断点继续输出。
+type LastEventId string
+
 // NdJson 表示 HTTP 回复中以 Content-Type: application/x-ndjson 格式传输的数据。
 //
 // API 方法可将此类型作为返回值，以使用 Newline Delimited JSON 格式，以流的形式输出结果。
@@ -102,6 +223,7 @@ func (x EventStream[DATA]) Iter() iter.Seq2[any, error] {
 type NdJson[DATA any] func(yield func(data DATA, err error) bool)
 
 var _ StreamingResponse = (*NdJson[any])(nil)
+var _ HeartbeatWriter = (*NdJson[any])(nil)
 
 // ContentType implements [StreamingResponse.ContentType].
 func (x NdJson[DATA]) ContentType() string {
@@ -114,6 +236,11 @@ func (x NdJson[DATA]) WriteJsonBlock(w io.Writer, jsonBlock []byte) {
 	w.Write([]byte{'\n'})
 }
 
+// WriteHeartbeat implements [HeartbeatWriter.WriteHeartbeat]. 输出一个空行，读取时应跳过空行。
+func (x NdJson[DATA]) WriteHeartbeat(w io.Writer) {
+	w.Write([]byte{'\n'})
+}
+
 // WriteFinalBlock implements [StreamingResponse.WriteFinalBlock].
 func (x NdJson[DATA]) WriteFinalBlock(w io.Writer) {
 	// NDJSON 不需要额外写入结束块。 HTTP 输出流结束就算结束了。
@@ -129,3 +256,95 @@ func (x NdJson[DATA]) Iter() iter.Seq2[any, error] {
 		}
 	}
 }
+
+// JsonArray 表示 HTTP 回复中以 Content-Type: application/json 格式（附加 stream=array 参数，见 [ContentTypeJsonArrayStream] ）
+// 逐段输出的一个 JSON 文档。
+//
+// API 方法可将此类型作为返回值，使一般的 JSON 客户端也能读取大量的数据，而服务端不必将全部数据放在内存中。
+//
+// 整个 response 是一个 [ApiResponse] ，其 Data 是各段数据组成的数组。与通常的信封不同， Data 字段放在最前面：
+//
+//	{"Data":[{...},{...},...],"Code":0,"Message":""}
+//
+// 由于 Code 和 Message 在所有数据之后输出，出现 error 时，仍能给出对应的错误信息：
+// 数组中保留此前已输出的数据， Code 和 Message 对应该 error ，该 error 之后迭代停止（ yield 返回 false ）。例如：
+//
+//	{"Data":[{...},{...}],"Code":500,"Message":"internal error"}
+//
+// 若输出流在中途被中断（如 panic 或连接断开），得到的是不完整的 JSON ，客户端解析时会出错。
+//
+// 由于数组元素之间需要逗号分隔，各段的输出需要跨段的状态，通过 [JsonArrayWriter] 完成；
+// 其 WriteJsonBlock 和 WriteFinalBlock 不执行任何操作。
+type JsonArray[DATA any] func(yield func(data DATA, err error) bool)
+
+var _ StreamingResponse = (*JsonArray[any])(nil)
+var _ HeartbeatWriter = (*JsonArray[any])(nil)
+var _ JsonArrayWriter = (*JsonArray[any])(nil)
+
+// ContentType implements [StreamingResponse.ContentType].
+func (x JsonArray[DATA]) ContentType() string {
+	return ContentTypeJsonArrayStream
+}
+
+// WriteJsonBlock implements [StreamingResponse.WriteJsonBlock]. 不执行任何操作，见 [JsonArrayWriter] 。
+func (x JsonArray[DATA]) WriteJsonBlock(w io.Writer, jsonBlock []byte) {
+}
+
+// WriteFinalBlock implements [StreamingResponse.WriteFinalBlock]. 不执行任何操作，见 [JsonArrayWriter] 。
+func (x JsonArray[DATA]) WriteFinalBlock(w io.Writer) {
+}
+
+// WriteHeartbeat implements [HeartbeatWriter.WriteHeartbeat]. 输出一个换行，其作为 JSON 中的空白被忽略。
+func (x JsonArray[DATA]) WriteHeartbeat(w io.Writer) {
+	w.Write([]byte{'\n'})
+}
+
+// WriteArrayStart implements [JsonArrayWriter.WriteArrayStart].
+func (x JsonArray[DATA]) WriteArrayStart(w io.Writer) {
+	w.Write([]byte(`{"Data":[`))
+}
+
+// WriteArrayItem implements [JsonArrayWriter.WriteArrayItem].
+func (x JsonArray[DATA]) WriteArrayItem(w io.Writer, index int, itemJson []byte) {
+	if index > 0 {
+		w.Write([]byte{','})
+	}
+	w.Write(itemJson)
+}
+
+// WriteArrayEnd implements [JsonArrayWriter.WriteArrayEnd].
+func (x JsonArray[DATA]) WriteArrayEnd(w io.Writer, envelope []byte) {
+	w.Write([]byte{']'})
+
+	// envelope 形如 {"Code":0,"Message":""} ，将其成员接在 Data 之后。
+	if len(envelope) > 2 {
+		w.Write([]byte{','})
+		w.Write(envelope[1:])
+	} else {
+		w.Write([]byte{'}'})
+	}
+}
+
+// Iter implements [StreamingResponse.Iter].
+func (x JsonArray[DATA]) Iter() iter.Seq2[any, error] {
+	return func(yield func(data any, err error) bool) {
+		for d, e := range x {
+			if !yield(d, e) {
+				return
+			}
+		}
+	}
+}
+
+// JsonArrayWriter 由 [JsonArray] 实现，用于逐段输出一个 Data 为数组的 JSON 文档。
+type JsonArrayWriter interface {
+	// WriteArrayStart 写入文档的开头，至数组的第一个元素之前。
+	WriteArrayStart(w io.Writer)
+
+	// WriteArrayItem 写入数组的一个元素， index 是其下标（从 0 开始）， itemJson 是数据的 JSON 序列化结果。
+	WriteArrayItem(w io.Writer, index int, itemJson []byte)
+
+	// WriteArrayEnd 写入文档的剩余部分。 envelope 是不含 Data 字段的 [ApiResponse] （或其衍生结构）的 JSON 序列化结果，
+	// 形如 {"Code":0,"Message":""} 。
+	WriteArrayEnd(w io.Writer, envelope []byte)
+}
@@ -0,0 +1,53 @@
+package webapi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamHeartbeat(t *testing.T) {
+	h := &ApiHandlerWrapper{StreamHeartbeat: time.Second}
+	require.Equal(t, time.Duration(0), StreamHeartbeat(&ApiState{}))
+	require.Equal(t, time.Second, StreamHeartbeat(&ApiState{Handler: h}))
+	require.Equal(t, time.Second, StreamHeartbeat(&ApiState{Handler: Wrap(h)}))
+
+	m := ApiMethod{Meta: NewApiMeta(map[string]any{ApiMetaStreamHeartbeat: 2 * time.Second})}
+	require.Equal(t, 2*time.Second, StreamHeartbeat(&ApiState{Handler: h, Method: m}))
+
+	m = ApiMethod{Meta: NewApiMeta(map[string]any{ApiMetaStreamHeartbeat: time.Duration(-1)})}
+	require.Equal(t, time.Duration(-1), StreamHeartbeat(&ApiState{Handler: h, Method: m}))
+}
+
+func TestHeartbeatWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	EventStream[int](nil).WriteHeartbeat(buf)
+	require.Equal(t, ": heartbeat\n\n", buf.String())
+
+	buf.Reset()
+	NdJson[int](nil).WriteHeartbeat(buf)
+	require.Equal(t, "\n", buf.String())
+
+	buf.Reset()
+	JsonArray[int](nil).WriteHeartbeat(buf)
+	require.Equal(t, "\n", buf.String())
+}
+
+func TestJsonArray(t *testing.T) {
+	write := func(items []string, envelope string) string {
+		x := JsonArray[int](nil)
+		buf := new(bytes.Buffer)
+		x.WriteArrayStart(buf)
+		for i, v := range items {
+			x.WriteArrayItem(buf, i, []byte(v))
+		}
+		x.WriteArrayEnd(buf, []byte(envelope))
+		return buf.String()
+	}
+
+	require.Equal(t, `{"Data":[1,2],"Code":0,"Message":""}`, write([]string{"1", "2"}, `{"Code":0,"Message":""}`))
+	require.Equal(t, `{"Data":[]}`, write(nil, `{}`))
+	require.Equal(t, "application/json; stream=array", JsonArray[int](nil).ContentType())
+}
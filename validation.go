@@ -0,0 +1,507 @@
+package webapi
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+/*
+当前文件提供基于 struct tag 的参数校验。
+*/
+
+// ValidateTagName 是用于声明校验规则的 struct tag 的名称。
+const ValidateTagName = "validate"
+
+// OptionalValue 由记录参数是否给出的包装类型（如 slimapi.Optional ）实现。
+// [ValidateStruct] 将字段上的规则作用于其包装的值，而不是包装类型本身。
+type OptionalValue interface {
+	// ElemType 返回包装的值的类型。
+	ElemType() reflect.Type
+
+	// GetAny 返回包装的值，及是否给出了非 null 的值。
+	GetAny() (any, bool)
+}
+
+var typeOptionalValue = reflect.TypeOf((*OptionalValue)(nil)).Elem()
+
+// FieldError 描述一个字段未通过校验的原因。
+type FieldError struct {
+	// Field 是字段的路径，如 Name 、 Inner.Name 、 Items[0].Name 、 Map[key] 。
+	Field string
+
+	// Rule 是未通过的规则名称，如 required 、 min 。
+	Rule string
+
+	// Param 是规则的参数，如 min=1 中的 1 。没有参数时为空。
+	Param string
+
+	// Message 是错误描述，含字段路径，如“Name is required”。
+	Message string
+}
+
+// ValidationErrors 是一组 [FieldError] ，由 [ValidateStruct] 返回。
+type ValidationErrors []FieldError
+
+var _ error = (ValidationErrors)(nil)
+
+// Error 实现 error 接口，返回各个错误的描述，使用“; ”分隔。
+func (x ValidationErrors) Error() string {
+	msgs := make([]string, len(x))
+	for i, v := range x {
+		msgs[i] = v.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateStruct 根据 struct 字段上的 validate tag 校验给定的值，返回全部未通过校验的字段；全部通过时返回 nil 。
+// v 可以是 struct 或其指针；其他类型的值不做校验。
+//
+// 规则间使用逗号分隔，如 `validate:"required,min=1,max=100"` 。支持的规则：
+//   - required 值不能是零值：数值不为 0 、字符串/slice/map 不为空；指针只要求不为 nil ，其指向的值可以是零值。
+//   - min=N 、 max=N 数值的大小，或字符串（按字符计）、 slice 、 map 的长度的下限和上限。
+//   - len=N 字符串（按字符计）、 slice 、 map 的长度必须为 N 。
+//   - oneof=a b c 值必须是以空格分隔的选项之一，数值按其十进制文本比较。
+//   - email 字符串必须是 E-mail 地址。
+//   - regex=PATTERN 字符串必须匹配给定的正则表达式。由于表达式中可能有逗号，此规则必须放在最后。
+//
+// 值为零值且没有 required 规则的字段，跳过其他规则，即字段是可选的。
+//
+// 字段实现了 [OptionalValue] （如 slimapi.Optional ）时，规则作用于其包装的值： required 要求给出了非 null 的值，
+// 值可以是零值；未给出或为 null 时，跳过其他规则；给出了值时，即使是零值也执行其他规则。
+//
+// 字段为 struct 、 struct 的 slice/array 、值为 struct 的 map （或它们的指针、 [OptionalValue] ）时，会递归校验其元素。
+// 若 tag 存在错误，如规则不存在、参数格式错误，则 panic 。可通过 [PrepareValidateRules] 提前发现此类错误。
+func ValidateStruct(v any) ValidationErrors {
+	val := reflect.ValueOf(v)
+	var errs ValidationErrors
+	validateValue(val, "", &errs)
+	return errs
+}
+
+// PrepareValidateRules 解析并缓存 typ 及其字段、元素中（同 [ValidateStruct] 的递归范围）各 struct 的 validate tag ，
+// 若 tag 存在错误，则 panic 。
+//
+// [ValidateStruct] 在首次遇到一个类型时才解析其 tag ，错误的 tag 在请求到达时才被发现。
+// 在注册方法时对参数的类型调用此方法，可使错误在启动时即暴露， [NewBasicApiMethodRegister] 会这样做。
+func PrepareValidateRules(typ reflect.Type) {
+	prepareValidateRules(typ, make(map[reflect.Type]bool))
+}
+
+func prepareValidateRules(typ reflect.Type, visited map[reflect.Type]bool) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if visited[typ] {
+		return
+	}
+	visited[typ] = true
+
+	if elem, ok := optionalElemType(typ); ok {
+		prepareValidateRules(elem, visited)
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		getValidateRules(typ)
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.IsExported() || (f.Anonymous && isStructOrStructPtr(f.Type)) {
+				prepareValidateRules(f.Type, visited)
+			}
+		}
+
+	case reflect.Slice, reflect.Array, reflect.Map:
+		prepareValidateRules(typ.Elem(), visited)
+	}
+}
+
+// validateRule 是解析后的一条规则。
+type validateRule struct {
+	name  string
+	param string
+	check func(v reflect.Value) bool // 值已被解引用，不会是指针。
+	msg   string                     // 错误描述，不含字段名称。
+}
+
+// 缓存每个 struct 类型的字段的规则。 key 为 reflect.Type ， value 为 [][]validateRule ，下标与字段对应。
+var validateRuleCache sync.Map
+
+func validateValue(val reflect.Value, path string, errs *ValidationErrors) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return
+		}
+		val = val.Elem()
+	}
+
+	if opt, ok := asOptionalValue(val); ok {
+		if v, ok := opt.GetAny(); ok {
+			validateValue(reflect.ValueOf(v), path, errs)
+		}
+		return
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		validateStruct(val, path, errs)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			validateValue(val.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+
+	case reflect.Map:
+		iter := val.MapRange()
+		for iter.Next() {
+			validateValue(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), errs)
+		}
+	}
+}
+
+func validateStruct(val reflect.Value, path string, errs *ValidationErrors) {
+	typ := val.Type()
+	rules := getValidateRules(typ)
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() && !(f.Anonymous && isStructOrStructPtr(f.Type)) {
+			continue
+		}
+
+		fieldVal := val.Field(i)
+
+		// 内嵌的 struct 的字段视同当前 struct 的字段。
+		fieldPath := path
+		if !f.Anonymous {
+			if fieldPath != "" {
+				fieldPath += "."
+			}
+			fieldPath += f.Name
+		}
+
+		if !checkValidateRules(fieldVal, fieldPath, rules[i], errs) {
+			continue
+		}
+
+		validateValue(fieldVal, fieldPath, errs)
+	}
+}
+
+// 依次执行规则，遇到不通过的规则即停止。返回是否全部通过。
+func checkValidateRules(val reflect.Value, path string, rules []validateRule, errs *ValidationErrors) bool {
+	if len(rules) == 0 {
+		return true
+	}
+
+	fail := func(r validateRule) bool {
+		*errs = append(*errs, FieldError{
+			Field:   path,
+			Rule:    r.name,
+			Param:   r.param,
+			Message: path + " " + r.msg,
+		})
+		return false
+	}
+
+	check := func(val reflect.Value, rules []validateRule) bool {
+		for val.Kind() == reflect.Ptr && !val.IsNil() {
+			val = val.Elem()
+		}
+
+		for _, r := range rules {
+			if !r.check(val) {
+				return fail(r)
+			}
+		}
+		return true
+	}
+
+	required := rules[0].name == "required"
+
+	// OptionalValue 的规则作用于其包装的值：没有给出值（未给出或为 null ）时， required 不通过，跳过其他规则；
+	// 给出了值时，即使是零值也执行其他规则。
+	if opt, ok := asOptionalValue(val); ok {
+		v, ok := opt.GetAny()
+		inner := reflect.ValueOf(v)
+		if ok && inner.Kind() == reflect.Ptr && inner.IsNil() {
+			ok = false
+		}
+
+		if !ok || !inner.IsValid() {
+			if required {
+				return fail(rules[0])
+			}
+			return true
+		}
+
+		if required {
+			rules = rules[1:]
+		}
+		return check(inner, rules)
+	}
+
+	if !required && val.IsZero() {
+		return true
+	}
+
+	// 指针类型的字段， required 只要求其不为 nil ，指向的值可以是零值，以表示“给出了零值”。
+	if required && val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return fail(rules[0])
+		}
+		rules = rules[1:]
+	}
+
+	return check(val, rules)
+}
+
+func getValidateRules(typ reflect.Type) [][]validateRule {
+	if v, ok := validateRuleCache.Load(typ); ok {
+		return v.([][]validateRule)
+	}
+
+	res := make([][]validateRule, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag, ok := f.Tag.Lookup(ValidateTagName)
+		if !ok || tag == "" {
+			continue
+		}
+
+		res[i] = parseValidateTag(typ, f, tag)
+	}
+
+	validateRuleCache.Store(typ, res)
+	return res
+}
+
+func parseValidateTag(structType reflect.Type, f reflect.StructField, tag string) []validateRule {
+	fail := func(format string, args ...any) {
+		panic(fmt.Sprintf("validate tag of %v.%s: %s", structType, f.Name, fmt.Sprintf(format, args...)))
+	}
+
+	fieldType := f.Type
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	// OptionalValue 的规则作用于其包装的值。
+	if elem, ok := optionalElemType(fieldType); ok {
+		fieldType = elem
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+	}
+	kind := fieldType.Kind()
+
+	var rules []validateRule
+	for tag != "" {
+		// regex 的参数可以包含逗号，须在去掉前导空白后识别，如 "required, regex=^a,b$" 。
+		tag = strings.TrimLeft(tag, " ")
+
+		var part string
+		if strings.HasPrefix(tag, "regex=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		r := validateRule{name: name, param: param}
+
+		switch name {
+		case "required":
+			r.msg = "is required"
+			r.check = func(v reflect.Value) bool { return !v.IsZero() }
+
+		case "min", "max":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				fail("invalid parameter of %s: %q", name, param)
+			}
+
+			size := validateSizeFunc(kind)
+			if size == nil {
+				fail("%s is not supported on %v", name, f.Type)
+			}
+
+			if name == "min" {
+				r.check = func(v reflect.Value) bool { return size(v) >= n }
+			} else {
+				r.check = func(v reflect.Value) bool { return size(v) <= n }
+			}
+
+			bound := "at least"
+			if name == "max" {
+				bound = "at most"
+			}
+
+			if isValidateNumberKind(kind) {
+				r.msg = "must be " + bound + " " + param
+			} else {
+				r.msg = "length must be " + bound + " " + param
+			}
+
+		case "len":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				fail("invalid parameter of len: %q", param)
+			}
+
+			if isValidateNumberKind(kind) || validateSizeFunc(kind) == nil {
+				fail("len is not supported on %v", f.Type)
+			}
+
+			size := validateSizeFunc(kind)
+			r.check = func(v reflect.Value) bool { return size(v) == float64(n) }
+			r.msg = "length must be " + param
+
+		case "oneof":
+			if kind != reflect.String && !isValidateNumberKind(kind) {
+				fail("oneof is not supported on %v", f.Type)
+			}
+
+			options := strings.Fields(param)
+			r.check = func(v reflect.Value) bool {
+				// 经由未导出的内嵌 struct 访问到的字段，不能调用 Interface() ，故按 Kind 格式化。
+				s := validateValueText(v)
+				for _, o := range options {
+					if s == o {
+						return true
+					}
+				}
+				return false
+			}
+			r.msg = "must be one of [" + strings.Join(options, " ") + "]"
+
+		case "email":
+			if kind != reflect.String {
+				fail("email is not supported on %v", f.Type)
+			}
+
+			r.check = func(v reflect.Value) bool {
+				addr, err := mail.ParseAddress(v.String())
+				return err == nil && addr.Address == v.String()
+			}
+			r.msg = "must be an email address"
+
+		case "regex":
+			if kind != reflect.String {
+				fail("regex is not supported on %v", f.Type)
+			}
+
+			re, err := regexp.Compile(param)
+			if err != nil {
+				fail("invalid regex: %v", err)
+			}
+			r.check = func(v reflect.Value) bool { return re.MatchString(v.String()) }
+			r.msg = "must match " + param
+
+		default:
+			fail("unknown rule %q", name)
+		}
+
+		// required 总是最先检查，这样后续规则不会遇到 nil 指针。
+		if name == "required" {
+			rules = append([]validateRule{r}, rules...)
+		} else {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules
+}
+
+// 返回字符串或数值的文本，数值使用十进制，同 fmt.Sprint 。
+func validateValueText(v reflect.Value) string {
+	switch kind := v.Kind(); {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+
+	case kind == reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+
+	case kind == reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+	return v.String()
+}
+
+func isValidateNumberKind(kind reflect.Kind) bool {
+	return (kind >= reflect.Int && kind <= reflect.Uintptr) || kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// 返回获取给定类型的“大小”的函数：数值为其值，字符串为字符数， slice/array/map 为元素数。不支持的类型返回 nil 。
+func validateSizeFunc(kind reflect.Kind) func(v reflect.Value) float64 {
+	switch {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return func(v reflect.Value) float64 { return float64(v.Int()) }
+
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return func(v reflect.Value) float64 { return float64(v.Uint()) }
+
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		return func(v reflect.Value) float64 { return v.Float() }
+
+	case kind == reflect.String:
+		return func(v reflect.Value) float64 { return float64(utf8.RuneCountInString(v.String())) }
+
+	case kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map:
+		return func(v reflect.Value) float64 { return float64(v.Len()) }
+	}
+	return nil
+}
+
+// 若 typ 实现了 OptionalValue ，返回其包装的值的类型。
+func optionalElemType(typ reflect.Type) (reflect.Type, bool) {
+	if !typ.Implements(typeOptionalValue) || typ.Kind() == reflect.Interface || typ.Kind() == reflect.Ptr {
+		return nil, false
+	}
+	return reflect.Zero(typ).Interface().(OptionalValue).ElemType(), true
+}
+
+// 若 val 实现了 OptionalValue （或是其指针），将其转换为 OptionalValue 。 nil 指针视为未给出。
+func asOptionalValue(val reflect.Value) (OptionalValue, bool) {
+	if !val.IsValid() {
+		return nil, false
+	}
+
+	typ := val.Type()
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if _, ok := optionalElemType(typ); !ok {
+		return nil, false
+	}
+
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return reflect.Zero(typ).Interface().(OptionalValue), true
+		}
+		val = val.Elem()
+	}
+
+	if !val.CanInterface() {
+		return nil, false
+	}
+	return val.Interface().(OptionalValue), true
+}
+
+func isStructOrStructPtr(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct
+}
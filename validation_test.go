@@ -0,0 +1,273 @@
+package webapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type validationTestInner struct {
+	Code string `validate:"required,len=3"`
+}
+
+type validationTestEmbedded struct {
+	E int `validate:"max=5"`
+}
+
+type validationTestStruct struct {
+	validationTestEmbedded
+	Name    string              `validate:"required,min=2,max=5"`
+	Age     int                 `validate:"min=1,max=100"`
+	Score   float64             `validate:"max=1.5"`
+	Ptr     *int                `validate:"required,min=10"`
+	Tags    []string            `validate:"min=1,max=2"`
+	Kind    string              `validate:"oneof=a b c"`
+	Level   int                 `validate:"oneof=1 2"`
+	Mail    string              `validate:"email"`
+	Phone   string              `validate:"regex=^\\d{3,4}$"`
+	Inner   validationTestInner // 没有 tag 也会递归校验。
+	InnerP  *validationTestInner
+	Items   []validationTestInner `validate:"max=3"`
+	Map     map[string]validationTestInner
+	private string `validate:"required"`
+}
+
+// 未导出的类型，用于测试经由未导出的内嵌 struct 访问到的字段。
+type validationTestOneOf struct {
+	Kind  string  `validate:"oneof=a b"`
+	Level uint8   `validate:"oneof=1 2"`
+	Ratio float32 `validate:"oneof=0.5 1.5"`
+}
+
+// 实现 OptionalValue ，模拟 slimapi.Optional 。
+type validationTestOptional[T any] struct {
+	value T
+	state int // 0 未给出， 1 null ， 2 给出了值。
+}
+
+func (x validationTestOptional[T]) ElemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (x validationTestOptional[T]) GetAny() (any, bool) {
+	return x.value, x.state == 2
+}
+
+func optionalOf[T any](v T) validationTestOptional[T] {
+	return validationTestOptional[T]{value: v, state: 2}
+}
+
+func validValidationTestStruct() validationTestStruct {
+	ten := 10
+	return validationTestStruct{
+		Name:  "abc",
+		Ptr:   &ten,
+		Inner: validationTestInner{Code: "123"},
+	}
+}
+
+func TestValidateStruct(t *testing.T) {
+	t.Run("non-struct", func(t *testing.T) {
+		require.Nil(t, ValidateStruct(nil))
+		require.Nil(t, ValidateStruct(1))
+		require.Nil(t, ValidateStruct((*validationTestStruct)(nil)))
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		v := validValidationTestStruct()
+		require.Nil(t, ValidateStruct(v))
+		require.Nil(t, ValidateStruct(&v))
+
+		v.Age = 100
+		v.Score = 1.5
+		v.Tags = []string{"a", "b"}
+		v.Kind = "c"
+		v.Level = 2
+		v.Mail = "a@b.c"
+		v.Phone = "1234"
+		v.E = 5
+		v.InnerP = &validationTestInner{Code: "中文字"}
+		v.Items = []validationTestInner{{Code: "111"}}
+		v.Map = map[string]validationTestInner{"k": {Code: "222"}}
+		require.Nil(t, ValidateStruct(v))
+	})
+
+	t.Run("required", func(t *testing.T) {
+		errs := ValidateStruct(validationTestStruct{})
+		require.Equal(t, ValidationErrors{
+			{Field: "Name", Rule: "required", Message: "Name is required"},
+			{Field: "Ptr", Rule: "required", Message: "Ptr is required"},
+			{Field: "Inner.Code", Rule: "required", Message: "Inner.Code is required"},
+		}, errs)
+		require.Equal(t, "Name is required; Ptr is required; Inner.Code is required", errs.Error())
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		v := validValidationTestStruct()
+		one := 1
+		v.E = 6
+		v.Name = "abcdef"
+		v.Age = -1
+		v.Score = 2
+		v.Ptr = &one
+		v.Tags = []string{"a", "b", "c"}
+		v.Kind = "d"
+		v.Level = 3
+		v.Mail = "not-a-mail"
+		v.Phone = "12"
+		v.Inner.Code = "1"
+		v.InnerP = &validationTestInner{}
+		v.Items = []validationTestInner{{Code: "111"}, {Code: "x"}}
+		v.Map = map[string]validationTestInner{"k": {Code: "1234"}}
+
+		errs := ValidateStruct(v)
+		require.Equal(t, ValidationErrors{
+			{Field: "E", Rule: "max", Param: "5", Message: "E must be at most 5"},
+			{Field: "Name", Rule: "max", Param: "5", Message: "Name length must be at most 5"},
+			{Field: "Age", Rule: "min", Param: "1", Message: "Age must be at least 1"},
+			{Field: "Score", Rule: "max", Param: "1.5", Message: "Score must be at most 1.5"},
+			{Field: "Ptr", Rule: "min", Param: "10", Message: "Ptr must be at least 10"},
+			{Field: "Tags", Rule: "max", Param: "2", Message: "Tags length must be at most 2"},
+			{Field: "Kind", Rule: "oneof", Param: "a b c", Message: "Kind must be one of [a b c]"},
+			{Field: "Level", Rule: "oneof", Param: "1 2", Message: "Level must be one of [1 2]"},
+			{Field: "Mail", Rule: "email", Message: "Mail must be an email address"},
+			{Field: "Phone", Rule: "regex", Param: `^\d{3,4}$`, Message: `Phone must match ^\d{3,4}$`},
+			{Field: "Inner.Code", Rule: "len", Param: "3", Message: "Inner.Code length must be 3"},
+			{Field: "InnerP.Code", Rule: "required", Message: "InnerP.Code is required"},
+			{Field: "Items[1].Code", Rule: "len", Param: "3", Message: "Items[1].Code length must be 3"},
+			{Field: "Map[k].Code", Rule: "len", Param: "3", Message: "Map[k].Code length must be 3"},
+		}, errs)
+	})
+
+	t.Run("required-not-first", func(t *testing.T) {
+		type S struct {
+			P *int `validate:"min=1,required"`
+		}
+		require.Equal(t, ValidationErrors{
+			{Field: "P", Rule: "required", Message: "P is required"},
+		}, ValidateStruct(S{}))
+	})
+
+	t.Run("required-ptr-to-zero", func(t *testing.T) {
+		type S struct {
+			P *int    `validate:"required"`
+			Q *string `validate:"required,max=1"`
+		}
+		zero, empty := 0, ""
+		require.Nil(t, ValidateStruct(S{P: &zero, Q: &empty}))
+
+		long := "ab"
+		require.Equal(t, ValidationErrors{
+			{Field: "P", Rule: "required", Message: "P is required"},
+			{Field: "Q", Rule: "max", Param: "1", Message: "Q length must be at most 1"},
+		}, ValidateStruct(S{Q: &long}))
+	})
+
+	t.Run("oneof-unexported-embedded", func(t *testing.T) {
+		type S struct {
+			validationTestOneOf
+		}
+		require.Nil(t, ValidateStruct(S{validationTestOneOf{Kind: "a", Level: 2, Ratio: 0.5}}))
+		require.Equal(t, ValidationErrors{
+			{Field: "Kind", Rule: "oneof", Param: "a b", Message: "Kind must be one of [a b]"},
+			{Field: "Level", Rule: "oneof", Param: "1 2", Message: "Level must be one of [1 2]"},
+			{Field: "Ratio", Rule: "oneof", Param: "0.5 1.5", Message: "Ratio must be one of [0.5 1.5]"},
+		}, ValidateStruct(S{validationTestOneOf{Kind: "c", Level: 3, Ratio: 2}}))
+	})
+
+	t.Run("optional", func(t *testing.T) {
+		type S struct {
+			A validationTestOptional[int]                 `validate:"min=1,max=10"`
+			B validationTestOptional[string]              `validate:"required,max=2"`
+			C *validationTestOptional[int]                `validate:"required"`
+			D validationTestOptional[validationTestInner] // 给出了值时递归校验。
+		}
+
+		// 未给出或为 null 时， required 不通过，其他规则被跳过。
+		require.Equal(t, ValidationErrors{
+			{Field: "B", Rule: "required", Message: "B is required"},
+			{Field: "C", Rule: "required", Message: "C is required"},
+		}, ValidateStruct(S{}))
+
+		null := validationTestOptional[int]{state: 1}
+		require.Equal(t, ValidationErrors{
+			{Field: "B", Rule: "required", Message: "B is required"},
+			{Field: "C", Rule: "required", Message: "C is required"},
+		}, ValidateStruct(S{B: validationTestOptional[string]{state: 1}, C: &null}))
+
+		// 给出了零值时， required 通过，其他规则照常执行。
+		zero := optionalOf(0)
+		require.Nil(t, ValidateStruct(S{B: optionalOf(""), C: &zero}))
+		require.Equal(t, ValidationErrors{
+			{Field: "A", Rule: "min", Param: "1", Message: "A must be at least 1"},
+			{Field: "B", Rule: "max", Param: "2", Message: "B length must be at most 2"},
+			{Field: "D.Code", Rule: "required", Message: "D.Code is required"},
+		}, ValidateStruct(S{A: optionalOf(0), B: optionalOf("abc"), C: &zero, D: optionalOf(validationTestInner{})}))
+
+		require.Nil(t, ValidateStruct(S{A: optionalOf(10), B: optionalOf("ab"), C: &zero, D: optionalOf(validationTestInner{Code: "123"})}))
+
+		// 规则按包装的值的类型检查。
+		require.NotPanics(t, func() { PrepareValidateRules(reflect.TypeOf(S{})) })
+
+		type Bad struct {
+			A validationTestOptional[int] `validate:"email"`
+		}
+		require.PanicsWithValue(t, `validate tag of webapi.Bad.A: email is not supported on webapi.validationTestOptional[int]`, func() { ValidateStruct(Bad{}) })
+	})
+
+	t.Run("regex-after-space", func(t *testing.T) {
+		// regex 前有空格，且其中有逗号，仍作为最后一条规则。
+		type S struct {
+			A string `validate:"required, regex=^a{1,2}$"`
+		}
+		require.Nil(t, ValidateStruct(S{A: "aa"}))
+		require.Equal(t, ValidationErrors{
+			{Field: "A", Rule: "regex", Param: "^a{1,2}$", Message: "A must match ^a{1,2}$"},
+		}, ValidateStruct(S{A: "aaa"}))
+	})
+
+	t.Run("bad-tag", func(t *testing.T) {
+		type Unknown struct {
+			A int `validate:"what"`
+		}
+		require.PanicsWithValue(t, `validate tag of webapi.Unknown.A: unknown rule "what"`, func() { ValidateStruct(Unknown{}) })
+
+		type BadParam struct {
+			A int `validate:"min=x"`
+		}
+		require.PanicsWithValue(t, `validate tag of webapi.BadParam.A: invalid parameter of min: "x"`, func() { ValidateStruct(BadParam{}) })
+
+		type BadType struct {
+			A int `validate:"email"`
+		}
+		require.PanicsWithValue(t, `validate tag of webapi.BadType.A: email is not supported on int`, func() { ValidateStruct(BadType{}) })
+
+		type BadLen struct {
+			A int `validate:"len=1"`
+		}
+		require.PanicsWithValue(t, `validate tag of webapi.BadLen.A: len is not supported on int`, func() { ValidateStruct(BadLen{}) })
+
+		type BadRegex struct {
+			A string `validate:"regex=("`
+		}
+		require.Panics(t, func() { ValidateStruct(BadRegex{}) })
+	})
+}
+
+func TestPrepareValidateRules(t *testing.T) {
+	type Node struct {
+		Children []*Node
+		Name     string `validate:"required"`
+	}
+	require.NotPanics(t, func() { PrepareValidateRules(reflect.TypeOf(Node{})) })
+	require.NotPanics(t, func() { PrepareValidateRules(reflect.TypeOf(1)) })
+
+	type Bad struct {
+		A int `validate:"what"`
+	}
+	type Outer struct {
+		M map[string][]*Bad
+	}
+	require.PanicsWithValue(t, `validate tag of webapi.Bad.A: unknown rule "what"`, func() { PrepareValidateRules(reflect.TypeOf(&Outer{})) })
+}
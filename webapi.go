@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/cmstar/go-errx"
 	"github.com/cmstar/go-logx"
@@ -65,12 +66,32 @@ type ApiHandlerWrapper struct {
 
 	// HttpMethods 是 ApiHandler.SupportedHttpMethods() 的返回值。
 	HttpMethods []string
+
+	// MaxBodySize 是请求 body 的最大字节数，在方法没有通过元数据 [ApiMetaMaxBodySize] 单独指定时使用，见 [LimitRequestBody] 。
+	// 小于等于 0 表示不限制。
+	MaxBodySize int64
+
+	// StreamHeartbeat 是流式输出的心跳间隔，在方法没有通过元数据 [ApiMetaStreamHeartbeat] 单独指定时使用，见 [StreamHeartbeat] 。
+	// 小于等于 0 表示不发送心跳。
+	StreamHeartbeat time.Duration
+
+	// LongPollMaxWait 是长轮询单次请求的最长等待时间，在方法没有通过元数据 [ApiMetaLongPollMaxWait] 单独指定时使用，见 [LongPollMaxWait] 。
+	// 小于等于 0 表示使用 [DefaultLongPollMaxWait] 。
+	LongPollMaxWait time.Duration
 }
 
 var _ ApiHandler = (*ApiHandlerWrapper)(nil)
 
 // Wrap 将一个 ApiHandler 包装为 *ApiHandlerWrapper ，用于“重写”其中的方法。
 func Wrap(h ApiHandler) *ApiHandlerWrapper {
+	var maxBodySize int64
+	var streamHeartbeat, longPollMaxWait time.Duration
+	if w, ok := h.(*ApiHandlerWrapper); ok {
+		maxBodySize = w.MaxBodySize
+		streamHeartbeat = w.StreamHeartbeat
+		longPollMaxWait = w.LongPollMaxWait
+	}
+
 	return &ApiHandlerWrapper{
 		ApiMethodRegister:   h,
 		ApiNameResolver:     h,
@@ -82,6 +103,9 @@ func Wrap(h ApiHandler) *ApiHandlerWrapper {
 		ApiLogger:           h,
 		HandlerName:         h.Name(),
 		HttpMethods:         h.SupportedHttpMethods(),
+		MaxBodySize:         maxBodySize,
+		StreamHeartbeat:     streamHeartbeat,
+		LongPollMaxWait:     longPollMaxWait,
 	}
 }
 
@@ -106,6 +130,18 @@ type ApiMethod struct {
 
 	// Provider 指定方法提供者的名称，用于对方法加以分类，可为空。
 	Provider string
+
+	// Meta 记录注册方法时附带的元数据，可为 nil 。
+	Meta *ApiMeta
+}
+
+// ParamName 返回方法参数表中第 index 个参数的名称，即元数据 [ApiMetaParamNames] 中对应位置的值。没有名称时返回空字符串。
+func (m ApiMethod) ParamName(index int) string {
+	names, _ := GetApiMetaValue[[]string](m.Meta, ApiMetaParamNames)
+	if index < 0 || index >= len(names) {
+		return ""
+	}
+	return names[index]
 }
 
 // ApiMethodRegister 用于向 ApiHandler 中注册 WebAPI 方法。
@@ -141,6 +177,30 @@ type ApiMethodRegister interface {
 	GetMethod(name string) (method ApiMethod, ok bool)
 }
 
+// ApiMethodLister 是 [ApiMethodRegister] 可选实现的接口，用于枚举已注册的方法，
+// 可用于生成 API 文档等需要枚举方法的场景。 [NewBasicApiMethodRegister] 返回的实例实现了此接口。
+type ApiMethodLister interface {
+	// Methods 返回已注册的全部方法，按名称（大小写不敏感）升序排列。
+	Methods() []ApiMethod
+}
+
+// ListMethods 通过 [ApiMethodLister] 返回 register 上已注册的全部方法，按名称（大小写不敏感）升序排列。
+// 若 register 是 [*ApiHandlerWrapper] ，则使用其内部的 ApiMethodRegister 。
+// 若 register 没有实现 [ApiMethodLister] ，则 panic 。
+func ListMethods(register ApiMethodRegister) []ApiMethod {
+	for {
+		if lister, ok := register.(ApiMethodLister); ok {
+			return lister.Methods()
+		}
+
+		w, ok := register.(*ApiHandlerWrapper)
+		if !ok {
+			panic(fmt.Sprintf("%T does not implement ApiMethodLister", register))
+		}
+		register = w.ApiMethodRegister
+	}
+}
+
 // ApiNameResolver 用于从当前 HTTP 请求中，解析得到目标 API 方法的名称。
 type ApiNameResolver interface {
 	// FillMethod 从当前 HTTP 请求里获取 API 方法的名称，并填入 ApiState.Name ；如果未能解析到名称，则不需要填写。
@@ -264,6 +324,7 @@ func (f ApiLoggerFunc) Log(state *ApiState) {
 func CreateHandlerFunc(handler ApiHandler, logFinder logx.LogFinder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		state := NewState(w, r, handler)
+		defer state.Cleanup()
 
 		// 把比较可能 panic 的步骤抽出来，添加一个 defer 捕获错误并填到 state.Error 是上，使 panic 后仍
 		// 可以预定义的报文返回结果。
@@ -291,9 +352,11 @@ func CreateHandlerFunc(handler ApiHandler, logFinder logx.LogFinder) http.Handle
 func doWriteResponse(state *ApiState, w http.ResponseWriter) {
 	flusher, canFlush := w.(http.Flusher)
 
-	// 若无法正确输出（最常见的是连接已断开），则留下 WriteResponseError 日志并尝试将日志级别提升到 warn 。
+	// 若无法正确输出（最常见的是连接已断开），则留下 WriteResponseError 日志并尝试将日志级别提升到 warn ，
+	// 同时取消请求的 context ，通知仍在执行的过程停止。
 	// 迭代或 flush 过程中若发生 panic ，由最外层的 Recoverer 中间件处理。
 	onWriteError := func(err error) {
+		state.CancelRequest()
 		state.LogMessage = append(state.LogMessage,
 			"WriteResponseError", err,
 		)
@@ -349,6 +412,8 @@ func handleRequest(state *ApiState, handler ApiHandler, logFinder logx.LogFinder
 		state.Logger = logFinder.Find(loggerName)
 	}
 
+	LimitRequestBody(state)
+
 	handler.Decode(state)
 	if state.Error == nil {
 		handler.Call(state)
@@ -3,6 +3,7 @@
 package webapi
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -106,6 +107,50 @@ func TestCreateHandlerFunc(t *testing.T) {
 	})
 }
 
+func TestCreateHandlerFunc_cleanup(t *testing.T) {
+	uri, _ := url.Parse("http://temp.org")
+	cleaned := false
+
+	handlerFunc := createHandlerFuncForTest(&ApiHandlerWrapper{
+		ApiUserHostResolver: ApiUserHostResolverFunc(func(state *ApiState) {
+			state.AddCleanup(func() { cleaned = true })
+			panic("gg")
+		}),
+	})
+	recorder := httptest.NewRecorder()
+	handlerFunc.ServeHTTP(recorder, &http.Request{URL: uri})
+
+	require.True(t, cleaned)
+}
+
+// 写入总是失败的 http.ResponseWriter 。
+type failedResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failedResponseWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken")
+}
+
+func TestCreateHandlerFunc_writeError(t *testing.T) {
+	uri, _ := url.Parse("http://temp.org")
+	var ctx context.Context
+
+	handlerFunc := createHandlerFuncForTest(&ApiHandlerWrapper{
+		ApiResponseWriter: ApiResponseWriterFunc(func(state *ApiState) {
+			ctx = state.RawRequest.Context()
+			state.ResponseBody = func(yield func([]byte) bool) {
+				// 输出失败后，请求的 context 被取消。
+				if !yield([]byte("a")) {
+					require.Error(t, ctx.Err())
+				}
+			}
+		}),
+	})
+	handlerFunc.ServeHTTP(failedResponseWriter{httptest.NewRecorder()}, &http.Request{URL: uri})
+	require.Error(t, ctx.Err())
+}
+
 func TestCreateHandlerFunc_panic(t *testing.T) {
 	uri, _ := url.Parse("http://temp.org")
 
@@ -182,3 +227,17 @@ func TestCreateHandlerFunc_panic(t *testing.T) {
 		require.Regexp(t, "msg", s.Error.Error())
 	})
 }
+
+func TestListMethods(t *testing.T) {
+	reg := NewBasicApiMethodRegister(BasicApiMethodRegisterOp{})
+	reg.RegisterMethod(ApiMethod{Name: "M", Value: reflect.ValueOf(func() {})})
+
+	h := Wrap(Wrap(setupApiHandlerWrapper(&ApiHandlerWrapper{ApiMethodRegister: reg})))
+	methods := ListMethods(h)
+	require.Len(t, methods, 1)
+	require.Equal(t, "M", methods[0].Name)
+
+	require.PanicsWithValue(t, "webapi.emptyApiMethodRegister does not implement ApiMethodLister", func() {
+		ListMethods(setupApiHandlerWrapper(&ApiHandlerWrapper{}))
+	})
+}
@@ -0,0 +1,179 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// IsWebSocketUpgrade 判断 r 是否为 WebSocket 的 upgrade 请求。
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade 将 HTTP 请求升级为 WebSocket 连接，返回服务端的 [*Conn] 。
+//
+// 请求不是有效的 WebSocket 握手请求时，向 w 输出 400 （版本不支持时为 426 ）并返回错误；
+// w 不支持 hijack 时，输出 500 并返回错误。成功后， w 和 r.Body 不再可用。
+//
+// 此方法不校验 Origin 头，跨域的限制需由调用方自行处理。
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	fail := func(status int, msg string) (*Conn, error) {
+		http.Error(w, msg, status)
+		return nil, errors.New("websocket: " + msg)
+	}
+
+	if r.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "method must be GET")
+	}
+
+	if !IsWebSocketUpgrade(r) {
+		return fail(http.StatusBadRequest, "not a websocket upgrade request")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, "hijack not supported")
+	}
+
+	// http.Server 可能按其 ReadTimeout 、 WriteTimeout 设置了连接的期限，它们不适用于长期存在的 WebSocket 连接。
+	netConn.SetDeadline(time.Time{})
+
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	brw.WriteString("Upgrade: websocket\r\n")
+	brw.WriteString("Connection: Upgrade\r\n")
+	brw.WriteString("Sec-WebSocket-Accept: " + computeAcceptKey(key) + "\r\n\r\n")
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	// 客户端可能在握手之后立即发送数据，其已被读入 brw.Reader ，需沿用。
+	return newConn(netConn, brw.Reader, true), nil
+}
+
+// Dial 连接 WebSocket 服务端， rawUrl 的 scheme 可以是 ws 、 wss 、 http 、 https 。
+// header 可为 nil ，其中的字段被添加到握手请求上，如 Cookie 、 Authorization 。
+//
+// 握手失败时，返回服务端的回执（若有）和错误，回执的 body 已被关闭。
+func Dial(ctx context.Context, rawUrl string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	useTls := false
+	switch u.Scheme {
+	case "ws", "http":
+		u.Scheme = "http"
+	case "wss", "https":
+		u.Scheme = "https"
+		useTls = true
+	default:
+		return nil, nil, fmt.Errorf("websocket: unsupported scheme '%s'", u.Scheme)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		if useTls {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	var netConn net.Conn
+	if useTls {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		netConn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		dialer := &net.Dialer{}
+		netConn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 握手期间，响应 ctx 的取消。
+	stop := context.AfterFunc(ctx, func() { netConn.Close() })
+	defer stop()
+
+	keyBytes := make([]byte, 16)
+	rand.Read(keyBytes)
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContainsToken(resp.Header, "Upgrade", "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != computeAcceptKey(key) {
+		resp.Body.Close()
+		netConn.Close()
+		return nil, resp, fmt.Errorf("websocket: bad handshake, status %d", resp.StatusCode)
+	}
+
+	// ctx 已被取消时，连接已被关闭。
+	if !stop() {
+		return nil, resp, ctx.Err()
+	}
+	return newConn(netConn, br, false), resp, nil
+}
+
+// 判断 HTTP 头 name 的值中，是否有逗号分隔的 token （大小写不敏感）。
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,447 @@
+// websocket 包实现 WebSocket 协议（ RFC 6455 ）的服务端和客户端，仅依赖标准库。
+//
+// 服务端通过 [Upgrade] 将一个 HTTP 请求升级为 WebSocket 连接；客户端通过 [Dial] 建立连接。
+// 不支持扩展（如 permessage-deflate ）和子协议。
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType 是消息的类型。
+type MessageType int
+
+const (
+	// TextMessage 表示文本消息，其数据是 UTF-8 编码的文本。
+	TextMessage MessageType = 1
+
+	// BinaryMessage 表示二进制消息。
+	BinaryMessage MessageType = 2
+)
+
+// 帧的操作码。
+const (
+	opContinuation = 0
+	opText         = 1
+	opBinary       = 2
+	opClose        = 8
+	opPing         = 9
+	opPong         = 10
+)
+
+// 关闭连接时使用的状态码，见 RFC 6455 7.4.1 。
+const (
+	CloseNormalClosure    = 1000 // 正常关闭。
+	CloseGoingAway        = 1001 // 一端离开，如服务器关闭。
+	CloseProtocolError    = 1002 // 协议错误。
+	CloseUnsupportedData  = 1003 // 收到不能处理的数据类型。
+	CloseNoStatusReceived = 1005 // 对方的 close 帧没有状态码，不能用于发送。
+	CloseInvalidPayload   = 1007 // 数据与消息类型不符，如文本消息不是 UTF-8 。
+	CloseMessageTooBig    = 1009 // 消息过大。
+	CloseInternalError    = 1011 // 服务端内部错误。
+)
+
+// DefaultMaxMessageSize 是 [Conn.MaxMessageSize] 的默认值。
+const DefaultMaxMessageSize = 10 * 1024 * 1024
+
+// 计算 Sec-WebSocket-Accept 时使用的 GUID ，见 RFC 6455 1.3 。
+const acceptGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrMessageTooBig 表示收到的消息超过了 [Conn.MaxMessageSize] 。
+var ErrMessageTooBig = errors.New("websocket: message too big")
+
+// ErrClosed 表示连接已关闭。
+var ErrClosed = errors.New("websocket: connection closed")
+
+// CloseError 表示连接因收到对方的 close 帧而关闭。
+type CloseError struct {
+	Code   int    // 状态码。对方没有给出时为 [CloseNoStatusReceived] 。
+	Reason string // 关闭的原因，可为空。
+}
+
+// Error implements error.Error().
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+// Conn 表示一个 WebSocket 连接。
+//
+// 可以在一个 goroutine 里读取，同时在多个 goroutine 里写入：
+// [Conn.ReadMessage] 不能并发调用； [Conn.WriteMessage] 和 [Conn.Close] 等写入方法是并发安全的。
+type Conn struct {
+	// MaxMessageSize 是可接收的单个消息的最大字节数，小于等于 0 时使用 [DefaultMaxMessageSize] 。
+	MaxMessageSize int64
+
+	// WriteTimeout 大于 0 时，每次写入帧之前，将底层连接的写入期限设置为此时长之后，以免对方长时间不读取时写入一直阻塞。
+	// 写入出错（包括超时）后，帧可能只写入了一部分，连接被关闭。
+	WriteTimeout time.Duration
+
+	// ReadTimeout 大于 0 时，每次读取帧之前，将底层连接的读取期限设置为此时长之后。
+	// 控制帧（如 pong ）也会刷新期限，因此可以配合定时的 [Conn.Ping] 发现已失效的连接。超时后连接被关闭。
+	ReadTimeout time.Duration
+
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+
+	writeMu    sync.Mutex
+	closeSent  bool // 已发送 close 帧，之后不能再发送数据帧。
+	closedOnce sync.Once
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+
+	return &Conn{
+		conn:     conn,
+		br:       br,
+		isServer: isServer,
+	}
+}
+
+// NetConn 返回底层的网络连接。
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// ReadMessage 读取一个完整的消息。分片的消息被合并；收到 ping 时自动回复 pong ， pong 被忽略。
+//
+// 收到 close 帧时，回复 close 帧并关闭连接，返回 [*CloseError] ；协议错误或消息过大时，
+// 发送对应状态码的 close 帧，关闭连接并返回错误。返回错误后，连接不再可用。
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		typ     MessageType
+		message []byte
+	)
+
+	for {
+		if c.ReadTimeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout))
+		}
+
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, c.fail(err)
+			}
+			continue
+
+		case opPong:
+			continue
+
+		case opClose:
+			return 0, nil, c.onClose(payload)
+
+		case opText, opBinary:
+			if typ != 0 {
+				return 0, nil, c.fail(protocolError("expect a continuation frame"))
+			}
+			typ = MessageType(op)
+
+		case opContinuation:
+			if typ == 0 {
+				return 0, nil, c.fail(protocolError("unexpected continuation frame"))
+			}
+
+		default:
+			return 0, nil, c.fail(protocolError(fmt.Sprintf("unknown opcode %d", op)))
+		}
+
+		if int64(len(message)+len(payload)) > c.maxMessageSize() {
+			return 0, nil, c.fail(ErrMessageTooBig)
+		}
+		message = append(message, payload...)
+
+		if fin {
+			if typ == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(invalidPayloadError("text message is not valid UTF-8"))
+			}
+
+			if message == nil {
+				message = []byte{}
+			}
+			return typ, message, nil
+		}
+	}
+}
+
+// WriteMessage 发送一个消息，不分片。并发安全。
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("websocket: unsupported message type %d", typ)
+	}
+	return c.writeFrame(byte(typ), data)
+}
+
+// Ping 发送一个 ping 帧。对方回复的 pong 由 [Conn.ReadMessage] 忽略。
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > 125 {
+		return errors.New("websocket: ping payload too long")
+	}
+	return c.writeFrame(opPing, data)
+}
+
+// Close 以 [CloseNormalClosure] 关闭连接，同 CloseWithReason(CloseNormalClosure, "") 。
+func (c *Conn) Close() error {
+	return c.CloseWithReason(CloseNormalClosure, "")
+}
+
+// CloseWithReason 发送带有给定状态码和原因的 close 帧（若尚未发送），之后关闭底层的网络连接。
+// 不等待对方回复 close 帧。可以被多次调用，仅第一次有效。
+func (c *Conn) CloseWithReason(code int, reason string) error {
+	c.writeClose(code, reason)
+	return c.closeNetConn()
+}
+
+func (c *Conn) maxMessageSize() int64 {
+	if c.MaxMessageSize > 0 {
+		return c.MaxMessageSize
+	}
+	return DefaultMaxMessageSize
+}
+
+// 读取一帧，返回其 FIN 标记、操作码和（已解除掩码的）数据。
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7f)
+
+	if head[0]&0x70 != 0 {
+		err = protocolError("reserved bits are set")
+		return
+	}
+
+	// 客户端发出的帧必须有掩码，服务端发出的帧必须没有。
+	if masked != c.isServer {
+		err = protocolError("invalid mask bit")
+		return
+	}
+
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(b[:]))
+
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(b[:]))
+		if length < 0 {
+			err = protocolError("invalid payload length")
+			return
+		}
+	}
+
+	if op >= opClose && (!fin || length > 125) {
+		err = protocolError("invalid control frame")
+		return
+	}
+
+	if length > c.maxMessageSize() {
+		err = ErrMessageTooBig
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return
+}
+
+// 写入一个 FIN 帧。客户端发出的帧带有随机的掩码。
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+	if op == opClose {
+		c.closeSent = true
+	}
+
+	length := len(payload)
+	frame := make([]byte, 0, 14+length)
+	frame = append(frame, 0x80|op)
+
+	maskBit := byte(0)
+	if !c.isServer {
+		maskBit = 0x80
+	}
+
+	switch {
+	case length <= 125:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	if c.isServer {
+		frame = append(frame, payload...)
+	} else {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	}
+
+	if c.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
+	}
+
+	_, err := c.conn.Write(frame)
+	if err != nil {
+		// 帧可能只写入了一部分，之后的帧无法被正确解析。
+		c.closeSent = true
+		c.closeNetConn()
+	}
+	return err
+}
+
+func (c *Conn) writeClose(code int, reason string) error {
+	var payload []byte
+	if code != CloseNoStatusReceived {
+		payload = binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, reason...)
+	}
+	return c.writeFrame(opClose, payload)
+}
+
+func (c *Conn) closeNetConn() error {
+	var err error
+	c.closedOnce.Do(func() {
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// 收到 close 帧：回复相同的状态码，然后关闭连接。
+// 帧的内容只有 1 个字节，或状态码不能出现在线路上时，按协议错误处理，见 RFC 6455 5.5.1 。
+func (c *Conn) onClose(payload []byte) error {
+	e := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.fail(protocolError("bad close frame"))
+
+	case len(payload) >= 2:
+		e.Code = int(binary.BigEndian.Uint16(payload))
+		e.Reason = string(payload[2:])
+		if !isValidCloseCode(e.Code) {
+			return c.fail(protocolError(fmt.Sprintf("invalid close code %d", e.Code)))
+		}
+	}
+
+	c.writeClose(e.Code, "")
+	c.closeNetConn()
+	return e
+}
+
+// 判断 close 帧中的状态码是否合法，见 RFC 6455 7.4 。
+// 1005 、 1006 、 1015 仅用于表示状态，不能出现在线路上； 1012~1014 是后来登记的，也允许使用。
+func isValidCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	default:
+		return false
+	}
+}
+
+// 读取出错：按错误的类型发送 close 帧，然后关闭连接。
+func (c *Conn) fail(err error) error {
+	var codeErr *closeCodeError
+	switch {
+	case errors.As(err, &codeErr):
+		c.writeClose(codeErr.code, codeErr.msg)
+	case errors.Is(err, ErrMessageTooBig):
+		c.writeClose(CloseMessageTooBig, "")
+	}
+
+	c.closeNetConn()
+	return err
+}
+
+// 读取过程中发现的错误，关闭连接时使用对应的状态码。
+type closeCodeError struct {
+	code int
+	msg  string
+}
+
+func (e *closeCodeError) Error() string {
+	return "websocket: " + e.msg
+}
+
+func protocolError(msg string) error {
+	return &closeCodeError{CloseProtocolError, msg}
+}
+
+func invalidPayloadError(msg string) error {
+	return &closeCodeError{CloseInvalidPayload, msg}
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i&3]
+	}
+}
+
+// 计算 Sec-WebSocket-Accept 的值。
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	h.Write([]byte(acceptGuid))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,333 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 启动一个服务端，将收到的消息原样发回；收到 "close" 时，以 4000 关闭连接。
+func newEchoServer(t *testing.T, setup func(*Conn)) (url string, serverErr chan error) {
+	serverErr = make(chan error, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		if setup != nil {
+			setup(conn)
+		}
+
+		for {
+			typ, data, err := conn.ReadMessage()
+			if err != nil {
+				serverErr <- err
+				return
+			}
+
+			if string(data) == "close" {
+				conn.CloseWithReason(4000, "bye")
+				serverErr <- nil
+				return
+			}
+			conn.WriteMessage(typ, data)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return "ws" + strings.TrimPrefix(s.URL, "http"), serverErr
+}
+
+func dial(t *testing.T, url string) *Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, resp, err := Dial(ctx, url, http.Header{"X-Test": {"1"}})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// 写入一个原始的帧，客户端使用。
+func writeRawFrame(c *Conn, fin bool, op byte, payload []byte) {
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+
+	frame := []byte{b0, 0x80 | byte(len(payload))}
+	var mask [4]byte
+	rand.Read(mask[:])
+	frame = append(frame, mask[:]...)
+
+	start := len(frame)
+	frame = append(frame, payload...)
+	maskBytes(mask, frame[start:])
+	c.conn.Write(frame)
+}
+
+func TestConn_echo(t *testing.T) {
+	url, _ := newEchoServer(t, nil)
+	conn := dial(t, url)
+
+	for _, size := range []int{0, 1, 125, 126, 0xffff, 0x10000, 100000} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		require.NoError(t, conn.WriteMessage(BinaryMessage, data))
+		typ, got, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, BinaryMessage, typ)
+		assert.Equal(t, data, got, "size %d", size)
+	}
+
+	require.NoError(t, conn.WriteMessage(TextMessage, []byte("中文")))
+	typ, got, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, TextMessage, typ)
+	assert.Equal(t, "中文", string(got))
+
+	assert.Error(t, conn.WriteMessage(MessageType(opPing), nil))
+}
+
+func TestConn_fragmentsAndControlFrames(t *testing.T) {
+	url, _ := newEchoServer(t, nil)
+	conn := dial(t, url)
+
+	// 分片之间穿插 ping ，服务端回复 pong 并将分片合并。
+	writeRawFrame(conn, false, opText, []byte("ab"))
+	writeRawFrame(conn, true, opPing, []byte("p"))
+	writeRawFrame(conn, false, opContinuation, []byte("cd"))
+	writeRawFrame(conn, true, opContinuation, []byte("ef"))
+
+	fin, op, payload, err := conn.readFrame()
+	require.NoError(t, err)
+	assert.True(t, fin)
+	assert.Equal(t, byte(opPong), op)
+	assert.Equal(t, "p", string(payload))
+
+	typ, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, TextMessage, typ)
+	assert.Equal(t, "abcdef", string(data))
+
+	// ReadMessage 自动忽略 pong 。
+	require.NoError(t, conn.Ping([]byte("x")))
+	require.NoError(t, conn.WriteMessage(TextMessage, []byte("after-ping")))
+	_, data, err = conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "after-ping", string(data))
+}
+
+func TestConn_close(t *testing.T) {
+	t.Run("by-server", func(t *testing.T) {
+		url, serverErr := newEchoServer(t, nil)
+		conn := dial(t, url)
+
+		require.NoError(t, conn.WriteMessage(TextMessage, []byte("close")))
+		_, _, err := conn.ReadMessage()
+
+		var closeErr *CloseError
+		require.ErrorAs(t, err, &closeErr)
+		assert.Equal(t, 4000, closeErr.Code)
+		assert.Equal(t, "bye", closeErr.Reason)
+		assert.NoError(t, <-serverErr)
+
+		assert.ErrorIs(t, conn.WriteMessage(TextMessage, nil), ErrClosed)
+	})
+
+	t.Run("codes", func(t *testing.T) {
+		// 合法的状态码被原样回复。
+		for _, code := range []int{CloseNormalClosure, CloseUnsupportedData, 1012, 3000, 4999} {
+			url, serverErr := newEchoServer(t, nil)
+			conn := dial(t, url)
+
+			writeRawFrame(conn, true, opClose, binary.BigEndian.AppendUint16(nil, uint16(code)))
+			_, _, err := conn.ReadMessage()
+
+			var closeErr *CloseError
+			require.ErrorAs(t, err, &closeErr, code)
+			assert.Equal(t, code, closeErr.Code)
+			require.ErrorAs(t, <-serverErr, &closeErr, code)
+			assert.Equal(t, code, closeErr.Code)
+		}
+	})
+
+	t.Run("no-status", func(t *testing.T) {
+		url, serverErr := newEchoServer(t, nil)
+		conn := dial(t, url)
+
+		writeRawFrame(conn, true, opClose, nil)
+		_, _, err := conn.ReadMessage()
+
+		var closeErr *CloseError
+		require.ErrorAs(t, err, &closeErr)
+		assert.Equal(t, CloseNoStatusReceived, closeErr.Code)
+		require.ErrorAs(t, <-serverErr, &closeErr)
+		assert.Equal(t, CloseNoStatusReceived, closeErr.Code)
+	})
+
+	t.Run("by-client", func(t *testing.T) {
+		url, serverErr := newEchoServer(t, nil)
+		conn := dial(t, url)
+
+		require.NoError(t, conn.CloseWithReason(CloseGoingAway, "leaving"))
+		require.NoError(t, conn.Close())
+
+		var closeErr *CloseError
+		require.ErrorAs(t, <-serverErr, &closeErr)
+		assert.Equal(t, CloseGoingAway, closeErr.Code)
+		assert.Equal(t, "leaving", closeErr.Reason)
+	})
+}
+
+func TestConn_errors(t *testing.T) {
+	// 服务端读取出错后，发送对应状态码的 close 帧。
+	expectClose := func(t *testing.T, conn *Conn, serverErr chan error, code int) error {
+		_, _, err := conn.ReadMessage()
+		var closeErr *CloseError
+		require.ErrorAs(t, err, &closeErr)
+		assert.Equal(t, code, closeErr.Code)
+		return <-serverErr
+	}
+
+	t.Run("too-big", func(t *testing.T) {
+		url, serverErr := newEchoServer(t, func(c *Conn) { c.MaxMessageSize = 4 })
+		conn := dial(t, url)
+
+		require.NoError(t, conn.WriteMessage(BinaryMessage, []byte("abcd")))
+		_, _, err := conn.ReadMessage()
+		require.NoError(t, err)
+
+		writeRawFrame(conn, false, opBinary, []byte("abc"))
+		writeRawFrame(conn, true, opContinuation, []byte("de"))
+		err = expectClose(t, conn, serverErr, CloseMessageTooBig)
+		assert.ErrorIs(t, err, ErrMessageTooBig)
+	})
+
+	t.Run("invalid-utf8", func(t *testing.T) {
+		url, serverErr := newEchoServer(t, nil)
+		conn := dial(t, url)
+
+		require.NoError(t, conn.WriteMessage(TextMessage, []byte{0xff}))
+		expectClose(t, conn, serverErr, CloseInvalidPayload)
+	})
+
+	t.Run("unexpected-continuation", func(t *testing.T) {
+		url, serverErr := newEchoServer(t, nil)
+		conn := dial(t, url)
+
+		writeRawFrame(conn, true, opContinuation, []byte("a"))
+		expectClose(t, conn, serverErr, CloseProtocolError)
+	})
+
+	t.Run("unmasked", func(t *testing.T) {
+		url, serverErr := newEchoServer(t, nil)
+		conn := dial(t, url)
+
+		// 客户端发出的帧必须有掩码。
+		conn.conn.Write([]byte{0x81, 1, 'a'})
+		expectClose(t, conn, serverErr, CloseProtocolError)
+	})
+
+	t.Run("close-payload-1-byte", func(t *testing.T) {
+		url, serverErr := newEchoServer(t, nil)
+		conn := dial(t, url)
+
+		writeRawFrame(conn, true, opClose, []byte{0x03})
+		err := expectClose(t, conn, serverErr, CloseProtocolError)
+		assert.EqualError(t, err, "websocket: bad close frame")
+	})
+
+	t.Run("invalid-close-code", func(t *testing.T) {
+		// 保留的状态码（ 1005 、 1006 、 1015 ）不能出现在线路上，不被回复。
+		for _, code := range []int{0, 999, 1004, CloseNoStatusReceived, 1006, 1015, 1016, 2999, 5000} {
+			url, serverErr := newEchoServer(t, nil)
+			conn := dial(t, url)
+
+			writeRawFrame(conn, true, opClose, binary.BigEndian.AppendUint16(nil, uint16(code)))
+			err := expectClose(t, conn, serverErr, CloseProtocolError)
+			assert.EqualError(t, err, fmt.Sprintf("websocket: invalid close code %d", code))
+		}
+	})
+
+	t.Run("long-control-frame", func(t *testing.T) {
+		url, serverErr := newEchoServer(t, nil)
+		conn := dial(t, url)
+
+		frame := []byte{0x80 | opPing, 0x80 | 126}
+		frame = binary.BigEndian.AppendUint16(frame, 200)
+		frame = append(frame, make([]byte, 4+200)...)
+		conn.conn.Write(frame)
+		expectClose(t, conn, serverErr, CloseProtocolError)
+	})
+}
+
+func TestConn_writeTimeout(t *testing.T) {
+	// net.Pipe 没有缓冲，对方不读取时，写入一直阻塞。
+	server, client := net.Pipe()
+	defer client.Close()
+
+	conn := newConn(server, nil, true)
+	conn.WriteTimeout = 10 * time.Millisecond
+
+	err := conn.WriteMessage(TextMessage, []byte("a"))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	// 超时后连接不再可用。
+	require.ErrorIs(t, conn.WriteMessage(TextMessage, []byte("a")), ErrClosed)
+	_, _, err = conn.ReadMessage()
+	require.Error(t, err)
+}
+
+func TestConn_readTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	conn := newConn(server, nil, true)
+	conn.ReadTimeout = 50 * time.Millisecond
+	peer := newConn(client, nil, false)
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadMessage()
+		readErr <- err
+	}()
+
+	// 每个帧都会刷新期限，包括 pong 。
+	for range 5 {
+		time.Sleep(20 * time.Millisecond)
+		writeRawFrame(peer, true, opPong, nil)
+	}
+
+	select {
+	case err := <-readErr:
+		t.Fatalf("unexpected read result: %v", err)
+	default:
+	}
+
+	// 对方不再发送任何帧，读取超时，连接不再可用。
+	select {
+	case err := <-readErr:
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("read does not time out")
+	}
+	require.Error(t, conn.WriteMessage(TextMessage, []byte("a")))
+}
+
+func TestComputeAcceptKey(t *testing.T) {
+	// RFC 6455 1.3 中的示例。
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", computeAcceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}